@@ -1,12 +1,23 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/chenxuan520/roadmap/backend/internal/config"
 	"github.com/chenxuan520/roadmap/backend/internal/handler"
+	"github.com/chenxuan520/roadmap/backend/internal/logging"
 	"github.com/chenxuan520/roadmap/backend/internal/server"
+	"github.com/chenxuan520/roadmap/backend/internal/tracing"
 )
 
 // 版本信息（通过 -ldflags -X 在构建时注入）
@@ -16,28 +27,122 @@ var (
 	BuildTime = "unknown"
 )
 
+// configFlags 定义配置相关的命令行参数。每个配置字段都有同名参数，例如 -port、-ai.base_url，
+// 优先级高于环境变量与配置文件。
+func configFlags(name string) (*flag.FlagSet, func() config.Sources) {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	configFile := flags.String("config", "", "配置文件路径，默认使用 $CONFIG_FILE 或 configs/config.json")
+	overrides := config.BindFlags(flags)
+	return flags, func() config.Sources {
+		return config.Sources{File: *configFile, Env: os.Environ(), Flags: overrides}
+	}
+}
+
+// validateConfig 实现 validate-config 子命令：列出所有错误与警告，存在错误时返回 1，便于在 CI 中使用
+func validateConfig(args []string) int {
+	flags, sources := configFlags("validate-config")
+	flags.Parse(args)
+
+	cfg, err := config.LoadSources(sources())
+	var issues []config.Issue
+	var invalid *config.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		issues = invalid.Issues
+	case err != nil:
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	default:
+		issues = config.Validate(cfg)
+	}
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if config.HasErrors(issues) {
+		return 1
+	}
+	fmt.Printf("config OK (%d warnings)\n", len(issues))
+	return 0
+}
+
 func main() {
-	cfg, err := config.Load()
+	if len(os.Args) > 1 && os.Args[1] == "validate-config" {
+		os.Exit(validateConfig(os.Args[2:]))
+	}
+
+	flags, sources := configFlags(os.Args[0])
+	printConfig := flags.Bool("print-config", false, "打印生效的配置（隐藏密钥）后退出")
+	flags.Parse(os.Args[1:])
+
+	src := sources()
+	cfg, err := config.LoadSources(src)
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	if *printConfig {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(config.MaskSecrets(cfg)); err != nil {
+			log.Fatalf("Failed to print configuration: %v", err)
+		}
+		return
+	}
+	if err := logging.Setup(cfg.Log); err != nil {
+		log.Fatalf("Failed to set up logging: %v", err)
+	}
+	// 加载成功时只剩警告，例如过短的 jwtSecret
+	for _, issue := range config.Validate(cfg) {
+		slog.Warn("config warning", "path", issue.Path, "message", issue.Message)
+	}
+	shutdownTracing, err := tracing.Setup(cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
 
 	// 初始化trafficpos数据
 	configPath := "./configs" // 配置文件目录
 	if err := handler.LoadTrafficPosData(configPath); err != nil {
-		fmt.Printf("Warning: Failed to load trafficpos data: %v\n", err)
+		slog.Warn("Failed to load trafficpos data", "error", err)
 		// 不中断服务，只是该功能不可用
 	}
 
-	r := server.NewRouter(cfg)
+	// 重新加载时使用启动时相同的配置文件、环境变量与命令行参数，*_FILE 引用的密钥文件会重新读取
+	store := config.NewStore(cfg, func() (config.Config, error) { return config.LoadSources(src) })
+	srv := server.New(store)
+	srv.OnShutdown(shutdownTracing) // 最后导出剩余的 span
+
+	slog.Info("Server running", "port", cfg.Port, "version", Version, "commit", Commit, "built", BuildTime)
+
+	// SIGINT/SIGTERM 触发优雅退出；退出开始后恢复默认行为，再次发送信号可立即终止
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	go watchConfig(ctx, store, src)
+	if err := srv.Run(ctx); err != nil {
+		log.Fatalf("Server error: %v", err)
+	}
+}
+
+// configWatchInterval 检查配置文件是否变化的间隔
+const configWatchInterval = 5 * time.Second
 
-	portStr := fmt.Sprintf(":%d", cfg.Port)
-	fmt.Printf("Server running on %s\n", portStr)
-	fmt.Printf("Backend version=%s commit=%s built=%s\n", Version, Commit, BuildTime)
-	fmt.Printf("Try Baidu: http://localhost:%d/api/cnmap/search?q=清华大学\n", cfg.Port)
-	fmt.Printf("Try Tian:  http://localhost:%d/api/tianmap/search?q=清华大学\n", cfg.Port)
-	fmt.Printf("Try Gaode: http://localhost:%d/api/gaode/search?q=清华大学\n", cfg.Port)
-	fmt.Printf("Try TrafficPos: http://localhost:%d/api/trafficpos?lat=39.9042&lon=116.4074\n", cfg.Port)
+// watchConfig 在收到 SIGHUP 或配置文件变化时重新加载配置，新配置无效时继续使用当前配置
+func watchConfig(ctx context.Context, store *config.Store, src config.Sources) {
+	if path, _ := src.Path(); path != "" {
+		go store.WatchFile(ctx, path, configWatchInterval)
+	}
 
-	r.Run(portStr)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			slog.Info("收到 SIGHUP，重新加载配置")
+			if err := store.Reload(); err != nil {
+				slog.Error("新配置无效，继续使用当前配置", "error", err)
+			}
+		}
+	}
 }
@@ -1,8 +1,9 @@
 module github.com/chenxuan520/roadmap/backend
 
-go 1.18
+go 1.21
 
 require (
+	github.com/andybalholm/brotli v1.1.1
 	github.com/gin-gonic/gin v1.8.1
 	github.com/golang-jwt/jwt/v4 v4.5.2
 	github.com/google/uuid v1.6.0
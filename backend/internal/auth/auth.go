@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/chenxuan520/roadmap/backend/internal/config" // 导入 config 包
@@ -14,36 +15,114 @@ import (
 // Claims 定义了JWT中包含的用户信息
 type Claims struct {
 	Username string `json:"username"`
+	// Role 为签发时的用户角色，供通过 JWKS 验签的外部服务使用
+	Role string `json:"role,omitempty"`
+	// Purpose 为空表示普通访问令牌；两步验证的挑战令牌为 PurposeTwoFactor
+	Purpose string `json:"purpose,omitempty"`
 	jwt.StandardClaims
 }
 
+// PurposeTwoFactor 标记只能用于完成两步验证的短期挑战令牌
+const PurposeTwoFactor = "2fa"
+
+// 挑战令牌的 aud 与 typ，不接受这类令牌的验签方可据此拒绝
+const (
+	challengeAudience  = "roadbook-2fa"
+	challengeTokenType = "roadbook-2fa+jwt"
+)
+
+// challengeTTL 挑战令牌的有效期
+const challengeTTL = 5 * time.Minute
+
+// tokenTTL 访问令牌的有效期
+const tokenTTL = 30 * 24 * time.Hour
+
 // Authenticator 定义了认证服务的接口
 type Authenticator interface {
 	Authenticate(username, password string) (string, error)
+	// VerifyCredentials 仅校验用户名与密码，不签发令牌
+	VerifyCredentials(username, password string) error
 	GenerateToken(username string) (string, error)
 	ParseToken(tokenString string) (*Claims, error)
+	// GenerateChallengeToken 签发密码校验通过、等待两步验证的短期挑战令牌
+	GenerateChallengeToken(username string) (string, error)
+	// ParseChallengeToken 校验挑战令牌并返回对应的用户名
+	ParseChallengeToken(tokenString string) (string, error)
+	// JWKS 返回用于验签的公钥集合，供其他服务校验 roadbook 令牌
+	JWKS() JWKSet
+	// Role 返回用户当前配置的角色，未知用户返回空字符串
+	Role(username string) string
+	// Users 返回全部已配置的用户名及其角色
+	Users() map[string]string
 }
 
-// service 结构体包含 JWT 密钥和用户列表
+// service 结构体持有当前生效的 JWT 密钥和用户列表，配置重新加载时整体替换
 type service struct {
-	jwtSecret []byte
-	users     map[string]config.UserCredentials
+	state atomic.Pointer[serviceState]
+}
+
+// serviceState 是某一版配置对应的密钥与用户
+type serviceState struct {
+	keys  *keySet
+	users map[string]config.UserCredentials
+}
+
+// Reloader 由 NewService 返回的服务实现，用于配置热加载
+type Reloader interface {
+	// PrepareReload 按新配置加载密钥并校验用户，成功时返回用于切换的函数；失败时不影响当前状态
+	PrepareReload(cfg *config.Config) (commit func(), err error)
 }
 
 // NewService 创建并返回一个认证服务实例
-func NewService(cfg config.Config) Authenticator {
-	return &service{
-		jwtSecret: []byte(cfg.JwtSecret),
-		users:     cfg.Users,
+func NewService(cfg config.Config) (Authenticator, error) {
+	st, err := newServiceState(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s := &service{}
+	s.state.Store(st)
+	return s, nil
+}
+
+func newServiceState(cfg config.Config) (*serviceState, error) {
+	keys, err := loadKeySet(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("加载JWT密钥失败: %w", err)
+	}
+	for username, creds := range cfg.Users {
+		if !IsValidRole(creds.Role) {
+			return nil, fmt.Errorf("用户 %s 的角色无效: %s", username, creds.Role)
+		}
+	}
+	return &serviceState{
+		keys:  keys,
+		users: cfg.Users,
+	}, nil
+}
+
+// PrepareReload 实现 Reloader。已签发的令牌在新配置中仍有对应的验签密钥与用户时继续有效。
+func (s *service) PrepareReload(cfg *config.Config) (func(), error) {
+	st, err := newServiceState(*cfg)
+	if err != nil {
+		return nil, err
 	}
+	return func() { s.state.Store(st) }, nil
 }
 
 // Authenticate 验证用户凭证并生成JWT token
 func (s *service) Authenticate(username, password string) (string, error) {
-	creds, ok := s.users[username]
+	if err := s.VerifyCredentials(username, password); err != nil {
+		return "", err
+	}
+	return s.GenerateToken(username)
+}
+
+// VerifyCredentials 验证用户名与密码
+func (s *service) VerifyCredentials(username, password string) error {
+	creds, ok := s.state.Load().users[username]
 	if !ok {
 		// User not found, return generic error to prevent username enumeration
-		return "", errors.New("无效的用户名或密码")
+		return errors.New("无效的用户名或密码")
 	}
 
 	// Hash the incoming password with the stored salt
@@ -53,25 +132,24 @@ func (s *service) Authenticate(username, password string) (string, error) {
 
 	// Compare the generated hash with the stored hash
 	if hashInHex != creds.Hash {
-		return "", errors.New("无效的用户名或密码")
+		return errors.New("无效的用户名或密码")
 	}
-
-	return s.GenerateToken(username)
+	return nil
 }
 
 // GenerateToken 为指定用户生成JWT token
 func (s *service) GenerateToken(username string) (string, error) {
-	expirationTime := time.Now().Add(30 * 24 * time.Hour) // Token 30天过期
+	expirationTime := time.Now().Add(tokenTTL) // Token 30天过期
 	claims := &Claims{
 		Username: username,
+		Role:     s.Role(username),
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: expirationTime.Unix(),
 			IssuedAt:  time.Now().Unix(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(s.jwtSecret) // 使用 service 中的 jwtSecret
+	tokenString, err := s.state.Load().keys.sign(claims) // 使用当前签名密钥
 	if err != nil {
 		return "", fmt.Errorf("生成JWT token失败: %w", err)
 	}
@@ -82,12 +160,7 @@ func (s *service) GenerateToken(username string) (string, error) {
 func (s *service) ParseToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("非预期的签名方法: %v", token.Header["alg"])
-		}
-		return s.jwtSecret, nil // 使用 service 中的 jwtSecret
-	})
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.state.Load().keys.keyFunc) // 按 kid 选择验签密钥
 
 	if err != nil {
 		if err == jwt.ErrSignatureInvalid {
@@ -100,5 +173,74 @@ func (s *service) ParseToken(tokenString string) (*Claims, error) {
 		return nil, errors.New("JWT token无效")
 	}
 
+	// 挑战令牌等特殊用途的令牌不能当作访问令牌使用
+	if claims.Purpose != "" {
+		return nil, errors.New("JWT token无效")
+	}
+
+	// 以当前配置的角色为准，使降级或删除用户立即生效
+	role := s.Role(claims.Username)
+	if role == "" {
+		return nil, errors.New("JWT token对应的用户不存在")
+	}
+	claims.Role = role
+
 	return claims, nil
-}
\ No newline at end of file
+}
+
+// GenerateChallengeToken 签发两步验证挑战令牌
+func (s *service) GenerateChallengeToken(username string) (string, error) {
+	claims := &Claims{
+		Username: username,
+		Purpose:  PurposeTwoFactor,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  challengeAudience,
+			ExpiresAt: time.Now().Add(challengeTTL).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+	}
+
+	// 挑战令牌只由本服务校验，使用不导出的内部密钥签名，避免被信任 JWKS 的外部服务当作访问令牌
+	tokenString, err := s.state.Load().keys.signChallenge(claims)
+	if err != nil {
+		return "", fmt.Errorf("生成挑战token失败: %w", err)
+	}
+	return tokenString, nil
+}
+
+// ParseChallengeToken 校验两步验证挑战令牌
+func (s *service) ParseChallengeToken(tokenString string) (string, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.state.Load().keys.challengeKeyFunc)
+	if err != nil || !token.Valid {
+		return "", errors.New("挑战token无效或已过期，请重新登录")
+	}
+	if claims.Purpose != PurposeTwoFactor || !claims.VerifyAudience(challengeAudience, true) || claims.Username == "" {
+		return "", errors.New("挑战token无效或已过期，请重新登录")
+	}
+	return claims.Username, nil
+}
+
+// JWKS 返回验签公钥集合
+func (s *service) JWKS() JWKSet {
+	return s.state.Load().keys.jwks()
+}
+
+// Role 返回用户当前配置的角色
+func (s *service) Role(username string) string {
+	creds, ok := s.state.Load().users[username]
+	if !ok {
+		return ""
+	}
+	return NormalizeRole(creds.Role)
+}
+
+// Users 返回全部用户名及其角色
+func (s *service) Users() map[string]string {
+	current := s.state.Load().users
+	users := make(map[string]string, len(current))
+	for username, creds := range current {
+		users[username] = NormalizeRole(creds.Role)
+	}
+	return users
+}
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+)
+
+var (
+	ErrAccountLocked  = errors.New("登录失败次数过多，账户已被临时锁定，请稍后再试")
+	ErrGlobalThrottle = errors.New("登录失败请求过多，登录已被临时限制，请稍后再试")
+)
+
+// 登录保护的默认参数
+const (
+	defaultMaxFailures   = 5
+	defaultBaseLockout   = 30 * time.Second
+	defaultMaxLockout    = time.Hour
+	defaultGlobalMax     = 100
+	defaultGlobalWindow  = time.Minute
+	guardCleanupInterval = time.Minute
+	// 当跟踪的用户名数量超过阈值时强制执行一次惰性清理
+	maxTrackedUsers = 10000
+)
+
+// attemptEntry 记录单个用户名的失败情况
+type attemptEntry struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// LoginGuard 按用户名跟踪登录失败次数并实施指数退避锁定，
+// 同时对所有用户、所有IP的失败总数设置全局上限，抵御分布式猜测。
+type LoginGuard struct {
+	mu sync.Mutex
+
+	maxFailures  int
+	baseLockout  time.Duration
+	maxLockout   time.Duration
+	globalMax    int
+	globalWindow time.Duration
+
+	users       map[string]*attemptEntry
+	lastCleanup time.Time
+
+	globalWindowStart time.Time
+	globalFailures    int
+
+	now func() time.Time
+}
+
+// NewLoginGuard 根据配置创建登录保护器，未配置的项使用默认值
+func NewLoginGuard(cfg config.LoginProtectionConfig) *LoginGuard {
+	g := &LoginGuard{
+		maxFailures:  cfg.MaxFailures,
+		baseLockout:  time.Duration(cfg.BaseLockoutSeconds) * time.Second,
+		maxLockout:   time.Duration(cfg.MaxLockoutSeconds) * time.Second,
+		globalMax:    cfg.GlobalMaxFailures,
+		globalWindow: time.Duration(cfg.GlobalWindowSeconds) * time.Second,
+		users:        make(map[string]*attemptEntry),
+		now:          time.Now,
+	}
+	if g.maxFailures <= 0 {
+		g.maxFailures = defaultMaxFailures
+	}
+	if g.baseLockout <= 0 {
+		g.baseLockout = defaultBaseLockout
+	}
+	if g.maxLockout <= 0 {
+		g.maxLockout = defaultMaxLockout
+	}
+	if g.maxLockout < g.baseLockout {
+		g.maxLockout = g.baseLockout
+	}
+	if g.globalMax <= 0 {
+		g.globalMax = defaultGlobalMax
+	}
+	if g.globalWindow <= 0 {
+		g.globalWindow = defaultGlobalWindow
+	}
+	return g
+}
+
+// Check 在校验密码之前调用。返回非nil错误时应拒绝本次登录，retryAfter 为建议的等待时长。
+func (g *LoginGuard) Check(username string) (time.Duration, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	g.rollGlobalWindow(now)
+	if g.globalFailures >= g.globalMax {
+		return g.globalWindowStart.Add(g.globalWindow).Sub(now), ErrGlobalThrottle
+	}
+
+	if entry, ok := g.users[username]; ok && now.Before(entry.lockedUntil) {
+		return entry.lockedUntil.Sub(now), ErrAccountLocked
+	}
+	return 0, nil
+}
+
+// RecordFailure 记录一次登录失败，返回因本次失败而触发的锁定时长（未锁定时为0）
+func (g *LoginGuard) RecordFailure(username, ip string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	g.lazyCleanupIfNeeded(now)
+	g.rollGlobalWindow(now)
+
+	g.globalFailures++
+	if g.globalFailures == g.globalMax {
+		Audit("login_global_throttle", "failures=%d window=%s ip=%s", g.globalFailures, g.globalWindow, ip)
+	}
+
+	entry, ok := g.users[username]
+	if !ok {
+		entry = &attemptEntry{}
+		g.users[username] = entry
+	}
+	entry.failures++
+	entry.lastFailure = now
+	Audit("login_failed", "username=%q ip=%s failures=%d", username, ip, entry.failures)
+
+	if entry.failures < g.maxFailures {
+		return 0
+	}
+	lockout := g.lockoutFor(entry.failures)
+	entry.lockedUntil = now.Add(lockout)
+	Audit("login_locked", "username=%q ip=%s failures=%d lockout=%s", username, ip, entry.failures, lockout)
+	return lockout
+}
+
+// RecordSuccess 登录成功后清除该用户名的失败记录
+func (g *LoginGuard) RecordSuccess(username, ip string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.users, username)
+	Audit("login_succeeded", "username=%q ip=%s", username, ip)
+}
+
+// lockoutFor 计算锁定时长：达到阈值后每多失败一次翻倍，不超过上限
+func (g *LoginGuard) lockoutFor(failures int) time.Duration {
+	lockout := g.baseLockout
+	for i := g.maxFailures; i < failures; i++ {
+		lockout *= 2
+		if lockout >= g.maxLockout {
+			return g.maxLockout
+		}
+	}
+	return lockout
+}
+
+// rollGlobalWindow 在窗口结束后重置全局计数，调用方需持有锁
+func (g *LoginGuard) rollGlobalWindow(now time.Time) {
+	if now.Sub(g.globalWindowStart) >= g.globalWindow {
+		g.globalWindowStart = now
+		g.globalFailures = 0
+	}
+}
+
+// lazyCleanupIfNeeded 惰性删除已解锁且长时间没有失败的记录，调用方需持有锁
+func (g *LoginGuard) lazyCleanupIfNeeded(now time.Time) {
+	if now.Sub(g.lastCleanup) < guardCleanupInterval && len(g.users) <= maxTrackedUsers {
+		return
+	}
+	// 失败记录在最长锁定时长过后自动遗忘
+	cutoff := now.Add(-g.maxLockout)
+	for username, entry := range g.users {
+		if now.After(entry.lockedUntil) && entry.lastFailure.Before(cutoff) {
+			delete(g.users, username)
+		}
+	}
+	g.lastCleanup = now
+}
+
+// Audit 输出审计日志，以 audit=true 标记便于从结构化日志中筛选
+func Audit(event, format string, args ...interface{}) {
+	slog.Info("audit", "audit", true, "event", event, "detail", fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) now() time.Time          { return f.t }
+func (f *fakeClock) advance(d time.Duration) { f.t = f.t.Add(d) }
+
+func newTestGuard(cfg config.LoginProtectionConfig) (*LoginGuard, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	g := NewLoginGuard(cfg)
+	g.now = clock.now
+	return g, clock
+}
+
+func TestLoginGuard_ExponentialLockout(t *testing.T) {
+	g, clock := newTestGuard(config.LoginProtectionConfig{MaxFailures: 3, BaseLockoutSeconds: 10, MaxLockoutSeconds: 35})
+
+	for i := 0; i < 2; i++ {
+		if d := g.RecordFailure("alice", "1.1.1.1"); d != 0 {
+			t.Fatalf("第 %d 次失败不应锁定, 得到 %s", i+1, d)
+		}
+	}
+	if _, err := g.Check("alice"); err != nil {
+		t.Fatalf("未达阈值时不应锁定: %v", err)
+	}
+
+	// 第3次失败：锁定10秒
+	if d := g.RecordFailure("alice", "1.1.1.1"); d != 10*time.Second {
+		t.Fatalf("期望锁定 10s, 得到 %s", d)
+	}
+	retry, err := g.Check("alice")
+	if err != ErrAccountLocked || retry != 10*time.Second {
+		t.Fatalf("期望锁定中 (10s), 得到 %s, %v", retry, err)
+	}
+	// 其他用户不受影响
+	if _, err := g.Check("bob"); err != nil {
+		t.Fatalf("其他用户不应被锁定: %v", err)
+	}
+
+	clock.advance(10 * time.Second)
+	if _, err := g.Check("alice"); err != nil {
+		t.Fatalf("锁定到期后应允许登录: %v", err)
+	}
+
+	// 继续失败：20秒，然后被上限截断为35秒
+	if d := g.RecordFailure("alice", "1.1.1.1"); d != 20*time.Second {
+		t.Fatalf("期望锁定 20s, 得到 %s", d)
+	}
+	if d := g.RecordFailure("alice", "1.1.1.1"); d != 35*time.Second {
+		t.Fatalf("期望锁定被截断为 35s, 得到 %s", d)
+	}
+
+	// 登录成功后清空记录
+	g.RecordSuccess("alice", "1.1.1.1")
+	if _, err := g.Check("alice"); err != nil {
+		t.Fatalf("登录成功后不应锁定: %v", err)
+	}
+	if d := g.RecordFailure("alice", "1.1.1.1"); d != 0 {
+		t.Fatalf("登录成功后失败计数应重置, 得到锁定 %s", d)
+	}
+}
+
+func TestLoginGuard_GlobalCap(t *testing.T) {
+	g, clock := newTestGuard(config.LoginProtectionConfig{MaxFailures: 100, GlobalMaxFailures: 5, GlobalWindowSeconds: 60})
+
+	// 分布式猜测：不同用户名、不同IP
+	for i := 0; i < 5; i++ {
+		g.RecordFailure(string(rune('a'+i)), "10.0.0."+string(rune('1'+i)))
+	}
+
+	clock.advance(20 * time.Second)
+	retry, err := g.Check("anyone")
+	if err != ErrGlobalThrottle {
+		t.Fatalf("期望触发全局限制, 得到 %v", err)
+	}
+	if retry != 40*time.Second {
+		t.Errorf("期望剩余 40s, 得到 %s", retry)
+	}
+
+	clock.advance(40 * time.Second)
+	if _, err := g.Check("anyone"); err != nil {
+		t.Fatalf("窗口结束后应解除全局限制: %v", err)
+	}
+}
+
+func TestLoginGuard_CleanupForgetsOldFailures(t *testing.T) {
+	g, clock := newTestGuard(config.LoginProtectionConfig{MaxFailures: 3, MaxLockoutSeconds: 60})
+
+	g.RecordFailure("alice", "1.1.1.1")
+	g.RecordFailure("alice", "1.1.1.1")
+
+	clock.advance(2 * time.Minute)
+	// 触发清理
+	g.RecordFailure("trigger", "1.1.1.1")
+
+	g.mu.Lock()
+	_, exists := g.users["alice"]
+	g.mu.Unlock()
+	if exists {
+		t.Fatal("期望长时间未失败的记录被清理")
+	}
+}
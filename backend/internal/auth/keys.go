@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// JWK 是公开给其他服务验签使用的 JSON Web Key（RFC 7517 / RFC 8037）
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA
+	E   string `json:"e,omitempty"`   // RSA
+	Crv string `json:"crv,omitempty"` // OKP
+	X   string `json:"x,omitempty"`   // OKP
+}
+
+// JWKSet 是 JWKS 端点返回的结构
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// signingKey 是一把非对称密钥，退役的密钥只有公钥
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+// keySet 管理签名与验签使用的全部密钥
+type keySet struct {
+	signing    *signingKey // 为 nil 时使用 HS256 + hmacSecret 签名
+	verify     map[string]*signingKey
+	hmacSecret []byte // 非空时接受 HS256 令牌
+	// hmacCutoff 非零时只接受 iat 早于它的 HS256 令牌。配置了非对称密钥后，
+	// 只有设置了 jwt.hs256_issued_before 才接受切换之前签发的 HS256 令牌
+	hmacCutoff time.Time
+	// challengeKey 只用于两步验证挑战令牌，由 jwtSecret 或签名私钥派生，从不导出，
+	// 持有 jwtSecret 或 JWKS 公钥的外部服务都无法把挑战令牌当作访问令牌验签通过
+	challengeKey []byte
+}
+
+// challengeKeyLabel 区分挑战令牌密钥与其他由同一密钥派生的用途
+const challengeKeyLabel = "roadbook 2fa challenge"
+
+// deriveKey 用 HMAC-SHA256 从 secret 派生 label 用途的密钥
+func deriveKey(secret []byte, label string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+// loadKeySet 根据配置加载密钥
+func loadKeySet(cfg config.Config) (*keySet, error) {
+	ks := &keySet{
+		verify:     make(map[string]*signingKey),
+		hmacSecret: []byte(cfg.JwtSecret),
+	}
+	if len(cfg.JWT.Keys) == 0 {
+		if len(ks.hmacSecret) == 0 {
+			return nil, errors.New("未配置 jwtSecret 或 jwt.keys")
+		}
+		ks.challengeKey = deriveKey(ks.hmacSecret, challengeKeyLabel)
+		return ks, nil
+	}
+
+	// 非对称密钥模式下 HS256 只用于兼容切换前的令牌，没有截止时间时不再接受
+	ks.hmacSecret = nil
+	if cfg.JWT.HS256IssuedBefore != "" && cfg.JwtSecret != "" {
+		cutoff, err := time.Parse(time.RFC3339, cfg.JWT.HS256IssuedBefore)
+		if err != nil {
+			return nil, fmt.Errorf("jwt.hs256_issued_before 格式错误: %w", err)
+		}
+		if cutoff.After(time.Now()) {
+			return nil, errors.New("jwt.hs256_issued_before 不能晚于当前时间")
+		}
+		ks.hmacSecret, ks.hmacCutoff = []byte(cfg.JwtSecret), cutoff
+	}
+
+	for _, kc := range cfg.JWT.Keys {
+		if kc.ID == "" {
+			return nil, errors.New("jwt.keys 中的每个密钥都必须设置 kid")
+		}
+		if _, dup := ks.verify[kc.ID]; dup {
+			return nil, fmt.Errorf("重复的 kid: %s", kc.ID)
+		}
+		key, err := loadKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("加载密钥 %s 失败: %w", kc.ID, err)
+		}
+		ks.verify[kc.ID] = key
+	}
+
+	signing, ok := ks.verify[cfg.JWT.SigningKeyID]
+	if !ok {
+		return nil, fmt.Errorf("signing_key_id %s 不在 jwt.keys 中", cfg.JWT.SigningKeyID)
+	}
+	if signing.private == nil {
+		return nil, fmt.Errorf("签名密钥 %s 缺少私钥", signing.kid)
+	}
+	ks.signing = signing
+	der, err := x509.MarshalPKCS8PrivateKey(signing.private)
+	if err != nil {
+		return nil, fmt.Errorf("签名密钥 %s 无法派生挑战令牌密钥: %w", signing.kid, err)
+	}
+	ks.challengeKey = deriveKey(der, challengeKeyLabel)
+	return ks, nil
+}
+
+// loadKey 读取 PEM 格式的密钥文件，根据密钥类型确定签名算法
+func loadKey(kc config.JWTKeyConfig) (*signingKey, error) {
+	key := &signingKey{kid: kc.ID}
+
+	if kc.PrivateKeyFile != "" {
+		data, err := os.ReadFile(kc.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+			key.method, key.private, key.public = jwt.SigningMethodRS256, rsaKey, &rsaKey.PublicKey
+		} else if edKey, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+			priv := edKey.(ed25519.PrivateKey)
+			key.method, key.private, key.public = jwt.SigningMethodEdDSA, priv, priv.Public()
+		} else {
+			return nil, errors.New("私钥既不是 RSA 也不是 Ed25519")
+		}
+	}
+
+	if kc.PublicKeyFile != "" {
+		data, err := os.ReadFile(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		var method jwt.SigningMethod
+		var public crypto.PublicKey
+		if rsaKey, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+			method, public = jwt.SigningMethodRS256, rsaKey
+		} else if edKey, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+			method, public = jwt.SigningMethodEdDSA, edKey
+		} else {
+			return nil, errors.New("公钥既不是 RSA 也不是 Ed25519")
+		}
+		if key.public != nil && !publicKeysEqual(key.public, public) {
+			return nil, errors.New("公钥与私钥不匹配")
+		}
+		key.method, key.public = method, public
+	}
+
+	if key.public == nil {
+		return nil, errors.New("必须提供 private_key_file 或 public_key_file")
+	}
+	if rsaKey, ok := key.public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, errors.New("RSA 密钥长度不能小于 2048 位")
+	}
+	return key, nil
+}
+
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	type equaler interface {
+		Equal(crypto.PublicKey) bool
+	}
+	ea, ok := a.(equaler)
+	return ok && ea.Equal(b)
+}
+
+// sign 使用当前签名密钥签发令牌，并在头部写入 kid
+func (ks *keySet) sign(claims jwt.Claims) (string, error) {
+	if ks.signing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.hmacSecret)
+	}
+	token := jwt.NewWithClaims(ks.signing.method, claims)
+	token.Header["kid"] = ks.signing.kid
+	return token.SignedString(ks.signing.private)
+}
+
+// signChallenge 使用内部密钥签发挑战令牌，并标记 typ，便于标准的验签方拒绝
+func (ks *keySet) signChallenge(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["typ"] = challengeTokenType
+	return token.SignedString(ks.challengeKey)
+}
+
+// challengeKeyFunc 只接受用内部密钥签名的 HS256 挑战令牌
+func (ks *keySet) challengeKeyFunc(token *jwt.Token) (interface{}, error) {
+	if token.Method != jwt.SigningMethodHS256 || token.Header["typ"] != challengeTokenType {
+		return nil, errors.New("不是挑战令牌")
+	}
+	return ks.challengeKey, nil
+}
+
+// keyFunc 根据令牌头部的 alg 与 kid 选择验签密钥，拒绝算法与密钥不匹配的令牌。
+// 切换到非对称密钥后，HS256 令牌的 iat 必须早于 hmacCutoff，且有效期不超过 tokenTTL：
+// 持有旧密钥者即使伪造更早的 iat，令牌也最晚在截止时间后 30 天失效
+func (ks *keySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if len(ks.hmacSecret) == 0 {
+			return nil, fmt.Errorf("非预期的签名方法: %v", token.Header["alg"])
+		}
+		if !ks.hmacCutoff.IsZero() {
+			claims, ok := token.Claims.(*Claims)
+			if !ok || claims.IssuedAt == 0 || !time.Unix(claims.IssuedAt, 0).Before(ks.hmacCutoff) ||
+				claims.ExpiresAt == 0 || claims.ExpiresAt-claims.IssuedAt > int64(tokenTTL/time.Second) {
+				return nil, errors.New("HS256 令牌签发于切换到非对称密钥之后")
+			}
+		}
+		return ks.hmacSecret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.verify[kid]
+	if !ok {
+		return nil, fmt.Errorf("未知的签名密钥: %s", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("非预期的签名方法: %v", token.Header["alg"])
+	}
+	return key.public, nil
+}
+
+// jwks 导出全部公钥。仅使用 HS256 时返回空集合。
+func (ks *keySet) jwks() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range ks.verify {
+		jwk := JWK{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
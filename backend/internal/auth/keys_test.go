@@ -0,0 +1,335 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// writeKeyPair 生成密钥对并写入 PEM 文件，返回私钥与公钥路径
+func writeKeyPair(t *testing.T, dir, name string, priv interface{}, pub interface{}) (string, string) {
+	t.Helper()
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privPath := filepath.Join(dir, name+".pem")
+	pubPath := filepath.Join(dir, name+".pub.pem")
+	if err := os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return privPath, pubPath
+}
+
+func newRSAKeyFiles(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeKeyPair(t, dir, name, key, &key.PublicKey)
+}
+
+func newEd25519KeyFiles(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeKeyPair(t, dir, name, priv, pub)
+}
+
+// testUsers 令牌只对已配置的用户有效
+var testUsers = map[string]config.UserCredentials{
+	"alice": {},
+	"bob":   {},
+}
+
+func mustService(t *testing.T, cfg config.Config) Authenticator {
+	t.Helper()
+	if cfg.Users == nil {
+		cfg.Users = testUsers
+	}
+	s, err := NewService(cfg)
+	if err != nil {
+		t.Fatalf("创建认证服务失败: %v", err)
+	}
+	return s
+}
+
+func TestKeys_SignAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	rsaPriv, _ := newRSAKeyFiles(t, dir, "rsa")
+	edPriv, _ := newEd25519KeyFiles(t, dir, "ed")
+
+	for _, tc := range []struct {
+		kid, file, alg string
+	}{
+		{"rsa-1", rsaPriv, "RS256"},
+		{"ed-1", edPriv, "EdDSA"},
+	} {
+		s := mustService(t, config.Config{JWT: config.JWTConfig{
+			SigningKeyID: tc.kid,
+			Keys:         []config.JWTKeyConfig{{ID: tc.kid, PrivateKeyFile: tc.file}},
+		}})
+		tokenString, err := s.GenerateToken("alice")
+		if err != nil {
+			t.Fatalf("%s: 签发失败: %v", tc.alg, err)
+		}
+		parsed, _, err := new(jwt.Parser).ParseUnverified(tokenString, &Claims{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.Header["alg"] != tc.alg || parsed.Header["kid"] != tc.kid {
+			t.Fatalf("头部不符合预期: %v", parsed.Header)
+		}
+		claims, err := s.ParseToken(tokenString)
+		if err != nil || claims.Username != "alice" {
+			t.Fatalf("%s: 验签失败: %v", tc.alg, err)
+		}
+	}
+}
+
+func TestKeys_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	oldPriv, oldPub := newRSAKeyFiles(t, dir, "old")
+	newPriv, _ := newEd25519KeyFiles(t, dir, "new")
+
+	before := mustService(t, config.Config{JWT: config.JWTConfig{
+		SigningKeyID: "old",
+		Keys:         []config.JWTKeyConfig{{ID: "old", PrivateKeyFile: oldPriv}},
+	}})
+	oldToken, err := before.GenerateToken("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 轮换：新密钥负责签名，旧密钥只保留公钥用于验签
+	after := mustService(t, config.Config{JWT: config.JWTConfig{
+		SigningKeyID: "new",
+		Keys: []config.JWTKeyConfig{
+			{ID: "new", PrivateKeyFile: newPriv},
+			{ID: "old", PublicKeyFile: oldPub},
+		},
+	}})
+	if _, err := after.ParseToken(oldToken); err != nil {
+		t.Fatalf("轮换后旧令牌应仍然有效: %v", err)
+	}
+	newToken, err := after.GenerateToken("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := before.ParseToken(newToken); err == nil {
+		t.Fatal("未配置新密钥的服务不应接受新令牌")
+	}
+
+	jwks := after.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kid != "new" || jwks.Keys[1].Kid != "old" {
+		t.Fatalf("JWKS 应包含两把公钥: %+v", jwks)
+	}
+	if jwks.Keys[0].Kty != "OKP" || jwks.Keys[0].X == "" || jwks.Keys[1].Kty != "RSA" || jwks.Keys[1].N == "" {
+		t.Fatalf("JWKS 内容不正确: %+v", jwks)
+	}
+
+	// 移除旧公钥后，旧令牌失效
+	final := mustService(t, config.Config{JWT: config.JWTConfig{
+		SigningKeyID: "new",
+		Keys:         []config.JWTKeyConfig{{ID: "new", PrivateKeyFile: newPriv}},
+	}})
+	if _, err := final.ParseToken(oldToken); err == nil {
+		t.Fatal("旧公钥移除后旧令牌应被拒绝")
+	}
+}
+
+func TestKeys_LegacyHS256(t *testing.T) {
+	dir := t.TempDir()
+	priv, _ := newEd25519KeyFiles(t, dir, "ed")
+
+	legacy := mustService(t, config.Config{JwtSecret: "secret"})
+	if _, err := legacy.GenerateToken("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if len(legacy.JWKS().Keys) != 0 {
+		t.Fatal("仅使用 HS256 时 JWKS 应为空")
+	}
+
+	now := time.Now()
+	hs256 := func(iat, exp time.Time) string {
+		claims := &Claims{Username: "alice", StandardClaims: jwt.StandardClaims{IssuedAt: iat.Unix(), ExpiresAt: exp.Unix()}}
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+		return token
+	}
+	issuedBeforeSwitch := hs256(now.Add(-2*time.Hour), now.Add(-2*time.Hour).Add(tokenTTL))
+	if _, err := legacy.ParseToken(issuedBeforeSwitch); err != nil {
+		t.Fatalf("仅使用 HS256 时应接受 HS256 令牌: %v", err)
+	}
+
+	keys := []config.JWTKeyConfig{{ID: "ed", PrivateKeyFile: priv}}
+	switchedAt := now.Add(-time.Hour).Format(time.RFC3339)
+
+	// 设置截止时间时，迁移期间只接受切换之前签发的 HS256 令牌
+	migrating := mustService(t, config.Config{JwtSecret: "secret", JWT: config.JWTConfig{
+		SigningKeyID: "ed", Keys: keys, HS256IssuedBefore: switchedAt,
+	}})
+	if _, err := migrating.ParseToken(issuedBeforeSwitch); err != nil {
+		t.Fatalf("迁移期间应接受切换前签发的 HS256 令牌: %v", err)
+	}
+	for name, forged := range map[string]string{
+		"切换后签发": hs256(now, now.Add(tokenTTL)),
+		"伪造早于截止时间的 iat 且有效期过长": hs256(now.Add(-2*time.Hour), now.Add(365*24*time.Hour)),
+	} {
+		if _, err := migrating.ParseToken(forged); err == nil {
+			t.Errorf("%s: 持有旧密钥伪造的 HS256 令牌应被拒绝", name)
+		}
+	}
+	noIAT, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{Username: "alice"}).SignedString([]byte("secret"))
+	if _, err := migrating.ParseToken(noIAT); err == nil {
+		t.Error("没有 iat 的 HS256 令牌应被拒绝")
+	}
+	token, _ := migrating.GenerateToken("bob")
+	if parsed, _, err := new(jwt.Parser).ParseUnverified(token, &Claims{}); err != nil || parsed.Header["alg"] != "EdDSA" {
+		t.Error("配置了非对称密钥时不应签发 HS256 令牌")
+	}
+
+	// 未设置截止时间时，即使保留 jwtSecret 也拒绝 HS256 令牌
+	for name, cfg := range map[string]config.Config{
+		"未设置截止时间":      {JwtSecret: "secret", JWT: config.JWTConfig{SigningKeyID: "ed", Keys: keys}},
+		"删除 jwtSecret": {JWT: config.JWTConfig{SigningKeyID: "ed", Keys: keys, HS256IssuedBefore: switchedAt}},
+	} {
+		if _, err := mustService(t, cfg).ParseToken(issuedBeforeSwitch); err == nil {
+			t.Errorf("%s: 应拒绝 HS256 令牌", name)
+		}
+	}
+
+	// 截止时间不能晚于当前时间，否则旧密钥在此之前仍可签发新令牌
+	if _, err := NewService(config.Config{JwtSecret: "secret", Users: testUsers, JWT: config.JWTConfig{
+		SigningKeyID: "ed", Keys: keys, HS256IssuedBefore: now.Add(time.Hour).Format(time.RFC3339),
+	}}); err == nil {
+		t.Error("未来的截止时间应被拒绝")
+	}
+}
+
+func TestKeys_RejectsUnknownKidAndAlgMismatch(t *testing.T) {
+	dir := t.TempDir()
+	priv, _ := newRSAKeyFiles(t, dir, "rsa")
+	s := mustService(t, config.Config{JWT: config.JWTConfig{
+		SigningKeyID: "rsa",
+		Keys:         []config.JWTKeyConfig{{ID: "rsa", PrivateKeyFile: priv}},
+	}})
+
+	claims := &Claims{Username: "mallory", StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour).Unix()}}
+
+	// 未知 kid
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = "unknown"
+	forged, _ := token.SignedString(edKey)
+	if _, err := s.ParseToken(forged); err == nil {
+		t.Fatal("未知 kid 的令牌应被拒绝")
+	}
+
+	// kid 正确但算法不匹配
+	token = jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = "rsa"
+	forged, _ = token.SignedString(edKey)
+	if _, err := s.ParseToken(forged); err == nil {
+		t.Fatal("算法与密钥不匹配的令牌应被拒绝")
+	}
+}
+
+func TestKeys_ConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	rsaPriv, _ := newRSAKeyFiles(t, dir, "rsa")
+	_, otherPub := newRSAKeyFiles(t, dir, "other")
+
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smallPriv, _ := writeKeyPair(t, dir, "small", small, &small.PublicKey)
+
+	cases := map[string]config.JWTConfig{
+		"签名密钥不存在":  {SigningKeyID: "missing", Keys: []config.JWTKeyConfig{{ID: "rsa", PrivateKeyFile: rsaPriv}}},
+		"签名密钥缺少私钥": {SigningKeyID: "pub", Keys: []config.JWTKeyConfig{{ID: "pub", PublicKeyFile: otherPub}}},
+		"公私钥不匹配":   {SigningKeyID: "rsa", Keys: []config.JWTKeyConfig{{ID: "rsa", PrivateKeyFile: rsaPriv, PublicKeyFile: otherPub}}},
+		"RSA密钥过短":  {SigningKeyID: "small", Keys: []config.JWTKeyConfig{{ID: "small", PrivateKeyFile: smallPriv}}},
+		"重复kid": {SigningKeyID: "rsa", Keys: []config.JWTKeyConfig{
+			{ID: "rsa", PrivateKeyFile: rsaPriv},
+			{ID: "rsa", PublicKeyFile: otherPub},
+		}},
+	}
+	for name, jwtCfg := range cases {
+		if _, err := NewService(config.Config{JWT: jwtCfg}); err == nil {
+			t.Errorf("%s: 期望返回错误", name)
+		}
+	}
+}
+
+func TestChallengeToken_NotUsableAsAccessToken(t *testing.T) {
+	dir := t.TempDir()
+	priv, pub := newEd25519KeyFiles(t, dir, "ed")
+	pubPEM, err := os.ReadFile(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, err := jwt.ParseEdPublicKeyFromPEM(pubPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		cfg config.Config
+		// external 模拟只持有公开密钥的外部验签方
+		external jwt.Keyfunc
+	}{
+		"HS256": {
+			cfg:      config.Config{JwtSecret: "secret"},
+			external: func(*jwt.Token) (interface{}, error) { return []byte("secret"), nil },
+		},
+		"非对称密钥": {
+			cfg:      config.Config{JWT: config.JWTConfig{SigningKeyID: "ed", Keys: []config.JWTKeyConfig{{ID: "ed", PrivateKeyFile: priv}}}},
+			external: func(*jwt.Token) (interface{}, error) { return edPub, nil },
+		},
+	} {
+		s := mustService(t, tc.cfg)
+		challenge, err := s.GenerateChallengeToken("alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if username, err := s.ParseChallengeToken(challenge); err != nil || username != "alice" {
+			t.Fatalf("%s: 挑战令牌应能完成两步验证: %v", name, err)
+		}
+		if _, err := s.ParseToken(challenge); err == nil {
+			t.Errorf("%s: 挑战令牌不应被当作访问令牌", name)
+		}
+		if _, err := jwt.ParseWithClaims(challenge, &Claims{}, tc.external); err == nil {
+			t.Errorf("%s: 外部验签方不应接受挑战令牌", name)
+		}
+		parsed, _, _ := new(jwt.Parser).ParseUnverified(challenge, &Claims{})
+		if parsed.Header["typ"] != challengeTokenType || parsed.Claims.(*Claims).Audience != challengeAudience {
+			t.Errorf("%s: 挑战令牌应带有专用的 typ 与 aud: %v", name, parsed.Header)
+		}
+
+		// 访问令牌不能当作挑战令牌使用
+		access, _ := s.GenerateToken("alice")
+		if _, err := s.ParseChallengeToken(access); err == nil {
+			t.Errorf("%s: 访问令牌不应通过挑战令牌校验", name)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package auth
+
+// 用户角色。权限从高到低依次为 admin、member、readonly。
+const (
+	RoleAdmin    = "admin"    // 可访问全部接口，包括用户管理与服务诊断
+	RoleMember   = "member"   // 普通用户：管理自己的计划并使用AI助手
+	RoleReadOnly = "readonly" // 只读用户：只能查看计划和使用搜索
+)
+
+// roleRank 用于比较角色高低
+var roleRank = map[string]int{
+	RoleReadOnly: 1,
+	RoleMember:   2,
+	RoleAdmin:    3,
+}
+
+// IsValidRole 判断角色名是否合法（空值视为 member）
+func IsValidRole(role string) bool {
+	if role == "" {
+		return true
+	}
+	_, ok := roleRank[role]
+	return ok
+}
+
+// NormalizeRole 将未配置的角色视为 member
+func NormalizeRole(role string) string {
+	if role == "" {
+		return RoleMember
+	}
+	return role
+}
+
+// RoleAtLeast 判断 role 是否不低于 min
+func RoleAtLeast(role, min string) bool {
+	return roleRank[NormalizeRole(role)] >= roleRank[min]
+}
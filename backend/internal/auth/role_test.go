@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+)
+
+func TestRoleAtLeast(t *testing.T) {
+	cases := []struct {
+		role, min string
+		want      bool
+	}{
+		{RoleAdmin, RoleAdmin, true},
+		{RoleAdmin, RoleReadOnly, true},
+		{RoleMember, RoleAdmin, false},
+		{RoleMember, RoleMember, true},
+		{"", RoleMember, true}, // 未配置视为 member
+		{"", RoleAdmin, false},
+		{RoleReadOnly, RoleMember, false},
+		{"superuser", RoleReadOnly, false},
+	}
+	for _, tc := range cases {
+		if got := RoleAtLeast(tc.role, tc.min); got != tc.want {
+			t.Errorf("RoleAtLeast(%q, %q) = %v, 期望 %v", tc.role, tc.min, got, tc.want)
+		}
+	}
+}
+
+func TestService_RoleInClaims(t *testing.T) {
+	users := map[string]config.UserCredentials{
+		"alice": {Role: RoleAdmin},
+		"bob":   {},
+	}
+	s := mustService(t, config.Config{JwtSecret: "secret", Users: users})
+
+	tokenString, err := s.GenerateToken("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := s.ParseToken(tokenString)
+	if err != nil || claims.Role != RoleAdmin {
+		t.Fatalf("期望角色 admin, 得到 %+v, %v", claims, err)
+	}
+	if s.Role("bob") != RoleMember {
+		t.Errorf("未配置角色的用户应为 member, 得到 %q", s.Role("bob"))
+	}
+
+	// 配置变更后以当前角色为准，被删除的用户令牌失效
+	demoted := mustService(t, config.Config{JwtSecret: "secret", Users: map[string]config.UserCredentials{
+		"alice": {Role: RoleReadOnly},
+	}})
+	claims, err = demoted.ParseToken(tokenString)
+	if err != nil || claims.Role != RoleReadOnly {
+		t.Fatalf("期望降级后角色为 readonly, 得到 %+v, %v", claims, err)
+	}
+	removed := mustService(t, config.Config{JwtSecret: "secret", Users: map[string]config.UserCredentials{
+		"bob": {},
+	}})
+	if _, err := removed.ParseToken(tokenString); err == nil {
+		t.Error("用户被删除后令牌应失效")
+	}
+}
+
+func TestNewService_InvalidRole(t *testing.T) {
+	_, err := NewService(config.Config{JwtSecret: "secret", Users: map[string]config.UserCredentials{
+		"alice": {Role: "root"},
+	}})
+	if err == nil {
+		t.Fatal("期望无效角色返回错误")
+	}
+}
+
+func TestPrepareReload(t *testing.T) {
+	s := mustService(t, config.Config{JwtSecret: "secret", Users: map[string]config.UserCredentials{
+		"alice": {Role: RoleAdmin},
+	}})
+	tokenString, err := s.GenerateToken("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloader := s.(Reloader)
+
+	// 无效的新配置被拒绝，当前状态不变
+	if _, err := reloader.PrepareReload(&config.Config{JwtSecret: "secret", Users: map[string]config.UserCredentials{
+		"alice": {Role: "root"},
+	}}); err == nil {
+		t.Fatal("期望无效角色返回错误")
+	}
+	if s.Role("alice") != RoleAdmin {
+		t.Fatal("准备失败不应影响当前配置")
+	}
+
+	commit, err := reloader.PrepareReload(&config.Config{JwtSecret: "secret", Users: map[string]config.UserCredentials{
+		"alice": {Role: RoleReadOnly},
+		"bob":   {},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Role("bob") != "" {
+		t.Fatal("提交前不应生效")
+	}
+	commit()
+	if s.Role("bob") != RoleMember {
+		t.Error("新增的用户应在提交后生效")
+	}
+	if claims, err := s.ParseToken(tokenString); err != nil || claims.Role != RoleReadOnly {
+		t.Errorf("密钥未变时已签发的令牌应继续有效并使用新角色, 得到 %+v, %v", claims, err)
+	}
+}
@@ -2,81 +2,330 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 )
 
 // UserCredentials holds the salt and hashed password for a user.
 type UserCredentials struct {
 	Salt string `json:"salt"`
-	Hash string `json:"hash"`
+	Hash string `json:"hash" secret:"true"`
+	// Role is one of "admin", "member" or "readonly". Empty means "member".
+	Role string `json:"role,omitempty"`
 }
 
 type Config struct {
-	Port                  int                          `json:"port"`
-	AllowedOrigins        []string                     `json:"allowed_origins"`
-	AllowNullOriginForDev bool                         `json:"allow_null_origin_for_dev,omitempty"`
-	JwtSecret             string                       `json:"jwtSecret"`
-	Users                 map[string]UserCredentials `json:"users"`
-	Search                SearchConfig                 `json:"search"`
-	AI                    AIConfig                     `json:"ai"`
+	Port                  int      `json:"port"`
+	AllowedOrigins        []string `json:"allowed_origins"`
+	AllowNullOriginForDev bool     `json:"allow_null_origin_for_dev,omitempty"`
+	// CORS tunes the cross-origin response headers for the origins listed in AllowedOrigins.
+	CORS CORSConfig `json:"cors"`
+	// TrustedProxies lists the IPs or CIDRs of reverse proxies whose forwarding headers are
+	// believed when resolving the client IP. nil defaults to loopback only (the bundled nginx);
+	// an empty list trusts no proxy and always uses the TCP peer address.
+	TrustedProxies []string `json:"trusted_proxies"`
+	// RealIPHeader names the single header carrying the client IP set by the trusted proxy,
+	// e.g. "X-Real-IP" or "CF-Connecting-IP". Defaults to X-Forwarded-For, then X-Real-IP.
+	RealIPHeader    string                     `json:"real_ip_header,omitempty"`
+	JwtSecret       string                     `json:"jwtSecret" secret:"true"`
+	JWT             JWTConfig                  `json:"jwt"`
+	Users           map[string]UserCredentials `json:"users"`
+	Search          SearchConfig               `json:"search"`
+	AI              AIConfig                   `json:"ai"`
+	OIDC            OIDCConfig                 `json:"oidc"`
+	LoginProtection LoginProtectionConfig      `json:"login_protection"`
+	RateLimit       RateLimitConfig            `json:"rate_limit"`
+	Log             LogConfig                  `json:"log"`
+	Metrics         MetricsConfig              `json:"metrics"`
+	Tracing         TracingConfig              `json:"tracing"`
+	Server          ServerConfig               `json:"server"`
+	TLS             TLSConfig                  `json:"tls"`
+	Static          StaticConfig               `json:"static"`
+	Compression     CompressionConfig          `json:"compression"`
+}
+
+// CompressionConfig compresses JSON and text responses with brotli or gzip for clients that
+// accept it. Leave it disabled when a reverse proxy already compresses responses.
+type CompressionConfig struct {
+	Enabled bool `json:"enabled"`
+	// Level is the gzip level from 1 (fastest) to 9 (smallest). 0 uses the default level.
+	Level int `json:"level,omitempty"`
+	// BrotliLevel is the brotli level from 1 (fastest) to 11 (smallest). 0 uses the default (6).
+	BrotliLevel int `json:"brotli_level,omitempty"`
+	// MinSizeBytes skips smaller responses. 0 defaults to 1024.
+	MinSizeBytes int `json:"min_size_bytes,omitempty"`
+}
+
+// StaticConfig serves the static/ frontend from the Go binary so a single container runs the
+// whole app without nginx. API routes always take precedence over files.
+type StaticConfig struct {
+	Enabled bool `json:"enabled"`
+	// Dir serves files from disk. Empty uses the copy embedded at build time with
+	// -tags embedstatic.
+	Dir string `json:"dir,omitempty"`
+	// MaxAgeSeconds lets browsers reuse assets other than index.html, sw.js and manifest.json
+	// without revalidating. 0 sends no-cache, so every load revalidates with the ETag.
+	MaxAgeSeconds int `json:"max_age_seconds,omitempty"`
+}
+
+// CORSConfig overrides the defaults used for requests from allowed origins.
+// Empty lists keep the defaults documented in the README.
+type CORSConfig struct {
+	AllowedMethods []string `json:"allowed_methods,omitempty"`
+	AllowedHeaders []string `json:"allowed_headers,omitempty"`
+	ExposedHeaders []string `json:"exposed_headers,omitempty"`
+	// MaxAgeSeconds lets browsers cache preflight results. 0 omits Access-Control-Max-Age.
+	MaxAgeSeconds int `json:"max_age_seconds,omitempty"`
+}
+
+// TLSConfig lets the binary terminate TLS itself instead of sitting behind nginx.
+type TLSConfig struct {
+	Enabled bool `json:"enabled"`
+	// CertFile and KeyFile are PEM files; the certificate file may contain the full chain.
+	// Both are re-read when their modification time changes, so renewed certificates
+	// (e.g. from certbot) are picked up without a restart.
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+	// HTTPRedirectPort, when set, starts a plain HTTP listener on that port that
+	// redirects every request to HTTPS on Port.
+	HTTPRedirectPort int `json:"http_redirect_port,omitempty"`
+	// DisableHTTP2 restricts TLS connections to HTTP/1.1. HTTP/2 is negotiated by default.
+	DisableHTTP2 bool `json:"disable_http2,omitempty"`
+}
+
+// ServerConfig tunes the HTTP server. Zero values use the defaults noted on each field.
+type ServerConfig struct {
+	ReadHeaderTimeoutSeconds int `json:"read_header_timeout_seconds,omitempty"` // default 10
+	ReadTimeoutSeconds       int `json:"read_timeout_seconds,omitempty"`        // default 60, covers plan uploads
+	// WriteTimeoutSeconds bounds the whole response. The default 0 means no limit, because
+	// AI chat streams can legitimately run for minutes.
+	WriteTimeoutSeconds    int `json:"write_timeout_seconds,omitempty"`
+	IdleTimeoutSeconds     int `json:"idle_timeout_seconds,omitempty"`     // default 120
+	ShutdownTimeoutSeconds int `json:"shutdown_timeout_seconds,omitempty"` // default 30, how long to drain on SIGTERM
+}
+
+// TracingConfig enables distributed tracing with spans exported over OTLP/HTTP (JSON).
+type TracingConfig struct {
+	Enabled bool `json:"enabled"`
+	// Endpoint is the collector base URL, e.g. "http://otel-collector:4318";
+	// spans are posted to Endpoint + "/v1/traces".
+	Endpoint string `json:"endpoint"`
+	// Headers are sent with every export request, e.g. an API key for a hosted backend.
+	// Values are masked by --print-config.
+	Headers     map[string]string `json:"headers,omitempty" secret:"true"`
+	ServiceName string            `json:"service_name,omitempty"` // defaults to "roadbook-backend"
+	// SampleRatio is the fraction of new traces recorded, in (0, 1]. 0 or omitted means 1.
+	// Requests that arrive with a traceparent follow the caller's sampling decision.
+	SampleRatio float64 `json:"sample_ratio,omitempty"`
+}
+
+// MetricsConfig controls the Prometheus endpoint at /metrics.
+type MetricsConfig struct {
+	Enabled bool `json:"enabled"`
+	// Token, when set, must be sent by the scraper as "Authorization: Bearer <token>".
+	Token string `json:"token,omitempty" secret:"true"`
+}
+
+// LogConfig controls the structured logger.
+type LogConfig struct {
+	Level  string `json:"level,omitempty"`  // debug, info (default), warn, error
+	Format string `json:"format,omitempty"` // json (default) or text
+}
+
+// RateLimitConfig defines named rate-limit policies and which route group uses which policy.
+// Built-in policies "login", "search", "ai" and "share" exist by default; entries in Policies
+// with the same name replace them.
+type RateLimitConfig struct {
+	Policies map[string]RateLimitPolicy `json:"policies,omitempty"`
+	// Routes maps a route group ("login", "search", "ai", "share") to a policy name.
+	// Unlisted groups use the policy with the same name; "off" disables limiting for the group.
+	Routes map[string]string `json:"routes,omitempty"`
+	// Store is "memory" (default, per process) or "redis" to share limits across replicas.
+	Store string      `json:"store,omitempty"`
+	Redis RedisConfig `json:"redis,omitempty"`
+}
+
+// RedisConfig points at a Redis server, or anything that speaks the Redis protocol.
+type RedisConfig struct {
+	Addr          string `json:"addr"` // host:port
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty" secret:"true"`
+	DB            int    `json:"db,omitempty"`
+	KeyPrefix     string `json:"key_prefix,omitempty"`     // default "roadbook:ratelimit:"
+	PoolSize      int    `json:"pool_size,omitempty"`      // default 10
+	TimeoutMillis int    `json:"timeout_millis,omitempty"` // per round trip, default 200
+}
+
+// RateLimitPolicy is a token bucket refilled at Rate requests per second holding at most Burst.
+type RateLimitPolicy struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+	// Key selects what is limited: "ip" (default), "user" (falls back to IP for anonymous
+	// requests) or "token" (per personal access token, falls back to user, then IP).
+	Key string `json:"key,omitempty"`
+}
+
+// LoginProtectionConfig tunes brute-force protection on the login endpoint.
+// Zero values fall back to the defaults noted on each field.
+type LoginProtectionConfig struct {
+	MaxFailures         int `json:"max_failures,omitempty"`          // failures per username before lockout, default 5
+	BaseLockoutSeconds  int `json:"base_lockout_seconds,omitempty"`  // first lockout, doubled on each further failure, default 30
+	MaxLockoutSeconds   int `json:"max_lockout_seconds,omitempty"`   // lockout cap, default 3600
+	GlobalMaxFailures   int `json:"global_max_failures,omitempty"`   // failures across all users and IPs per window, default 100
+	GlobalWindowSeconds int `json:"global_window_seconds,omitempty"` // window for the global cap, default 60
 }
 
 type AIConfig struct {
 	Enabled bool   `json:"enabled"`
 	BaseURL string `json:"base_url,omitempty"`
-	Key     string `json:"key,omitempty"`
+	Key     string `json:"key,omitempty" secret:"true"`
 	Model   string `json:"model,omitempty"`
 }
 
+// JWTConfig enables asymmetric token signing. When Keys is empty, tokens are signed
+// with HS256 using jwtSecret. Once keys are configured HS256 tokens are rejected, except
+// those issued before HS256IssuedBefore while jwtSecret is still set.
+type JWTConfig struct {
+	// SigningKeyID is the kid of the key used to sign new tokens; it must have a private key.
+	SigningKeyID string         `json:"signing_key_id,omitempty"`
+	Keys         []JWTKeyConfig `json:"keys,omitempty"`
+	// HS256IssuedBefore (RFC 3339, e.g. the time of the switch to Keys) keeps HS256 tokens
+	// whose iat is earlier valid during the migration. It must not be in the future, so the
+	// old secret cannot mint new tokens.
+	HS256IssuedBefore string `json:"hs256_issued_before,omitempty"`
+}
+
+// JWTKeyConfig describes one key pair. RSA keys sign with RS256 and Ed25519 keys with EdDSA.
+// Retired keys only need the public key so that tokens they signed still verify.
+type JWTKeyConfig struct {
+	ID             string `json:"kid"`
+	PrivateKeyFile string `json:"private_key_file,omitempty"` // PEM (PKCS#1 or PKCS#8)
+	PublicKeyFile  string `json:"public_key_file,omitempty"`  // PEM (PKIX), derived from the private key when omitted
+}
+
+// OIDCConfig holds the OpenID Connect login settings. Users authenticated by the
+// identity provider are mapped to roadbook users and receive a normal roadbook JWT.
+type OIDCConfig struct {
+	Enabled      bool     `json:"enabled"`
+	Issuer       string   `json:"issuer,omitempty"`
+	ClientID     string   `json:"client_id,omitempty"`
+	ClientSecret string   `json:"client_secret,omitempty" secret:"true"`
+	RedirectURL  string   `json:"redirect_url,omitempty"` // e.g. https://roadbook.example.com/api/v1/oidc/callback
+	Scopes       []string `json:"scopes,omitempty"`       // defaults to openid, email, profile
+	// UsernameClaim selects the ID token claim used to identify the user: "email" (default),
+	// "sub" or "preferred_username".
+	UsernameClaim string `json:"username_claim,omitempty"`
+	// UserMapping maps claim values to roadbook usernames. Only a verified email
+	// (email_verified is true) that equals a configured username is accepted without an
+	// entry; "sub" and "preferred_username" values always need one.
+	UserMapping map[string]string `json:"user_mapping,omitempty"`
+	// FrontendRedirectURL receives the issued token as "#token=..." after login.
+	// When empty the callback responds with JSON instead.
+	FrontendRedirectURL string `json:"frontend_redirect_url,omitempty"`
+}
+
 // SearchProviderConfig holds configuration for a single search provider, like an API key.
 type SearchProviderConfig struct {
-	Key          string `json:"key"`
+	Key          string `json:"key" secret:"true"`
 	LoginRequired bool   `json:"login_required,omitempty"`
+	// Disabled turns the provider off; it stays in the provider list with enabled=false.
+	Disabled bool `json:"disabled,omitempty"`
 }
 
-// SearchProviders holds configurations for all supported search providers.
-type SearchProviders struct {
-	Gaode SearchProviderConfig `json:"gaode"`
+// SearchProviders maps a provider name (e.g. "gaode", "baidu", "tianmap") to its settings.
+// For tianmap, Key overrides the built-in Tianditu browser key.
+type SearchProviders map[string]SearchProviderConfig
+
+// Get returns the settings of the named provider, or the zero config when it has no entry.
+func (p SearchProviders) Get(name string) SearchProviderConfig {
+	return p[name]
 }
 
 // SearchConfig holds all search-related configurations.
 type SearchConfig struct {
 	Providers SearchProviders `json:"providers"`
+	// Federated tunes provider=all, which queries every enabled provider at once.
+	Federated FederatedSearchConfig `json:"federated"`
+	// Fallback is the order in which other providers are tried when a search fails, e.g.
+	// ["baidu", "gaode", "tianmap"]. Empty disables failover.
+	Fallback []string `json:"fallback,omitempty"`
+	// CircuitBreaker stops calling a provider that keeps failing.
+	CircuitBreaker CircuitBreakerConfig `json:"circuit_breaker"`
+	// Cache holds recent results in front of every provider.
+	Cache SearchCacheConfig `json:"cache"`
+}
+
+// SearchCacheConfig tunes the search result cache. It is read once at startup.
+type SearchCacheConfig struct {
+	// Disabled sends every search upstream; identical concurrent searches are no longer coalesced either.
+	Disabled bool `json:"disabled,omitempty"`
+	// MaxEntries caps the number of cached queries; the least recently used are evicted. 0 defaults to 1000.
+	MaxEntries int `json:"max_entries,omitempty"`
+	// TTLSeconds is how long results are served from the cache. 0 defaults to 600.
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+	// File persists the cache across restarts when set, e.g. "data/search_cache.json".
+	File string `json:"file,omitempty"`
 }
 
+// CircuitBreakerConfig tunes the per-provider circuit breaker.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens the breaker. 0 defaults to 5.
+	FailureThreshold int `json:"failure_threshold,omitempty"`
+	// CooldownSeconds is how long an open breaker rejects calls before letting a trial call through. 0 defaults to 30.
+	CooldownSeconds int `json:"cooldown_seconds,omitempty"`
+}
+
+// FederatedSearchConfig tunes how provider=all merges results.
+type FederatedSearchConfig struct {
+	// TimeoutMillis bounds each provider call; slower providers are left out. 0 defaults to 3000.
+	TimeoutMillis int `json:"timeout_millis,omitempty"`
+	// DedupDistanceMeters merges results with similar names closer than this. 0 defaults to 200.
+	DedupDistanceMeters float64 `json:"dedup_distance_meters,omitempty"`
+}
+
+// defaultConfigFile is used when neither Sources.File nor $CONFIG_FILE is set.
+const defaultConfigFile = "configs/config.json"
+
+// Load reads the config file and applies ROADBOOK_* environment overrides.
 func Load() (Config, error) {
+	return LoadSources(Sources{Env: os.Environ()})
+}
+
+// LoadSources builds the effective config from src, lowest precedence first: the JSON
+// file, environment variables (including *_FILE secrets), then command-line flags.
+func LoadSources(src Sources) (Config, error) {
 	var config Config
 
-	configPath := os.Getenv("CONFIG_FILE")
-	if configPath == "" {
-		configPath = "configs/config.json"
-	}
+	configPath, optional := src.Path()
 
 	file, err := os.ReadFile(configPath)
-	if err != nil {
+	switch {
+	case err == nil:
+		err = json.Unmarshal(file, &config)
+		if err != nil {
+			return config, fmt.Errorf("error parsing config file: %w", err)
+		}
+	case optional && errors.Is(err, fs.ErrNotExist):
+		// No file at the default location: everything comes from env vars and flags.
+	default:
 		return config, fmt.Errorf("config file not found or error reading: %w", err)
 	}
 
-	err = json.Unmarshal(file, &config)
-	if err != nil {
-		return config, fmt.Errorf("error parsing config file: %w", err)
+	if err := applyEnv(&config, src.Env); err != nil {
+		return config, err
 	}
-
-	if config.Port <= 0 || config.Port > 65535 {
-		// Fallback to a default port if the configured one is invalid, but log it.
-		fmt.Println("Invalid port in config, using default port 8080")
-		config.Port = 8080
+	if err := applyFlags(&config, src.Flags); err != nil {
+		return config, err
 	}
 
-	// 检查 JwtSecret 是否为空
-	if config.JwtSecret == "" {
-		return config, fmt.Errorf("jwtSecret must not be empty in config")
+	if config.Port == 0 {
+		config.Port = 8080
 	}
 
-	// 检查 Users 是否为空
-	if len(config.Users) == 0 {
-		return config, fmt.Errorf("users must not be empty in config")
+	if issues := Validate(config); HasErrors(issues) {
+		return config, &ValidationError{Issues: issues}
 	}
 
 	return config, nil
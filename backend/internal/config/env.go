@@ -0,0 +1,400 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// EnvPrefix starts the name of every environment variable that overrides a config field.
+const EnvPrefix = "ROADBOOK_"
+
+// secretMask replaces non-empty secret values in MaskSecrets output.
+const secretMask = "******"
+
+// Sources lists where Load reads configuration from. Later sources take precedence:
+// the JSON file, then environment variables, then command-line flags.
+type Sources struct {
+	// File is the JSON config path. Empty means $CONFIG_FILE, then configs/config.json;
+	// only the built-in default may be missing, so a deployment can rely on env vars alone.
+	File string
+	// Env holds "KEY=value" pairs, normally os.Environ().
+	Env []string
+	// Flags maps a field path (see Field.Path) to its raw command-line value, normally the
+	// map returned by BindFlags.
+	Flags map[string]string
+}
+
+// Path returns the config file LoadSources reads, and whether it is the built-in default
+// that may be missing.
+func (src Sources) Path() (path string, optional bool) {
+	if src.File != "" {
+		return src.File, false
+	}
+	if path := lookupEnv(src.Env, "CONFIG_FILE"); path != "" {
+		return path, false
+	}
+	return defaultConfigFile, true
+}
+
+// Field describes one overridable config field.
+type Field struct {
+	// Path is the dotted JSON path, e.g. "ai.base_url". It is also the flag name.
+	Path string
+	// Env is the environment variable, e.g. ROADBOOK_AI_BASE_URL. Env + "_FILE" names a
+	// file whose contents (without the trailing newline) are used instead, for Docker and
+	// Kubernetes secrets.
+	Env    string
+	Secret bool
+
+	index []int
+	typ   reflect.Type
+	// mapKey and elemIndex locate a field inside a map entry, e.g. the key field of
+	// search.providers["gaode"]; index then points at the map.
+	mapKey    string
+	elemIndex []int
+}
+
+var (
+	searchProvidersMu   sync.Mutex
+	searchProviderNames []string
+)
+
+// RegisterSearchProvider is called by the search package for each provider it registers,
+// so the provider's entry in search.providers gets its own environment variables and flags
+// (e.g. ROADBOOK_SEARCH_PROVIDERS_GAODE_KEY). Registering a name again has no effect.
+func RegisterSearchProvider(name string) {
+	searchProvidersMu.Lock()
+	defer searchProvidersMu.Unlock()
+	for _, n := range searchProviderNames {
+		if n == name {
+			return
+		}
+	}
+	searchProviderNames = append(searchProviderNames, name)
+}
+
+// Fields lists every overridable field in declaration order. Nested objects are expanded;
+// maps and slices are single fields whose value replaces the whole map or list. Entries of
+// search.providers for registered providers are expanded as well.
+func Fields() []Field {
+	return collectFields(reflect.TypeOf(Config{}), "", nil)
+}
+
+func collectFields(t reflect.Type, prefix string, index []int) []Field {
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		idx := append(append([]int(nil), index...), i)
+		if sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, collectFields(sf.Type, prefix+name+".", idx)...)
+			continue
+		}
+		path := prefix + name
+		fields = append(fields, Field{
+			Path:   path,
+			Env:    envName(path),
+			Secret: sf.Tag.Get("secret") == "true",
+			index:  idx,
+			typ:    sf.Type,
+		})
+		if sf.Type == reflect.TypeOf(SearchProviders(nil)) {
+			fields = append(fields, searchProviderFields(path, idx)...)
+		}
+	}
+	return fields
+}
+
+// searchProviderFields lists the fields of each registered provider's entry in the
+// search.providers map found at path.
+func searchProviderFields(path string, index []int) []Field {
+	searchProvidersMu.Lock()
+	names := append([]string(nil), searchProviderNames...)
+	searchProvidersMu.Unlock()
+
+	var fields []Field
+	for _, provider := range names {
+		for _, f := range collectFields(reflect.TypeOf(SearchProviderConfig{}), path+"."+provider+".", nil) {
+			f.Env = envName(f.Path)
+			f.mapKey, f.elemIndex, f.index = provider, f.index, index
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// envName turns a JSON path into an environment variable: "rate_limit.redis.addr" becomes
+// ROADBOOK_RATE_LIMIT_REDIS_ADDR and "jwtSecret" becomes ROADBOOK_JWT_SECRET.
+func envName(path string) string {
+	var b strings.Builder
+	b.WriteString(EnvPrefix)
+	prev := rune(0)
+	for _, r := range path {
+		switch {
+		case r == '.' || r == '-':
+			r = '_'
+		case unicode.IsUpper(r) && unicode.IsLower(prev):
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+		prev = r
+	}
+	return b.String()
+}
+
+// lookupEnv returns the value of key in a list of "KEY=value" pairs.
+func lookupEnv(env []string, key string) string {
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// applyEnv overrides fields from ROADBOOK_* variables. Setting both X and X_FILE is an error,
+// as is any ROADBOOK_* variable that matches no field, so typos do not go unnoticed.
+func applyEnv(cfg *Config, env []string) error {
+	byEnv := make(map[string]Field)
+	for _, f := range Fields() {
+		byEnv[f.Env] = f
+	}
+	values := make(map[string]string)
+	for _, kv := range env {
+		k, v, ok := strings.Cut(kv, "=")
+		if ok && strings.HasPrefix(k, EnvPrefix) {
+			values[k] = v
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for k := range values {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		raw := values[k]
+		f, ok := byEnv[k]
+		if !ok {
+			base := strings.TrimSuffix(k, "_FILE")
+			if f, ok = byEnv[base]; !ok || base == k {
+				return fmt.Errorf("environment variable %s does not match any config field", k)
+			}
+			if _, both := values[base]; both {
+				return fmt.Errorf("environment variables %s and %s are both set", base, k)
+			}
+			content, err := os.ReadFile(raw)
+			if err != nil {
+				return fmt.Errorf("environment variable %s: %w", k, err)
+			}
+			raw = strings.TrimRight(string(content), "\r\n")
+		}
+		if err := f.set(cfg, raw); err != nil {
+			return fmt.Errorf("environment variable %s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// applyFlags overrides fields from command-line values keyed by field path.
+func applyFlags(cfg *Config, flags map[string]string) error {
+	byPath := make(map[string]Field)
+	for _, f := range Fields() {
+		byPath[f.Path] = f
+	}
+	paths := make([]string, 0, len(flags))
+	for p := range flags {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		f, ok := byPath[p]
+		if !ok {
+			return fmt.Errorf("flag -%s does not match any config field", p)
+		}
+		if err := f.set(cfg, flags[p]); err != nil {
+			return fmt.Errorf("flag -%s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// set parses raw according to the field type and stores it in cfg. Scalars use their usual
+// text form; string lists accept "a,b,c" or a JSON array; string maps accept "k=v,k2=v2" or
+// a JSON object; anything else (users, rate-limit policies, JWT keys) must be JSON.
+func (f Field) set(cfg *Config, raw string) error {
+	v := reflect.ValueOf(cfg).Elem().FieldByIndex(f.index)
+	if f.mapKey == "" {
+		return f.setValue(v, raw)
+	}
+	// Map entries are not addressable; update a copy of the entry and store it back.
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	key := reflect.ValueOf(f.mapKey)
+	elem := reflect.New(v.Type().Elem()).Elem()
+	if existing := v.MapIndex(key); existing.IsValid() {
+		elem.Set(existing)
+	}
+	if err := f.setValue(elem.FieldByIndex(f.elemIndex), raw); err != nil {
+		return err
+	}
+	v.SetMapIndex(key, elem)
+	return nil
+}
+
+// setValue parses raw according to the field type and stores it in v.
+func (f Field) setValue(v reflect.Value, raw string) error {
+	trimmed := strings.TrimSpace(raw)
+	switch {
+	case f.typ.Kind() == reflect.String:
+		v.SetString(raw)
+	case f.typ.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(trimmed)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case f.typ.Kind() == reflect.Int:
+		n, err := strconv.Atoi(trimmed)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(int64(n))
+	case f.typ.Kind() == reflect.Float64:
+		x, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(x)
+	case f.typ == reflect.TypeOf([]string(nil)) && !strings.HasPrefix(trimmed, "["):
+		list := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	case f.typ == reflect.TypeOf(map[string]string(nil)) && !strings.HasPrefix(trimmed, "{"):
+		m := make(map[string]string)
+		for _, pair := range strings.Split(raw, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			k, val, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("invalid key=value pair %q", pair)
+			}
+			m[strings.TrimSpace(k)] = strings.TrimSpace(val)
+		}
+		v.Set(reflect.ValueOf(m))
+	default:
+		// Decode into a fresh value so the override replaces rather than merges.
+		ptr := reflect.New(f.typ)
+		if err := json.Unmarshal([]byte(raw), ptr.Interface()); err != nil {
+			return fmt.Errorf("invalid JSON: %w", err)
+		}
+		v.Set(ptr.Elem())
+	}
+	return nil
+}
+
+// BindFlags defines one flag per config field on fs, named by its JSON path (e.g.
+// -ai.base_url or --tls.enabled). Values given on the command line are collected into the
+// returned map, which is meant for Sources.Flags.
+func BindFlags(fs *flag.FlagSet) map[string]string {
+	values := make(map[string]string)
+	for _, f := range Fields() {
+		usage := "overrides " + f.Path + " (env " + f.Env + ")"
+		fs.Var(&flagValue{path: f.Path, values: values, isBool: f.typ.Kind() == reflect.Bool}, f.Path, usage)
+	}
+	return values
+}
+
+type flagValue struct {
+	path   string
+	values map[string]string
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil || v.values == nil {
+		return ""
+	}
+	return v.values[v.path]
+}
+
+func (v *flagValue) Set(s string) error {
+	v.values[v.path] = s
+	return nil
+}
+
+// IsBoolFlag lets boolean fields be given as a bare -tls.enabled.
+func (v *flagValue) IsBoolFlag() bool { return v.isBool }
+
+// MaskSecrets returns a copy of cfg with every non-empty field tagged secret:"true"
+// replaced by a placeholder, including those inside map and slice elements.
+func MaskSecrets(cfg Config) Config {
+	// A JSON round trip gives a deep copy, so masking cannot touch the caller's maps.
+	var masked Config
+	data, _ := json.Marshal(cfg)
+	json.Unmarshal(data, &masked)
+	maskValue(reflect.ValueOf(&masked).Elem())
+	return masked
+}
+
+func maskValue(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			if v.Type().Field(i).Tag.Get("secret") == "true" {
+				maskSecret(field)
+				continue
+			}
+			maskValue(field)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			maskValue(v.Index(i))
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			// Map elements are not addressable; mask a copy and store it back.
+			elem := reflect.New(iter.Value().Type()).Elem()
+			elem.Set(iter.Value())
+			maskValue(elem)
+			v.SetMapIndex(iter.Key(), elem)
+		}
+	}
+}
+
+// maskSecret masks a field tagged secret:"true": a string, or every value of a map of
+// strings (e.g. tracing.headers, whose keys are header names worth seeing).
+func maskSecret(v reflect.Value) {
+	switch {
+	case v.Kind() == reflect.String:
+		if v.String() != "" {
+			v.SetString(secretMask)
+		}
+	case v.Kind() == reflect.Map && v.Type().Elem().Kind() == reflect.String:
+		iter := v.MapRange()
+		for iter.Next() {
+			if iter.Value().String() != "" {
+				v.SetMapIndex(iter.Key(), reflect.ValueOf(secretMask).Convert(v.Type().Elem()))
+			}
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig 写入一个最小可用的配置文件
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testHash 是一个格式正确的 SHA-256 十六进制摘要
+const testHash = "5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8"
+
+const baseConfig = `{"port": 5436, "jwtSecret": "file-secret", "users": {"admin": {"salt": "s", "hash": "` + testHash + `", "role": "admin"}}}`
+
+func TestEnvName(t *testing.T) {
+	for path, want := range map[string]string{
+		"port":                            "ROADBOOK_PORT",
+		"jwtSecret":                       "ROADBOOK_JWT_SECRET",
+		"rate_limit.redis.addr":           "ROADBOOK_RATE_LIMIT_REDIS_ADDR",
+		"search.providers.gaode.key":      "ROADBOOK_SEARCH_PROVIDERS_GAODE_KEY",
+		"server.shutdown_timeout_seconds": "ROADBOOK_SERVER_SHUTDOWN_TIMEOUT_SECONDS",
+	} {
+		if got := envName(path); got != want {
+			t.Errorf("envName(%q) = %s, 期望 %s", path, got, want)
+		}
+	}
+}
+
+func TestLoadSources_Precedence(t *testing.T) {
+	path := writeConfig(t, baseConfig)
+	secretFile := filepath.Join(t.TempDir(), "ai_key")
+	os.WriteFile(secretFile, []byte("sk-from-file\n"), 0o600)
+
+	cfg, err := LoadSources(Sources{
+		File: path,
+		Env: []string{
+			"ROADBOOK_PORT=7000",
+			"ROADBOOK_JWT_SECRET=env-secret",
+			"ROADBOOK_AI_ENABLED=true",
+			"ROADBOOK_AI_BASE_URL=https://api.example.com",
+			"ROADBOOK_AI_KEY_FILE=" + secretFile,
+			"ROADBOOK_ALLOWED_ORIGINS=https://a.example.com, https://b.example.com",
+			"ROADBOOK_TRACING_HEADERS=x-api-key=k1,x-team=roadbook",
+			`ROADBOOK_RATE_LIMIT_POLICIES={"search": {"rate": 1, "burst": 2}}`,
+			"PATH=/usr/bin",
+		},
+		Flags: map[string]string{"port": "8000", "tls.disable_http2": "true"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 8000 {
+		t.Errorf("命令行参数应覆盖环境变量, port=%d", cfg.Port)
+	}
+	if cfg.JwtSecret != "env-secret" {
+		t.Errorf("环境变量应覆盖配置文件, jwtSecret=%q", cfg.JwtSecret)
+	}
+	if cfg.AI.Key != "sk-from-file" || !cfg.AI.Enabled || cfg.AI.BaseURL != "https://api.example.com" {
+		t.Errorf("AI 配置错误: %+v", cfg.AI)
+	}
+	if len(cfg.AllowedOrigins) != 2 || cfg.AllowedOrigins[1] != "https://b.example.com" {
+		t.Errorf("逗号分隔的列表解析错误: %q", cfg.AllowedOrigins)
+	}
+	if cfg.Tracing.Headers["x-team"] != "roadbook" {
+		t.Errorf("key=value 列表解析错误: %v", cfg.Tracing.Headers)
+	}
+	if p := cfg.RateLimit.Policies["search"]; p.Rate != 1 || p.Burst != 2 {
+		t.Errorf("JSON 值解析错误: %+v", cfg.RateLimit.Policies)
+	}
+	if !cfg.TLS.DisableHTTP2 {
+		t.Error("布尔参数未生效")
+	}
+	if cfg.Users["admin"].Hash != testHash {
+		t.Error("未覆盖的字段应保留配置文件中的值")
+	}
+}
+
+func TestLoadSources_SearchProviderFields(t *testing.T) {
+	RegisterSearchProvider("env-test")
+	path := writeConfig(t, strings.TrimSuffix(baseConfig, "}")+`, "search": {"providers": {"env-test": {"login_required": true}, "other": {"key": "o"}}}}`)
+	cfg, err := LoadSources(Sources{
+		File:  path,
+		Env:   []string{"ROADBOOK_SEARCH_PROVIDERS_ENV_TEST_KEY=k"},
+		Flags: map[string]string{"search.providers.env-test.disabled": "true"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := cfg.Search.Providers.Get("env-test"); p.Key != "k" || !p.LoginRequired || !p.Disabled {
+		t.Errorf("已注册搜索源的环境变量与命令行参数应只覆盖对应字段: %+v", p)
+	}
+	if cfg.Search.Providers.Get("other").Key != "o" {
+		t.Error("其他搜索源的配置应保留")
+	}
+	if masked := MaskSecrets(cfg); masked.Search.Providers.Get("env-test").Key != secretMask {
+		t.Error("搜索源的 key 应隐藏")
+	}
+
+	// 配置文件中没有该搜索源时也能单独设置
+	cfg, err = LoadSources(Sources{File: writeConfig(t, baseConfig), Env: []string{"ROADBOOK_SEARCH_PROVIDERS_ENV_TEST_KEY=k"}})
+	if err != nil || cfg.Search.Providers.Get("env-test").Key != "k" {
+		t.Errorf("应创建搜索源的配置项: %+v, %v", cfg.Search.Providers, err)
+	}
+}
+
+func TestLoadSources_WithoutDefaultFile(t *testing.T) {
+	// 默认路径 configs/config.json 不存在
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	cfg, err := LoadSources(Sources{Env: []string{
+		"ROADBOOK_JWT_SECRET=s",
+		`ROADBOOK_USERS={"admin": {"salt": "s", "hash": "` + testHash + `"}}`,
+	}})
+	if err != nil {
+		t.Fatalf("默认配置文件不存在时应只使用环境变量: %v", err)
+	}
+	if cfg.Port != 8080 || cfg.Users["admin"].Salt != "s" {
+		t.Errorf("配置错误: %+v", cfg)
+	}
+
+	// 显式指定的配置文件必须存在
+	if _, err := LoadSources(Sources{Env: []string{"CONFIG_FILE=missing.json"}}); err == nil {
+		t.Error("CONFIG_FILE 指向的文件不存在时应返回错误")
+	}
+}
+
+func TestLoadSources_Errors(t *testing.T) {
+	path := writeConfig(t, baseConfig)
+	for name, src := range map[string]Sources{
+		"未知环境变量":       {Env: []string{"ROADBOOK_PROT=1"}},
+		"未知的 _FILE 变量": {Env: []string{"ROADBOOK_NOPE_FILE=/tmp/x"}},
+		"同时设置值与文件":     {Env: []string{"ROADBOOK_JWT_SECRET=a", "ROADBOOK_JWT_SECRET_FILE=/tmp/x"}},
+		"密钥文件不存在":      {Env: []string{"ROADBOOK_JWT_SECRET_FILE=" + filepath.Join(t.TempDir(), "missing")}},
+		"整数格式错误":       {Env: []string{"ROADBOOK_PORT=abc"}},
+		"JSON 格式错误":    {Env: []string{"ROADBOOK_USERS={"}},
+		"未知命令行参数":      {Flags: map[string]string{"nope": "1"}},
+	} {
+		src.File = path
+		if _, err := LoadSources(src); err == nil {
+			t.Errorf("%s: 应返回错误", name)
+		}
+	}
+}
+
+func TestBindFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	values := BindFlags(fs)
+	if err := fs.Parse([]string{"--tls.enabled", "-ai.base_url=https://x", "--rate_limit.redis.db", "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if values["tls.enabled"] != "true" || values["ai.base_url"] != "https://x" || values["rate_limit.redis.db"] != "2" {
+		t.Errorf("参数解析错误: %v", values)
+	}
+}
+
+func TestMaskSecrets(t *testing.T) {
+	cfg := Config{
+		JwtSecret: "secret",
+		Users:     map[string]UserCredentials{"admin": {Salt: "s", Hash: "h"}},
+		AI:        AIConfig{BaseURL: "https://api.example.com", Key: "sk"},
+		Tracing:   TracingConfig{Headers: map[string]string{"Authorization": "Bearer abc", "X-Empty": ""}},
+	}
+	masked := MaskSecrets(cfg)
+	if masked.Tracing.Headers["Authorization"] != secretMask || masked.Tracing.Headers["X-Empty"] != "" {
+		t.Errorf("tracing.headers 的值应隐藏, 请求头名称保留: %v", masked.Tracing.Headers)
+	}
+	if cfg.Tracing.Headers["Authorization"] != "Bearer abc" {
+		t.Error("隐藏 tracing.headers 不应修改原配置")
+	}
+	if masked.JwtSecret != secretMask || masked.Users["admin"].Hash != secretMask || masked.AI.Key != secretMask {
+		t.Errorf("密钥未隐藏: %+v", masked)
+	}
+	if masked.AI.BaseURL != "https://api.example.com" || masked.Users["admin"].Salt != "s" {
+		t.Error("非密钥字段不应修改")
+	}
+	if masked.OIDC.ClientSecret != "" {
+		t.Error("空的密钥应保持为空，便于看出未配置")
+	}
+	if cfg.Users["admin"].Hash != "h" {
+		t.Error("不应修改原配置")
+	}
+
+	// Fields 应标记出密钥字段
+	var secrets []string
+	for _, f := range Fields() {
+		if f.Secret {
+			secrets = append(secrets, f.Path)
+		}
+	}
+	if got := strings.Join(secrets, ","); !strings.Contains(got, "jwtSecret") || !strings.Contains(got, "ai.key") || !strings.Contains(got, "tracing.headers") {
+		t.Errorf("secret 字段列表错误: %s", got)
+	}
+}
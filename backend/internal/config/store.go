@@ -0,0 +1,153 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReloadHook prepares a component for a new config. It must not change any state; instead
+// it returns a commit func that applies the prepared change and cannot fail. If any hook
+// returns an error the reload is rejected and the old config stays active everywhere.
+type ReloadHook func(next *Config) (commit func(), err error)
+
+// Store holds the active config behind an atomically swappable pointer. Request handlers
+// call Current once per request and keep using that snapshot, so a reload never changes
+// the config underneath a running AI stream. Snapshots must be treated as read-only.
+type Store struct {
+	current atomic.Pointer[Config]
+	load    func() (Config, error)
+
+	mu    sync.Mutex // serializes reloads
+	hooks []ReloadHook
+}
+
+// NewStore returns a store holding cfg. load produces a fresh, validated config on each
+// reload, normally by calling LoadSources with the sources used at startup.
+func NewStore(cfg Config, load func() (Config, error)) *Store {
+	s := &Store{load: load}
+	s.current.Store(&cfg)
+	return s
+}
+
+// Current returns the active config.
+func (s *Store) Current() *Config {
+	return s.current.Load()
+}
+
+// OnReload registers a hook that runs, in registration order, before a new config is
+// activated.
+func (s *Store) OnReload(hook ReloadHook) {
+	s.mu.Lock()
+	s.hooks = append(s.hooks, hook)
+	s.mu.Unlock()
+}
+
+// Reload loads and validates the config again and activates it. An invalid config, or one
+// a hook refuses, is rejected and the current config stays active.
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, err := s.load()
+	if err != nil {
+		return err
+	}
+	commits := make([]func(), 0, len(s.hooks))
+	for _, hook := range s.hooks {
+		commit, err := hook(&next)
+		if err != nil {
+			return err
+		}
+		if commit != nil {
+			commits = append(commits, commit)
+		}
+	}
+
+	prev := s.current.Load()
+	s.current.Store(&next)
+	for _, commit := range commits {
+		commit()
+	}
+	for _, issue := range Validate(next) {
+		slog.Warn("config warning", "path", issue.Path, "message", issue.Message)
+	}
+	if fields := RestartRequired(*prev, next); len(fields) > 0 {
+		slog.Warn("配置已重新加载，但以下字段的修改需要重启后生效", "fields", fields)
+	} else {
+		slog.Info("配置已重新加载")
+	}
+	return nil
+}
+
+// restartFields are read once at startup: listeners, middleware chains built with the
+// router, and background components. Everything else is read per request.
+var restartFields = []struct {
+	path string
+	get  func(Config) interface{}
+}{
+	{"port", func(c Config) interface{} { return c.Port }},
+	{"trusted_proxies", func(c Config) interface{} { return c.TrustedProxies }},
+	{"real_ip_header", func(c Config) interface{} { return c.RealIPHeader }},
+	{"oidc", func(c Config) interface{} { return c.OIDC }},
+	{"login_protection", func(c Config) interface{} { return c.LoginProtection }},
+	{"rate_limit", func(c Config) interface{} { return c.RateLimit }},
+	{"log", func(c Config) interface{} { return c.Log }},
+	{"metrics", func(c Config) interface{} { return c.Metrics }},
+	{"tracing", func(c Config) interface{} { return c.Tracing }},
+	{"server", func(c Config) interface{} { return c.Server }},
+	{"tls", func(c Config) interface{} { return c.TLS }},
+	{"static", func(c Config) interface{} { return c.Static }},
+	{"compression", func(c Config) interface{} { return c.Compression }},
+	{"search.cache", func(c Config) interface{} { return c.Search.Cache }},
+}
+
+// RestartRequired lists the fields that differ between prev and next but only
+// take effect after a restart.
+func RestartRequired(prev, next Config) []string {
+	var fields []string
+	for _, f := range restartFields {
+		if !reflect.DeepEqual(f.get(prev), f.get(next)) {
+			fields = append(fields, f.path)
+		}
+	}
+	return fields
+}
+
+// WatchFile reloads the store whenever path's modification time or size changes, checking
+// every interval until ctx is done. Polling keeps working when editors replace the file
+// or Kubernetes swaps a ConfigMap symlink, where inotify-style watches are lost.
+func (s *Store) WatchFile(ctx context.Context, path string, interval time.Duration) {
+	stamp := func() string {
+		info, err := os.Stat(path)
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())
+	}
+	last := stamp()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current := stamp()
+		if current == last || current == "" {
+			// Unchanged, or mid-replace; a missing file is picked up once it reappears.
+			continue
+		}
+		last = current
+		slog.Info("检测到配置文件变化，重新加载", "path", path)
+		if err := s.Reload(); err != nil {
+			slog.Error("新配置无效，继续使用当前配置", "error", err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore_Reload(t *testing.T) {
+	path := writeConfig(t, baseConfig)
+	store := NewStore(validConfig(), func() (Config, error) { return LoadSources(Sources{File: path}) })
+	old := store.Current()
+
+	var committed *Config
+	store.OnReload(func(next *Config) (func(), error) {
+		return func() { committed = next }, nil
+	})
+	if err := store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if store.Current().JwtSecret != "file-secret" || committed != store.Current() {
+		t.Errorf("重新加载后应使用新配置: %+v", store.Current())
+	}
+	if old.JwtSecret != strings.Repeat("k", 64) {
+		t.Error("旧的快照不应被修改")
+	}
+}
+
+func TestStore_RejectsInvalidConfig(t *testing.T) {
+	path := writeConfig(t, baseConfig)
+	store := NewStore(validConfig(), func() (Config, error) { return LoadSources(Sources{File: path}) })
+	active := store.Current()
+
+	// 新配置本身无效
+	os.WriteFile(path, []byte(`{"port": 5436, "users": {}}`), 0o600)
+	var invalid *ValidationError
+	if err := store.Reload(); !errors.As(err, &invalid) {
+		t.Fatalf("应返回 ValidationError, 得到 %v", err)
+	}
+	if store.Current() != active {
+		t.Fatal("无效的新配置不应生效")
+	}
+
+	// 组件拒绝新配置时，已准备的组件也不提交
+	os.WriteFile(path, []byte(baseConfig), 0o600)
+	committed := false
+	store.OnReload(func(*Config) (func(), error) { return func() { committed = true }, nil })
+	store.OnReload(func(*Config) (func(), error) { return nil, errors.New("bad key file") })
+	if err := store.Reload(); err == nil || store.Current() != active || committed {
+		t.Fatalf("组件拒绝时应保留旧配置, err=%v committed=%v", err, committed)
+	}
+}
+
+func TestRestartRequired(t *testing.T) {
+	prev := validConfig()
+	next := validConfig()
+	next.AllowedOrigins = []string{"https://new.example.com"}
+	next.Search.Providers = SearchProviders{"gaode": {Key: "k"}}
+	if fields := RestartRequired(prev, next); len(fields) != 0 {
+		t.Errorf("CORS 与搜索配置可以热加载, 得到 %v", fields)
+	}
+	next.Port = 9000
+	next.RateLimit.Store = "redis"
+	next.Search.Cache.TTLSeconds = 60
+	if got := strings.Join(RestartRequired(prev, next), ","); got != "port,rate_limit,search.cache" {
+		t.Errorf("期望 port,rate_limit,search.cache 需要重启, 得到 %s", got)
+	}
+}
+
+func TestStore_WatchFile(t *testing.T) {
+	path := writeConfig(t, baseConfig)
+	store := NewStore(validConfig(), func() (Config, error) { return LoadSources(Sources{File: path}) })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go store.WatchFile(ctx, path, 10*time.Millisecond)
+
+	time.Sleep(30 * time.Millisecond)
+	os.WriteFile(path, []byte(strings.Replace(baseConfig, "file-secret", "changed-secret", 1)), 0o600)
+	for i := 0; i < 200; i++ {
+		if store.Current().JwtSecret == "changed-secret" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("配置文件变化后应自动重新加载")
+}
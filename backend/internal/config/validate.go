@@ -0,0 +1,443 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Issue is one problem found by Validate, located by its JSON path, e.g. "users.admin.hash".
+type Issue struct {
+	Path    string
+	Message string
+	// Warning marks issues that do not stop the server from starting, such as a weak secret.
+	Warning bool
+}
+
+func (i Issue) String() string {
+	level := "error"
+	if i.Warning {
+		level = "warning"
+	}
+	return fmt.Sprintf("%s: %s: %s", level, i.Path, i.Message)
+}
+
+// ValidationError is returned by LoadSources when Validate finds at least one error.
+// Issues holds everything that was found, warnings included.
+type ValidationError struct {
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	var lines []string
+	for _, issue := range e.Issues {
+		if !issue.Warning {
+			lines = append(lines, issue.Path+": "+issue.Message)
+		}
+	}
+	return fmt.Sprintf("invalid config (%d errors):\n  %s", len(lines), strings.Join(lines, "\n  "))
+}
+
+// HasErrors reports whether any issue is an error rather than a warning.
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if !issue.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+// Values accepted by several fields. They mirror the constants in the auth, middleware
+// and logging packages, which cannot be imported here without a cycle.
+var (
+	validRoles          = []string{"", "admin", "member", "readonly"}
+	validRateLimitKeys  = []string{"", "ip", "user", "token"}
+	rateLimitRoutes     = []string{"login", "search", "ai", "share"}
+	validLogLevels      = []string{"", "debug", "info", "warn", "error"}
+	validLogFormats     = []string{"", "json", "text"}
+	validUsernameClaims = []string{"", "email", "sub", "preferred_username"}
+)
+
+// minJWTSecretLength is the length below which an HS256 secret is reported as weak;
+// `openssl rand -hex 32`, as used by scripts/generate_config.sh, gives 64 characters.
+const minJWTSecretLength = 32
+
+// Credentials shipped in configs/config.json and the README. Anyone can mint tokens with
+// the example secret or log in with the example password, so using them is flagged.
+const (
+	exampleJWTSecret = "8f9bd0d6162dc18dc48853a3c348218bb464005ed83c3f7c1c3e75b4f326d0a3"
+	exampleUserHash  = "77aba870b69a0b23c01295e2aab23447d6c9e16705a9faa2ddd1367b3cd0f365"
+)
+
+var sha256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// validator collects issues while walking the config.
+type validator struct {
+	issues []Issue
+}
+
+func (v *validator) errorf(path, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warnf(path, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{Path: path, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+// oneOf checks value against allowed, whose first entry is the empty default.
+func (v *validator) oneOf(path, value string, allowed []string) {
+	if contains(allowed, value) {
+		return
+	}
+	v.errorf(path, "must be one of %s, got %q", strings.Join(allowed[1:], ", "), value)
+}
+
+func (v *validator) nonNegative(path string, n int) {
+	if n < 0 {
+		v.errorf(path, "must not be negative, got %d", n)
+	}
+}
+
+func (v *validator) port(path string, p int) {
+	if p < 1 || p > 65535 {
+		v.errorf(path, "must be between 1 and 65535, got %d", p)
+	}
+}
+
+// httpURL checks that s is an absolute http or https URL.
+func (v *validator) httpURL(path, s string) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.errorf(path, "must be an absolute http(s) URL, got %q", s)
+	}
+}
+
+// Validate checks cfg as a whole and returns every problem found, sorted by path.
+// Map entries are visited in sorted key order so the output is stable.
+func Validate(cfg Config) []Issue {
+	v := &validator{}
+
+	v.port("port", cfg.Port)
+	for i, origin := range cfg.AllowedOrigins {
+		v.origin(fmt.Sprintf("allowed_origins[%d]", i), origin)
+	}
+	if cfg.AllowNullOriginForDev {
+		v.warnf("allow_null_origin_for_dev", "accepts requests from file:// pages; disable in production")
+	}
+	v.headerNames("cors.allowed_methods", cfg.CORS.AllowedMethods)
+	v.headerNames("cors.allowed_headers", cfg.CORS.AllowedHeaders)
+	v.headerNames("cors.exposed_headers", cfg.CORS.ExposedHeaders)
+	v.nonNegative("cors.max_age_seconds", cfg.CORS.MaxAgeSeconds)
+	for i, proxy := range cfg.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				v.errorf(fmt.Sprintf("trusted_proxies[%d]", i), "must be an IP address or CIDR, got %q", proxy)
+			}
+		}
+	}
+
+	v.jwt(cfg)
+	v.users(cfg.Users)
+
+	v.nonNegative("search.federated.timeout_millis", cfg.Search.Federated.TimeoutMillis)
+	if cfg.Search.Federated.DedupDistanceMeters < 0 {
+		v.errorf("search.federated.dedup_distance_meters", "must not be negative, got %g", cfg.Search.Federated.DedupDistanceMeters)
+	}
+	seen := make(map[string]bool, len(cfg.Search.Fallback))
+	for i, name := range cfg.Search.Fallback {
+		path := fmt.Sprintf("search.fallback[%d]", i)
+		switch {
+		case name == "" || name == "all":
+			v.errorf(path, "must name a single provider, got %q", name)
+		case seen[name]:
+			v.errorf(path, "lists %q twice", name)
+		}
+		seen[name] = true
+	}
+	v.nonNegative("search.circuit_breaker.failure_threshold", cfg.Search.CircuitBreaker.FailureThreshold)
+	v.nonNegative("search.circuit_breaker.cooldown_seconds", cfg.Search.CircuitBreaker.CooldownSeconds)
+	v.nonNegative("search.cache.max_entries", cfg.Search.Cache.MaxEntries)
+	v.nonNegative("search.cache.ttl_seconds", cfg.Search.Cache.TTLSeconds)
+	if gaode := cfg.Search.Providers.Get("gaode"); gaode.LoginRequired && gaode.Key == "" {
+		v.warnf("search.providers.gaode.login_required", "has no effect while search.providers.gaode.key is empty")
+	}
+
+	if cfg.AI.Enabled {
+		if cfg.AI.BaseURL == "" {
+			v.errorf("ai.base_url", "is required when ai is enabled")
+		} else {
+			v.httpURL("ai.base_url", cfg.AI.BaseURL)
+		}
+		if cfg.AI.Model == "" {
+			v.warnf("ai.model", "is empty; the provider must have a default model")
+		}
+		if cfg.AI.Key == "" {
+			v.warnf("ai.key", "is empty; only providers without authentication will work")
+		}
+	}
+
+	if cfg.OIDC.Enabled {
+		for path, value := range map[string]string{
+			"oidc.issuer":       cfg.OIDC.Issuer,
+			"oidc.client_id":    cfg.OIDC.ClientID,
+			"oidc.redirect_url": cfg.OIDC.RedirectURL,
+		} {
+			if value == "" {
+				v.errorf(path, "is required when oidc is enabled")
+			}
+		}
+		if cfg.OIDC.Issuer != "" {
+			v.httpURL("oidc.issuer", cfg.OIDC.Issuer)
+		}
+		if cfg.OIDC.RedirectURL != "" {
+			v.httpURL("oidc.redirect_url", cfg.OIDC.RedirectURL)
+		}
+		if cfg.OIDC.FrontendRedirectURL != "" {
+			v.httpURL("oidc.frontend_redirect_url", cfg.OIDC.FrontendRedirectURL)
+		}
+		v.oneOf("oidc.username_claim", cfg.OIDC.UsernameClaim, validUsernameClaims)
+		for _, claim := range sortedKeys(cfg.OIDC.UserMapping) {
+			if _, ok := cfg.Users[cfg.OIDC.UserMapping[claim]]; !ok {
+				v.errorf("oidc.user_mapping."+claim, "refers to unknown user %q", cfg.OIDC.UserMapping[claim])
+			}
+		}
+	}
+
+	lp := cfg.LoginProtection
+	v.nonNegative("login_protection.max_failures", lp.MaxFailures)
+	v.nonNegative("login_protection.base_lockout_seconds", lp.BaseLockoutSeconds)
+	v.nonNegative("login_protection.max_lockout_seconds", lp.MaxLockoutSeconds)
+	v.nonNegative("login_protection.global_max_failures", lp.GlobalMaxFailures)
+	v.nonNegative("login_protection.global_window_seconds", lp.GlobalWindowSeconds)
+
+	v.rateLimit(cfg.RateLimit)
+
+	v.oneOf("log.level", strings.ToLower(cfg.Log.Level), validLogLevels)
+	v.oneOf("log.format", strings.ToLower(cfg.Log.Format), validLogFormats)
+
+	if cfg.Metrics.Enabled && cfg.Metrics.Token == "" {
+		v.warnf("metrics.token", "is empty; /metrics is readable by anyone who can reach the server")
+	}
+
+	if cfg.Tracing.Enabled {
+		if cfg.Tracing.Endpoint == "" {
+			v.errorf("tracing.endpoint", "is required when tracing is enabled")
+		} else {
+			v.httpURL("tracing.endpoint", cfg.Tracing.Endpoint)
+		}
+	}
+	if r := cfg.Tracing.SampleRatio; r < 0 || r > 1 {
+		v.errorf("tracing.sample_ratio", "must be between 0 and 1, got %g", r)
+	}
+
+	v.nonNegative("server.read_header_timeout_seconds", cfg.Server.ReadHeaderTimeoutSeconds)
+	v.nonNegative("server.read_timeout_seconds", cfg.Server.ReadTimeoutSeconds)
+	v.nonNegative("server.write_timeout_seconds", cfg.Server.WriteTimeoutSeconds)
+	v.nonNegative("server.idle_timeout_seconds", cfg.Server.IdleTimeoutSeconds)
+	v.nonNegative("server.shutdown_timeout_seconds", cfg.Server.ShutdownTimeoutSeconds)
+
+	if cfg.TLS.Enabled {
+		if cfg.TLS.CertFile == "" {
+			v.errorf("tls.cert_file", "is required when tls is enabled")
+		}
+		if cfg.TLS.KeyFile == "" {
+			v.errorf("tls.key_file", "is required when tls is enabled")
+		}
+		if p := cfg.TLS.HTTPRedirectPort; p != 0 {
+			v.port("tls.http_redirect_port", p)
+			if p == cfg.Port {
+				v.errorf("tls.http_redirect_port", "must differ from port")
+			}
+		}
+	}
+	v.nonNegative("static.max_age_seconds", cfg.Static.MaxAgeSeconds)
+	if l := cfg.Compression.Level; l < 0 || l > 9 {
+		v.errorf("compression.level", "must be between 1 and 9, or 0 for the default, got %d", l)
+	}
+	if l := cfg.Compression.BrotliLevel; l < 0 || l > 11 {
+		v.errorf("compression.brotli_level", "must be between 1 and 11, or 0 for the default, got %d", l)
+	}
+	v.nonNegative("compression.min_size_bytes", cfg.Compression.MinSizeBytes)
+
+	sort.SliceStable(v.issues, func(i, j int) bool { return v.issues[i].Path < v.issues[j].Path })
+	return v.issues
+}
+
+// origin checks an allowed_origins entry: scheme://host[:port] without a path. The host
+// may start with "*." to allow every subdomain, e.g. https://*.example.com.
+func (v *validator) origin(path, origin string) {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		v.errorf(path, "must be an origin like https://example.com, got %q", origin)
+		return
+	}
+	if strings.Contains(strings.TrimPrefix(u.Host, "*."), "*") || strings.HasPrefix(u.Hostname(), "*.") && !strings.Contains(u.Hostname()[2:], ".") {
+		v.errorf(path, "wildcards are only allowed as the leftmost label below a registrable domain, like https://*.example.com, got %q", origin)
+		return
+	}
+	if u.Path == "/" {
+		v.errorf(path, "must not end with a slash; browsers send %q", strings.TrimSuffix(origin, "/"))
+	}
+}
+
+// headerNames checks a list of HTTP methods or header names, one per entry.
+func (v *validator) headerNames(path string, names []string) {
+	for i, name := range names {
+		if name == "" || strings.ContainsAny(name, " ,\t") {
+			v.errorf(fmt.Sprintf("%s[%d]", path, i), "must be a single name without spaces or commas, got %q", name)
+		}
+	}
+}
+
+func (v *validator) jwt(cfg Config) {
+	if cfg.JwtSecret == "" && len(cfg.JWT.Keys) == 0 {
+		v.errorf("jwtSecret", "is required unless jwt.keys is set")
+	}
+	switch {
+	case cfg.JwtSecret == "":
+	case cfg.JwtSecret == exampleJWTSecret:
+		v.warnf("jwtSecret", "is the example secret from the repository; anyone can forge tokens with it")
+	case len(cfg.JwtSecret) < minJWTSecretLength:
+		v.warnf("jwtSecret", "is only %d characters; use at least %d random characters (openssl rand -hex 32)", len(cfg.JwtSecret), minJWTSecretLength)
+	}
+
+	if len(cfg.JWT.Keys) == 0 {
+		if cfg.JWT.HS256IssuedBefore != "" {
+			v.warnf("jwt.hs256_issued_before", "has no effect while jwt.keys is empty")
+		}
+		return
+	}
+	switch cutoff := cfg.JWT.HS256IssuedBefore; {
+	case cutoff == "" && cfg.JwtSecret != "":
+		v.warnf("jwtSecret", "no longer validates tokens while jwt.keys is set; set jwt.hs256_issued_before to accept HS256 tokens issued before the switch, or remove jwtSecret")
+	case cutoff == "":
+	case cfg.JwtSecret == "":
+		v.warnf("jwt.hs256_issued_before", "has no effect while jwtSecret is empty")
+	default:
+		t, err := time.Parse(time.RFC3339, cutoff)
+		switch {
+		case err != nil:
+			v.errorf("jwt.hs256_issued_before", "must be an RFC 3339 time such as 2025-06-01T00:00:00Z, got %q", cutoff)
+		case t.After(time.Now()):
+			v.errorf("jwt.hs256_issued_before", "is in the future; HS256 tokens minted until then would be accepted")
+		}
+	}
+	ids := make(map[string]bool)
+	signingKeyFound := false
+	for i, key := range cfg.JWT.Keys {
+		path := fmt.Sprintf("jwt.keys[%d]", i)
+		switch {
+		case key.ID == "":
+			v.errorf(path+".kid", "must not be empty")
+		case ids[key.ID]:
+			v.errorf(path+".kid", "duplicates key %q", key.ID)
+		}
+		ids[key.ID] = true
+		if key.PrivateKeyFile == "" && key.PublicKeyFile == "" {
+			v.errorf(path, "needs private_key_file or public_key_file")
+		}
+		if key.ID == cfg.JWT.SigningKeyID {
+			signingKeyFound = true
+			if key.PrivateKeyFile == "" {
+				v.errorf(path+".private_key_file", "is required for the signing key %q", key.ID)
+			}
+		}
+	}
+	switch {
+	case cfg.JWT.SigningKeyID == "":
+		v.errorf("jwt.signing_key_id", "is required when jwt.keys is set")
+	case !signingKeyFound:
+		v.errorf("jwt.signing_key_id", "refers to unknown key %q", cfg.JWT.SigningKeyID)
+	}
+}
+
+func (v *validator) users(users map[string]UserCredentials) {
+	if len(users) == 0 {
+		v.errorf("users", "must contain at least one user")
+		return
+	}
+	for _, name := range sortedKeys(users) {
+		creds, path := users[name], "users."+name
+		if name == "" {
+			v.errorf(path, "username must not be empty")
+		}
+		if creds.Salt == "" {
+			v.warnf(path+".salt", "is empty; the password hash is unsalted")
+		}
+		switch {
+		case creds.Hash == "":
+			v.errorf(path+".hash", "must not be empty")
+		case !sha256Hex.MatchString(creds.Hash):
+			v.errorf(path+".hash", "must be a lowercase hex SHA-256 digest (64 characters)")
+		case creds.Hash == exampleUserHash:
+			v.warnf(path+".hash", "is the example password from the README; change it")
+		}
+		v.oneOf(path+".role", creds.Role, validRoles)
+	}
+}
+
+func (v *validator) rateLimit(cfg RateLimitConfig) {
+	switch cfg.Store {
+	case "", "memory":
+	case "redis":
+		if cfg.Redis.Addr == "" {
+			v.errorf("rate_limit.redis.addr", "is required when rate_limit.store is redis")
+		}
+	default:
+		v.errorf("rate_limit.store", "must be memory or redis, got %q", cfg.Store)
+	}
+	v.nonNegative("rate_limit.redis.db", cfg.Redis.DB)
+	v.nonNegative("rate_limit.redis.pool_size", cfg.Redis.PoolSize)
+	v.nonNegative("rate_limit.redis.timeout_millis", cfg.Redis.TimeoutMillis)
+
+	policies := map[string]bool{}
+	for _, name := range rateLimitRoutes {
+		policies[name] = true // built-in policies share their route group's name
+	}
+	for _, name := range sortedKeys(cfg.Policies) {
+		p, path := cfg.Policies[name], "rate_limit.policies."+name
+		policies[name] = true
+		if name == "off" {
+			v.errorf(path, `"off" is reserved for disabling a route group`)
+		}
+		if p.Rate <= 0 {
+			v.errorf(path+".rate", "must be greater than 0")
+		}
+		if p.Burst <= 0 {
+			v.errorf(path+".burst", "must be greater than 0")
+		}
+		v.oneOf(path+".key", p.Key, validRateLimitKeys)
+	}
+	for _, group := range sortedKeys(cfg.Routes) {
+		path, policy := "rate_limit.routes."+group, cfg.Routes[group]
+		if !contains(rateLimitRoutes, group) {
+			v.errorf(path, "unknown route group; must be one of %s", strings.Join(rateLimitRoutes, ", "))
+		}
+		if policy != "off" && !policies[policy] {
+			v.errorf(path, "refers to unknown policy %q", policy)
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
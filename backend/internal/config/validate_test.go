@@ -0,0 +1,170 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func validConfig() Config {
+	return Config{
+		Port:      5436,
+		JwtSecret: strings.Repeat("k", 64),
+		Users:     map[string]UserCredentials{"admin": {Salt: "s", Hash: testHash, Role: "admin"}},
+	}
+}
+
+// issuesByPath 把检查结果按路径归类，值为 "error" 或 "warning"
+func issuesByPath(issues []Issue) map[string]string {
+	m := make(map[string]string)
+	for _, issue := range issues {
+		level := "error"
+		if issue.Warning {
+			level = "warning"
+		}
+		m[issue.Path] = level
+	}
+	return m
+}
+
+func TestValidate_ValidConfig(t *testing.T) {
+	if issues := Validate(validConfig()); len(issues) != 0 {
+		t.Fatalf("合法配置不应有问题: %v", issues)
+	}
+}
+
+func TestValidate_ReportsAllErrorsWithPaths(t *testing.T) {
+	cfg := validConfig()
+	cfg.Port = 70000
+	cfg.AllowedOrigins = []string{"https://ok.example.com", "https://slash.example.com/", "not-an-origin", "https://*.example.com", "https://*.com", "https://a.*.example.com"}
+	cfg.CORS = CORSConfig{AllowedHeaders: []string{"X-Ok", "X-A, X-B"}, MaxAgeSeconds: -1}
+	cfg.TrustedProxies = []string{"10.0.0.0/8", "nginx"}
+	cfg.Users["bob"] = UserCredentials{Salt: "s", Hash: "", Role: "owner"}
+	cfg.AI = AIConfig{Enabled: true}
+	cfg.OIDC = OIDCConfig{Enabled: true, Issuer: "accounts.example.com", UserMapping: map[string]string{"a@example.com": "carol"}}
+	cfg.RateLimit = RateLimitConfig{
+		Policies: map[string]RateLimitPolicy{"strict": {Rate: 0, Burst: 1, Key: "session"}},
+		Routes:   map[string]string{"search": "missing", "upload": "off"},
+	}
+	cfg.Log.Level = "verbose"
+	cfg.Tracing = TracingConfig{Enabled: true, SampleRatio: 2}
+	cfg.Server.ShutdownTimeoutSeconds = -1
+	cfg.TLS = TLSConfig{Enabled: true, HTTPRedirectPort: 70000}
+	cfg.JWT = JWTConfig{SigningKeyID: "k2", Keys: []JWTKeyConfig{{ID: "k1", PublicKeyFile: "k1.pub"}, {ID: "k1"}}}
+	cfg.Search.Fallback = []string{"baidu", "all", "baidu"}
+	cfg.Search.CircuitBreaker.CooldownSeconds = -1
+
+	got := issuesByPath(Validate(cfg))
+	for _, path := range []string{
+		"port",
+		"allowed_origins[1]",
+		"allowed_origins[2]",
+		"allowed_origins[4]",
+		"allowed_origins[5]",
+		"cors.allowed_headers[1]",
+		"cors.max_age_seconds",
+		"trusted_proxies[1]",
+		"users.bob.hash",
+		"users.bob.role",
+		"ai.base_url",
+		"oidc.issuer",
+		"oidc.client_id",
+		"oidc.redirect_url",
+		"oidc.user_mapping.a@example.com",
+		"rate_limit.policies.strict.rate",
+		"rate_limit.policies.strict.key",
+		"rate_limit.routes.search",
+		"rate_limit.routes.upload",
+		"log.level",
+		"tracing.endpoint",
+		"tracing.sample_ratio",
+		"server.shutdown_timeout_seconds",
+		"tls.cert_file",
+		"tls.key_file",
+		"tls.http_redirect_port",
+		"jwt.keys[1]",
+		"jwt.keys[1].kid",
+		"jwt.signing_key_id",
+		"search.fallback[1]",
+		"search.fallback[2]",
+		"search.circuit_breaker.cooldown_seconds",
+	} {
+		if got[path] != "error" {
+			t.Errorf("%s: 应报告错误, 得到 %q", path, got[path])
+		}
+	}
+	for _, path := range []string{"allowed_origins[0]", "allowed_origins[3]", "cors.allowed_headers[0]", "trusted_proxies[0]", "users.admin.hash", "search.fallback[0]"} {
+		if _, ok := got[path]; ok {
+			t.Errorf("%s: 合法的值不应报告问题", path)
+		}
+	}
+}
+
+func TestValidate_Warnings(t *testing.T) {
+	cfg := validConfig()
+	cfg.JwtSecret = "short"
+	cfg.AllowNullOriginForDev = true
+	cfg.Users["admin"] = UserCredentials{Hash: exampleUserHash}
+	cfg.Metrics.Enabled = true
+
+	issues := Validate(cfg)
+	if HasErrors(issues) {
+		t.Fatalf("只应有警告: %v", issues)
+	}
+	got := issuesByPath(issues)
+	for _, path := range []string{"jwtSecret", "allow_null_origin_for_dev", "users.admin.salt", "users.admin.hash", "metrics.token"} {
+		if got[path] != "warning" {
+			t.Errorf("%s: 应报告警告, 得到 %q", path, got[path])
+		}
+	}
+
+	cfg = validConfig()
+	cfg.JwtSecret = exampleJWTSecret
+	if issues := Validate(cfg); len(issues) != 1 || !strings.Contains(issues[0].Message, "example secret") {
+		t.Errorf("仓库中的示例密钥应报告警告: %v", issues)
+	}
+}
+
+func TestValidate_HS256Cutoff(t *testing.T) {
+	keys := JWTConfig{SigningKeyID: "k1", Keys: []JWTKeyConfig{{ID: "k1", PrivateKeyFile: "k1.pem"}}}
+	for name, tc := range map[string]struct {
+		cutoff string
+		want   string
+	}{
+		"同时配置 jwtSecret 与 jwt.keys 但未设置截止时间": {"", "warning"},
+		"截止时间格式错误":                           {"2025-06-01", "error"},
+		"截止时间在未来":                            {time.Now().Add(time.Hour).Format(time.RFC3339), "error"},
+		"截止时间在过去":                            {"2025-06-01T00:00:00Z", ""},
+	} {
+		cfg := validConfig()
+		cfg.JWT = keys
+		cfg.JWT.HS256IssuedBefore = tc.cutoff
+		got := issuesByPath(Validate(cfg))
+		path := "jwt.hs256_issued_before"
+		if tc.cutoff == "" {
+			path = "jwtSecret"
+		}
+		if got[path] != tc.want {
+			t.Errorf("%s: %s 应为 %q, 得到 %q", name, path, tc.want, got[path])
+		}
+	}
+}
+
+func TestLoadSources_ValidationError(t *testing.T) {
+	path := writeConfig(t, `{"port": 5436, "users": {"admin": {"salt": "s", "hash": ""}}, "ai": {"enabled": true}}`)
+	_, err := LoadSources(Sources{File: path})
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("应返回 ValidationError, 得到 %v", err)
+	}
+	msg := err.Error()
+	for _, want := range []string{"3 errors", "jwtSecret", "users.admin.hash", "ai.base_url"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("错误信息应包含 %q:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "ai.key") {
+		t.Error("错误信息不应包含警告")
+	}
+}
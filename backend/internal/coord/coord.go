@@ -88,6 +88,11 @@ func ConvertBaiduToGPS(mx, my float64) (float64, float64) {
 	return gcj02ToWgs84(gcjLng, gcjLat)
 }
 
+// ConvertGCJToGPS converts GCJ-02 coordinates (used by Gaode/AMap) to WGS-84.
+func ConvertGCJToGPS(lng, lat float64) (float64, float64) {
+	return gcj02ToWgs84(lng, lat)
+}
+
 func ParseBaiduGeo(geo string) (float64, float64, bool) {
 	if geo == "" {
 		return 0, 0, false
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/auth"
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/token"
+	"github.com/chenxuan520/roadmap/backend/internal/twofactor"
+	"github.com/gin-gonic/gin"
+)
+
+// startedAt 记录进程启动时间，用于诊断信息中的运行时长
+var startedAt = time.Now()
+
+// AdminHandler 包含了管理员专用的用户管理与诊断接口
+type AdminHandler struct {
+	store            *config.Store
+	authService      auth.Authenticator
+	tokenService     token.Service
+	twoFactorService twofactor.Service
+}
+
+// NewAdminHandler 创建一个新的 AdminHandler 实例
+func NewAdminHandler(store *config.Store, authService auth.Authenticator, tokenService token.Service, twoFactorService twofactor.Service) *AdminHandler {
+	return &AdminHandler{
+		store:            store,
+		authService:      authService,
+		tokenService:     tokenService,
+		twoFactorService: twoFactorService,
+	}
+}
+
+// adminSession 管理操作只允许管理员的登录会话执行，个人访问令牌即使属于管理员也不行
+func adminSession(c *gin.Context) bool {
+	if c.GetString("auth_type") != AuthTypeJWT {
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Message: "个人访问令牌不能用于管理操作，请使用登录会话",
+			Code:    http.StatusForbidden,
+		})
+		return false
+	}
+	return true
+}
+
+// targetUser 读取路径中的用户名并确认用户存在
+func (h *AdminHandler) targetUser(c *gin.Context) (string, bool) {
+	username := c.Param("username")
+	if h.authService.Role(username) == "" {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Message: "用户不存在",
+			Code:    http.StatusNotFound,
+		})
+		return "", false
+	}
+	return username, true
+}
+
+// ListUsersHandler 列出全部用户及其角色、两步验证和令牌情况
+func (h *AdminHandler) ListUsersHandler(c *gin.Context) {
+	if !adminSession(c) {
+		return
+	}
+
+	users := h.authService.Users()
+	infos := make([]AdminUserInfo, 0, len(users))
+	for username, role := range users {
+		enabled, err := h.twoFactorService.Enabled(username)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Message: "获取两步验证状态失败: " + err.Error(),
+				Code:    http.StatusInternalServerError,
+			})
+			return
+		}
+		tokens, err := h.tokenService.List(username)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Message: "获取令牌列表失败: " + err.Error(),
+				Code:    http.StatusInternalServerError,
+			})
+			return
+		}
+		infos = append(infos, AdminUserInfo{
+			Username:         username,
+			Role:             role,
+			TwoFactorEnabled: enabled,
+			TokenCount:       len(tokens),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Username < infos[j].Username })
+	c.JSON(http.StatusOK, ListUsersResponse{Users: infos})
+}
+
+// RevokeUserTokensHandler 吊销指定用户的全部个人访问令牌
+func (h *AdminHandler) RevokeUserTokensHandler(c *gin.Context) {
+	if !adminSession(c) {
+		return
+	}
+	username, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.tokenService.RevokeAll(username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: "吊销令牌失败: " + err.Error(),
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+	auth.Audit("admin_revoke_tokens", "admin=%q username=%q revoked=%d", c.GetString("username"), username, revoked)
+	c.JSON(http.StatusOK, RevokeUserTokensResponse{Revoked: revoked})
+}
+
+// ResetTwoFactorHandler 为丢失验证器的用户清除两步验证
+func (h *AdminHandler) ResetTwoFactorHandler(c *gin.Context) {
+	if !adminSession(c) {
+		return
+	}
+	username, ok := h.targetUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.twoFactorService.Reset(username); err != nil {
+		if errors.Is(err, twofactor.ErrNotEnabled) {
+			twoFactorError(c, err)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: "重置两步验证失败: " + err.Error(),
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+	auth.Audit("admin_reset_2fa", "admin=%q username=%q", c.GetString("username"), username)
+	c.JSON(http.StatusOK, gin.H{"message": "两步验证已重置"})
+}
+
+// DiagnosticsHandler 返回服务运行状态与已启用的功能
+func (h *AdminHandler) DiagnosticsHandler(c *gin.Context) {
+	if !adminSession(c) {
+		return
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	cfg := h.store.Current()
+	signing := "HS256"
+	for _, key := range h.authService.JWKS().Keys {
+		if key.Kid == cfg.JWT.SigningKeyID {
+			signing = key.Alg
+		}
+	}
+
+	c.JSON(http.StatusOK, DiagnosticsResponse{
+		Version:       Version,
+		Commit:        Commit,
+		BuildTime:     BuildTime,
+		GoVersion:     runtime.Version(),
+		StartedAt:     startedAt.UTC(),
+		UptimeSeconds: int64(time.Since(startedAt).Seconds()),
+		Goroutines:    runtime.NumGoroutine(),
+		HeapAllocMB:   float64(mem.HeapAlloc) / (1 << 20),
+		Users:         len(cfg.Users),
+		Features: map[string]bool{
+			"ai":          cfg.AI.Enabled,
+			"oidc":        cfg.OIDC.Enabled,
+			"gaodeSearch": cfg.Search.Providers.Get("gaode").Key != "",
+		},
+		JWTSigning: signing,
+	})
+}
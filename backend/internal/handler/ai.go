@@ -11,8 +11,12 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/logging"
+	"github.com/chenxuan520/roadmap/backend/internal/metrics"
+	"github.com/chenxuan520/roadmap/backend/internal/tracing"
 	"github.com/gin-gonic/gin"
 )
 
@@ -54,6 +58,13 @@ type OpenAIStreamResponse struct {
 			Content string `json:"content"`
 		} `json:"delta"`
 	} `json:"choices"`
+	// Usage is only present in the final chunk, and only for providers that report it.
+	Usage *OpenAIUsage `json:"usage,omitempty"`
+}
+
+type OpenAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
 }
 
 func getSessionFilePath() string {
@@ -67,8 +78,9 @@ func ensureAIDir() error {
 	return nil
 }
 
-func GetAIConfig(cfg *config.Config) gin.HandlerFunc {
+func GetAIConfig(store *config.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		cfg := store.Current()
 		c.JSON(http.StatusOK, gin.H{
 			"enabled": cfg.AI.Enabled,
 			"model":   cfg.AI.Model,
@@ -138,8 +150,10 @@ func SaveAISession(c *gin.Context) {
 
 // AIChat is now stateless. It receives context, streams response.
 // The client is responsible for saving the history via SaveAISession.
-func AIChat(cfg *config.Config) gin.HandlerFunc {
+// The config snapshot is taken once per request, so a reload never affects a running stream.
+func AIChat(store *config.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		cfg := store.Current()
 		if !cfg.AI.Enabled {
 			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI service is disabled"})
 			return
@@ -168,9 +182,17 @@ func AIChat(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		logger := logging.FromContext(c.Request.Context())
 		client := &http.Client{}
 		url := fmt.Sprintf("%s/chat/completions", cfg.AI.BaseURL)
-		proxyReq, err := http.NewRequest("POST", url, bytes.NewBuffer(reqBody))
+		// The span covers the whole upstream call, from connecting until the stream ends.
+		ctx, span := tracing.Start(c.Request.Context(), "AIChat", tracing.SpanKindClient,
+			tracing.String("gen_ai.request.model", cfg.AI.Model),
+			tracing.Int("gen_ai.request.messages", len(providerMessages)))
+		defer span.End()
+
+		// Bind the upstream call to the client request so a disconnect stops the stream.
+		proxyReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqBody))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request"})
 			return
@@ -178,16 +200,25 @@ func AIChat(cfg *config.Config) gin.HandlerFunc {
 
 		proxyReq.Header.Set("Content-Type", "application/json")
 		proxyReq.Header.Set("Authorization", "Bearer "+cfg.AI.Key)
+		tracing.Inject(ctx, proxyReq.Header)
 
+		start := time.Now()
 		resp, err := client.Do(proxyReq)
 		if err != nil {
+			logger.Error("ai provider request failed", "model", cfg.AI.Model, "error", err)
+			span.RecordError(err)
+			metrics.AIStreamDuration.Observe(time.Since(start).Seconds(), cfg.AI.Model, "error")
 			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to connect to AI provider"})
 			return
 		}
 		defer resp.Body.Close()
+		span.SetAttributes(tracing.Int("http.response.status_code", resp.StatusCode))
 
 		if resp.StatusCode != http.StatusOK {
 			bodyBytes, _ := io.ReadAll(resp.Body)
+			logger.Warn("ai provider returned error", "model", cfg.AI.Model, "status", resp.StatusCode)
+			span.SetError(http.StatusText(resp.StatusCode))
+			metrics.AIStreamDuration.Observe(time.Since(start).Seconds(), cfg.AI.Model, "error")
 			c.JSON(resp.StatusCode, gin.H{"error": "AI Provider Error", "details": string(bodyBytes)})
 			return
 		}
@@ -201,13 +232,24 @@ func AIChat(cfg *config.Config) gin.HandlerFunc {
 		// Just proxy the stream and record response
 		reader := bufio.NewReader(resp.Body)
 		var fullResponse strings.Builder
+		var streamed int
+		var streamErr error
+		var usage *OpenAIUsage
+		var chunks int // content chunks; not tokens, recorded in a separate series
 
 		c.Stream(func(w io.Writer) bool {
 			line, err := reader.ReadBytes('\n')
 			if err != nil {
+				if err != io.EOF {
+					streamErr = err
+				}
 				return false
 			}
+			if streamed == 0 {
+				span.SetAttributes(tracing.Int64("ai.time_to_first_chunk_ms", time.Since(start).Milliseconds()))
+			}
 			w.Write(line)
+			streamed += len(line)
 
 			// Parse line for history accumulation
 			// SSE format: data: {...}
@@ -217,8 +259,12 @@ func AIChat(cfg *config.Config) gin.HandlerFunc {
 				if dataContent != "[DONE]" {
 					var streamResp OpenAIStreamResponse
 					if json.Unmarshal([]byte(dataContent), &streamResp) == nil {
-						if len(streamResp.Choices) > 0 {
+						if len(streamResp.Choices) > 0 && streamResp.Choices[0].Delta.Content != "" {
 							fullResponse.WriteString(streamResp.Choices[0].Delta.Content)
+							chunks++
+						}
+						if streamResp.Usage != nil {
+							usage = streamResp.Usage
 						}
 					}
 				}
@@ -226,6 +272,25 @@ func AIChat(cfg *config.Config) gin.HandlerFunc {
 			return true
 		})
 
+		duration := time.Since(start)
+		if streamErr != nil {
+			logger.Warn("ai stream interrupted", "model", cfg.AI.Model, "duration_ms", duration.Milliseconds(), "bytes", streamed, "error", streamErr)
+			metrics.AIStreamDuration.Observe(duration.Seconds(), cfg.AI.Model, "interrupted")
+			span.RecordError(streamErr)
+		} else {
+			logger.Info("ai stream completed", "model", cfg.AI.Model, "duration_ms", duration.Milliseconds(), "bytes", streamed)
+			metrics.AIStreamDuration.Observe(duration.Seconds(), cfg.AI.Model, "ok")
+		}
+		span.SetAttributes(tracing.Int("ai.response.bytes", streamed))
+		if usage != nil {
+			metrics.AITokens.Add(float64(usage.PromptTokens), cfg.AI.Model, "prompt")
+			metrics.AITokens.Add(float64(usage.CompletionTokens), cfg.AI.Model, "completion")
+			span.SetAttributes(
+				tracing.Int("gen_ai.usage.input_tokens", usage.PromptTokens),
+				tracing.Int("gen_ai.usage.output_tokens", usage.CompletionTokens))
+		}
+		metrics.AIStreamChunks.Add(float64(chunks), cfg.AI.Model)
+
 		// After stream finishes, save session to disk
 		aiMsg := Message{
 			Role:    "assistant",
@@ -248,13 +313,16 @@ func AIChat(cfg *config.Config) gin.HandlerFunc {
 
 		if err := ensureAIDir(); err != nil {
 			// Log error but don't disrupt the response (too late anyway)
+			logger.Error("failed to create ai data directory", "error", err)
 			return
 		}
 
 		path := getSessionFilePath()
 		data, err := json.MarshalIndent(messagesToSave, "", "  ")
 		if err == nil {
-			_ = os.WriteFile(path, data, 0644)
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				logger.Error("failed to save ai session", "error", err)
+			}
 		}
 	}
 }
@@ -1,22 +1,45 @@
 package handler
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/chenxuan520/roadmap/backend/internal/auth"
+	"github.com/chenxuan520/roadmap/backend/internal/twofactor"
 	"github.com/gin-gonic/gin"
 )
 
+// 认证中间件写入 Context 的 auth_type 取值
+const (
+	AuthTypeJWT   = "jwt"   // 通过登录获得的JWT
+	AuthTypeToken = "token" // 个人访问令牌
+)
+
 // AuthHandler 包含了认证相关的处理函数
 type AuthHandler struct {
-	authService auth.Authenticator
+	authService      auth.Authenticator
+	loginGuard       *auth.LoginGuard
+	twoFactorService twofactor.Service
 }
 
 // NewAuthHandler 创建一个新的 AuthHandler 实例
-func NewAuthHandler(authService auth.Authenticator) *AuthHandler {
+func NewAuthHandler(authService auth.Authenticator, loginGuard *auth.LoginGuard, twoFactorService twofactor.Service) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
+		authService:      authService,
+		loginGuard:       loginGuard,
+		twoFactorService: twoFactorService,
+	}
+}
+
+// setRetryAfter 设置 Retry-After 响应头（秒，向上取整）
+func setRetryAfter(c *gin.Context, d time.Duration) {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
 	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
 }
 
 // LoginHandler 处理用户登录请求
@@ -30,8 +53,72 @@ func (h *AuthHandler) LoginHandler(c *gin.Context) {
 		return
 	}
 
-	token, err := h.authService.Authenticate(req.Username, req.Password)
+	// 账户被锁定或全局失败过多时，直接拒绝而不校验密码
+	if !h.checkLoginGuard(c, req.Username) {
+		return
+	}
+
+	if err := h.authService.VerifyCredentials(req.Username, req.Password); err != nil {
+		h.loginGuard.RecordFailure(req.Username, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Message: err.Error(),
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
+	enabled, err := h.twoFactorService.Enabled(req.Username)
 	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: "读取两步验证状态失败",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+	if enabled {
+		// 密码正确但还需要第二因素：返回短期挑战令牌，失败计数留到两步验证完成后再清零
+		challenge, err := h.authService.GenerateChallengeToken(req.Username)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Message: "生成挑战token失败",
+				Code:    http.StatusInternalServerError,
+			})
+			return
+		}
+		c.JSON(http.StatusOK, LoginResponse{TwoFactorRequired: true, ChallengeToken: challenge})
+		return
+	}
+
+	h.issueToken(c, req.Username)
+}
+
+// LoginTwoFactorHandler 使用挑战令牌与TOTP验证码（或恢复码）完成登录
+func (h *AuthHandler) LoginTwoFactorHandler(c *gin.Context) {
+	var req LoginTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "请求参数错误",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	username, err := h.authService.ParseChallengeToken(req.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Message: err.Error(),
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
+	// 验证码错误同样计入失败次数，防止在挑战令牌有效期内穷举验证码
+	if !h.checkLoginGuard(c, username) {
+		return
+	}
+
+	if err := h.twoFactorService.Verify(username, req.Code); err != nil {
+		h.loginGuard.RecordFailure(username, c.ClientIP())
 		c.JSON(http.StatusUnauthorized, ErrorResponse{
 			Message: err.Error(),
 			Code:    http.StatusUnauthorized,
@@ -39,6 +126,35 @@ func (h *AuthHandler) LoginHandler(c *gin.Context) {
 		return
 	}
 
+	h.issueToken(c, username)
+}
+
+// checkLoginGuard 检查账户锁定状态，被拒绝时写入 429 响应并返回 false
+func (h *AuthHandler) checkLoginGuard(c *gin.Context, username string) bool {
+	retryAfter, err := h.loginGuard.Check(username)
+	if err == nil {
+		return true
+	}
+	setRetryAfter(c, retryAfter)
+	c.JSON(http.StatusTooManyRequests, ErrorResponse{
+		Message: err.Error(),
+		Code:    http.StatusTooManyRequests,
+	})
+	return false
+}
+
+// issueToken 登录成功：清除失败记录并签发访问令牌
+func (h *AuthHandler) issueToken(c *gin.Context, username string) {
+	token, err := h.authService.GenerateToken(username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: "生成JWT token失败",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	h.loginGuard.RecordSuccess(username, c.ClientIP())
 	c.JSON(http.StatusOK, LoginResponse{Token: token})
 }
 
@@ -63,6 +179,15 @@ func (h *AuthHandler) RefreshHandler(c *gin.Context) {
 		return
 	}
 
+	// 个人访问令牌不能换取登录会话
+	if c.GetString("auth_type") == AuthTypeToken {
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Message: "个人访问令牌不支持续约",
+			Code:    http.StatusForbidden,
+		})
+		return
+	}
+
 	token, err := h.authService.GenerateToken(username)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
@@ -74,3 +199,9 @@ func (h *AuthHandler) RefreshHandler(c *gin.Context) {
 
 	c.JSON(http.StatusOK, LoginResponse{Token: token})
 }
+
+// JWKSHandler 返回验签公钥集合（JWKS）
+func (h *AuthHandler) JWKSHandler(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.JWKS())
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/health"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	healthDataDir = "data"          // 计划、令牌等文件仓库共用的数据目录
+	probeCacheTTL = time.Minute     // 搜索服务商探测结果的缓存时间
+	healthTimeout = 3 * time.Second // 单项检查的超时
+)
+
+// HealthHandler 提供存活与就绪检查
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+// NewHealthHandler 根据配置注册就绪检查项。只有数据目录是关键组件，
+// 其余组件失败时服务仍可部分工作，整体状态为 degraded。
+// 组件是否启用在每次检查时从 store 读取，随配置热加载更新。
+func NewHealthHandler(store *config.Store) *HealthHandler {
+	probeClient := &http.Client{Timeout: healthTimeout}
+	probe := func(url string) func(ctx context.Context) error {
+		return health.Cached(probeCacheTTL, health.HTTPReachable(probeClient, url))
+	}
+	gaodeProbe := probe("https://restapi.amap.com/")
+	gaode := func(ctx context.Context) error {
+		if store.Current().Search.Providers.Get("gaode").Key == "" {
+			return health.ErrDisabled
+		}
+		return gaodeProbe(ctx)
+	}
+
+	return &HealthHandler{checker: health.NewChecker(healthTimeout,
+		health.Check{Name: "dataDir", Critical: true, Run: health.DirWritable(healthDataDir)},
+		health.Check{Name: "trafficPos", Run: func(context.Context) error { return TrafficPosStatus() }},
+		health.Check{Name: "search.baidu", Run: probe("https://map.baidu.com/")},
+		health.Check{Name: "search.tianmap", Run: probe("https://api.tianditu.gov.cn/")},
+		health.Check{Name: "search.gaode", Run: gaode},
+		health.Check{Name: "ai", Run: func(context.Context) error { return checkAIConfig(store.Current().AI) }},
+	)}
+}
+
+// checkAIConfig 检查 AI 服务商配置是否完整，不会发起实际调用
+func checkAIConfig(cfg config.AIConfig) error {
+	if !cfg.Enabled {
+		return health.ErrDisabled
+	}
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("ai.base_url 不是有效的 http(s) 地址")
+	}
+	if cfg.Key == "" {
+		return errors.New("未配置 ai.key")
+	}
+	if cfg.Model == "" {
+		return errors.New("未配置 ai.model")
+	}
+	return nil
+}
+
+// LivenessHandler 存活检查：进程能处理请求即返回 200，不检查任何依赖
+func (h *HealthHandler) LivenessHandler(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"status":        health.StatusOK,
+		"version":       Version,
+		"uptimeSeconds": int64(time.Since(startedAt).Seconds()),
+	})
+}
+
+// ReadinessHandler 就绪检查：返回各组件状态，关键组件失败时返回 503
+func (h *HealthHandler) ReadinessHandler(c *gin.Context) {
+	report := h.checker.Run(c.Request.Context())
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
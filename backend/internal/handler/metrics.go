@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/chenxuan520/roadmap/backend/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsHandler 以 Prometheus 文本格式输出服务指标。
+// token 非空时要求请求携带 Authorization: Bearer <token>。
+func MetricsHandler(registry *metrics.Registry, token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token != "" {
+			got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+				c.JSON(http.StatusUnauthorized, ErrorResponse{
+					Message: "无效的指标访问令牌",
+					Code:    http.StatusUnauthorized,
+				})
+				return
+			}
+		}
+		c.Header("Content-Type", metrics.ContentType)
+		c.Status(http.StatusOK)
+		registry.WriteTo(c.Writer)
+	}
+}
@@ -19,6 +19,28 @@ type LoginRequest struct {
 
 type LoginResponse struct {
 	Token string `json:"token"`
+	// 启用两步验证的用户在密码校验通过后会收到挑战令牌，而不是访问令牌
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+}
+
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"` // TOTP验证码或恢复码
+}
+
+// 两步验证
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type EnrollTwoFactorResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
 }
 
 // 计划管理
@@ -84,3 +106,62 @@ type SavePlanResponse struct {
 type DeletePlanResponse struct {
 	Message string `json:"message"`
 }
+
+// 个人访问令牌
+type CreateTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expiresInDays,omitempty"` // 0 表示永不过期
+}
+
+type TokenInfo struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+type CreateTokenResponse struct {
+	Token string    `json:"token"` // 明文令牌，仅在创建时返回一次
+	Info  TokenInfo `json:"info"`
+}
+
+type ListTokensResponse struct {
+	Tokens []TokenInfo `json:"tokens"`
+}
+
+type RevokeTokenResponse struct {
+	Message string `json:"message"`
+}
+
+// 管理员接口
+type AdminUserInfo struct {
+	Username         string `json:"username"`
+	Role             string `json:"role"`
+	TwoFactorEnabled bool   `json:"twoFactorEnabled"`
+	TokenCount       int    `json:"tokenCount"`
+}
+
+type ListUsersResponse struct {
+	Users []AdminUserInfo `json:"users"`
+}
+
+type RevokeUserTokensResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+type DiagnosticsResponse struct {
+	Version       string          `json:"version"`
+	Commit        string          `json:"commit"`
+	BuildTime     string          `json:"buildTime"`
+	GoVersion     string          `json:"goVersion"`
+	StartedAt     time.Time       `json:"startedAt"`
+	UptimeSeconds int64           `json:"uptimeSeconds"`
+	Goroutines    int             `json:"goroutines"`
+	HeapAllocMB   float64         `json:"heapAllocMB"`
+	Users         int             `json:"users"`
+	Features      map[string]bool `json:"features"`
+	JWTSigning    string          `json:"jwtSigning"` // 当前签名算法，如 HS256、RS256、EdDSA
+}
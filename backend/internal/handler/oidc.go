@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/chenxuan520/roadmap/backend/internal/auth"
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/logging"
+	"github.com/chenxuan520/roadmap/backend/internal/oidc"
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie 保存发起登录时的 state，回调时与查询参数中的 state 比对，
+// 确保回调来自同一个浏览器发起的登录，防止攻击者诱导受害者登录到攻击者的账号
+const (
+	oidcStateCookie     = "roadbook_oidc_state"
+	oidcStateCookiePath = "/api/v1/oidc"
+)
+
+// OIDCHandler 包含了 OpenID Connect 登录相关的处理函数
+type OIDCHandler struct {
+	client      *oidc.Client
+	authService auth.Authenticator
+	store       *config.Store
+	// frontendRedirectURL 与 OIDC 客户端一样只在启动时读取，用户列表随配置热加载更新
+	frontendRedirectURL string
+	// secureCookie 在回调地址使用 HTTPS 时为 true，state Cookie 只通过 HTTPS 发送
+	secureCookie bool
+}
+
+// NewOIDCHandler 创建一个新的 OIDCHandler 实例
+func NewOIDCHandler(client *oidc.Client, authService auth.Authenticator, store *config.Store) *OIDCHandler {
+	return &OIDCHandler{
+		client:              client,
+		authService:         authService,
+		store:               store,
+		frontendRedirectURL: store.Current().OIDC.FrontendRedirectURL,
+		secureCookie:        strings.HasPrefix(store.Current().OIDC.RedirectURL, "https://"),
+	}
+}
+
+// LoginHandler 将浏览器重定向到身份提供方的授权页面
+func (h *OIDCHandler) LoginHandler(c *gin.Context) {
+	authURL, state, err := h.client.AuthCodeURL(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("OIDC 登录初始化失败", "error", err)
+		c.JSON(http.StatusBadGateway, ErrorResponse{
+			Message: "无法连接身份提供方: " + err.Error(),
+			Code:    http.StatusBadGateway,
+		})
+		return
+	}
+	// 身份提供方回调是跨站的顶级导航，SameSite=Lax 的 Cookie 会随之发送
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, int(oidc.PendingTTL.Seconds()), oidcStateCookiePath, "", h.secureCookie, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// CallbackHandler 处理身份提供方的回调，校验通过后签发 roadbook JWT
+func (h *OIDCHandler) CallbackHandler(c *gin.Context) {
+	// state Cookie 只用一次，无论成功与否都清除
+	stateCookie, _ := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, oidcStateCookiePath, "", h.secureCookie, true)
+
+	if idpErr := c.Query("error"); idpErr != "" {
+		h.fail(c, http.StatusUnauthorized, "身份提供方拒绝了登录: "+idpErr)
+		return
+	}
+
+	state := c.Query("state")
+	if stateCookie == "" || subtle.ConstantTimeCompare([]byte(stateCookie), []byte(state)) != 1 {
+		logging.FromContext(c.Request.Context()).Warn("OIDC 回调的 state 与浏览器不匹配", "has_cookie", stateCookie != "")
+		h.fail(c, http.StatusUnauthorized, "登录状态校验失败，请重新登录")
+		return
+	}
+
+	claims, err := h.client.Exchange(c.Request.Context(), c.Query("code"), state)
+	if err != nil {
+		h.fail(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	username, err := h.client.ResolveUser(claims, h.store.Current().Users)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Warn("OIDC 用户登录被拒绝", "subject", claims.Subject, "error", err)
+		h.fail(c, http.StatusForbidden, err.Error())
+		return
+	}
+
+	token, err := h.authService.GenerateToken(username)
+	if err != nil {
+		h.fail(c, http.StatusInternalServerError, "生成JWT token失败")
+		return
+	}
+
+	if h.frontendRedirectURL == "" {
+		c.JSON(http.StatusOK, LoginResponse{Token: token})
+		return
+	}
+	// 令牌放在 fragment 中，不会出现在服务器日志和 Referer 里
+	c.Redirect(http.StatusFound, h.frontendRedirectURL+"#token="+url.QueryEscape(token))
+}
+
+// fail 根据是否配置了前端地址，以重定向或 JSON 的方式返回错误
+func (h *OIDCHandler) fail(c *gin.Context, statusCode int, message string) {
+	if h.frontendRedirectURL != "" {
+		c.Redirect(http.StatusFound, h.frontendRedirectURL+"#error="+url.QueryEscape(message))
+		return
+	}
+	c.JSON(statusCode, ErrorResponse{
+		Message: message,
+		Code:    statusCode,
+	})
+}
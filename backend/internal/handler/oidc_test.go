@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/oidc"
+	"github.com/gin-gonic/gin"
+)
+
+func TestOIDCHandler_StateBoundToBrowser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var idp *httptest.Server
+	idp = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	}))
+	defer idp.Close()
+
+	cfg := config.Config{OIDC: config.OIDCConfig{
+		Enabled:     true,
+		Issuer:      idp.URL,
+		ClientID:    "roadbook",
+		RedirectURL: "https://roadbook.example.com/api/v1/oidc/callback",
+	}}
+	store := config.NewStore(cfg, func() (config.Config, error) { return cfg, nil })
+	h := NewOIDCHandler(oidc.NewClient(cfg.OIDC), nil, store)
+	r := gin.New()
+	r.GET("/api/v1/oidc/login", h.LoginHandler)
+	r.GET("/api/v1/oidc/callback", h.CallbackHandler)
+
+	login := func() (state string, cookie *http.Cookie) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/oidc/login", nil))
+		if w.Code != http.StatusFound {
+			t.Fatalf("登录入口应重定向, 得到 %d: %s", w.Code, w.Body.String())
+		}
+		location, _ := url.Parse(w.Header().Get("Location"))
+		for _, c := range w.Result().Cookies() {
+			if c.Name == oidcStateCookie {
+				cookie = c
+			}
+		}
+		return location.Query().Get("state"), cookie
+	}
+
+	state, cookie := login()
+	if cookie == nil || cookie.Value != state {
+		t.Fatalf("登录入口应把 state 写入 Cookie: %+v", cookie)
+	}
+	if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode || cookie.Path != oidcStateCookiePath {
+		t.Errorf("state Cookie 属性错误: %+v", cookie)
+	}
+
+	// 攻击者自己发起登录得到的 state，受害者浏览器中没有对应的 Cookie
+	attackerState, _ := login()
+	for name, c := range map[string]*http.Cookie{
+		"没有 Cookie":  nil,
+		"Cookie 不匹配": {Name: oidcStateCookie, Value: state},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/oidc/callback?code=attacker-code&state="+attackerState, nil)
+		if c != nil {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "登录状态校验失败") {
+			t.Errorf("%s: 回调应被拒绝, 得到 %d: %s", name, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Set-Cookie"); !strings.Contains(got, oidcStateCookie+"=;") {
+			t.Errorf("%s: 回调后应清除 state Cookie, 得到 %q", name, got)
+		}
+	}
+}
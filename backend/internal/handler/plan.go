@@ -55,7 +55,7 @@ func (h *PlanHandler) CreatePlanHandler(c *gin.Context) {
 		Content:     req.Content,
 	}
 
-	if err := h.planRepo.Save(newPlan); err != nil {
+	if err := h.planRepo.Save(c.Request.Context(), newPlan); err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: "创建计划失败: " + err.Error(),
 			Code:    http.StatusInternalServerError,
@@ -72,7 +72,7 @@ func (h *PlanHandler) CreatePlanHandler(c *gin.Context) {
 
 // ListPlansHandler 处理列出所有计划的请求
 func (h *PlanHandler) ListPlansHandler(c *gin.Context) {
-	summaries, err := h.planRepo.FindAll()
+	summaries, err := h.planRepo.FindAll(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: "获取计划列表失败: " + err.Error(),
@@ -109,7 +109,7 @@ func (h *PlanHandler) GetPlanHandler(c *gin.Context) {
 		return
 	}
 
-	p, err := h.planRepo.FindByID(id)
+	p, err := h.planRepo.FindByID(c.Request.Context(), id)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == fmt.Sprintf("计划 %s 未找到", id) { // 检查是否是“未找到”的错误
@@ -148,7 +148,7 @@ func (h *PlanHandler) SavePlanHandler(c *gin.Context) {
 	}
 
 	// 尝试获取现有计划，如果不存在则报错
-	existingPlan, err := h.planRepo.FindByID(id)
+	existingPlan, err := h.planRepo.FindByID(c.Request.Context(), id)
 	if err != nil {
 		if err.Error() == fmt.Sprintf("计划 %s 未找到", id) {
 			c.JSON(http.StatusNotFound, ErrorResponse{
@@ -173,7 +173,7 @@ func (h *PlanHandler) SavePlanHandler(c *gin.Context) {
 	existingPlan.Content = req.Content
 	existingPlan.UpdatedAt = time.Now().UTC() // 确保更新时间
 
-	if err := h.planRepo.Save(existingPlan); err != nil {
+	if err := h.planRepo.Save(c.Request.Context(), existingPlan); err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Message: "保存计划失败: " + err.Error(),
 			Code:    http.StatusInternalServerError,
@@ -199,7 +199,7 @@ func (h *PlanHandler) DeletePlanHandler(c *gin.Context) {
 		return
 	}
 
-	err := h.planRepo.Delete(id)
+	err := h.planRepo.Delete(c.Request.Context(), id)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == fmt.Sprintf("计划 %s 未找到，无法删除", id) { // 检查是否是“未找到”的错误
@@ -228,7 +228,7 @@ func (h *PlanHandler) SharePlanHandler(c *gin.Context) {
 		return
 	}
 
-	p, err := h.planRepo.FindByID(id)
+	p, err := h.planRepo.FindByID(c.Request.Context(), id)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == fmt.Sprintf("计划 %s 未找到", id) {
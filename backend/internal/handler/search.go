@@ -2,77 +2,184 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
-	"github.com/chenxuan520/roadmap/backend/internal/config"
-	"github.com/chenxuan520/roadmap/backend/internal/search/baidu"
-	"github.com/chenxuan520/roadmap/backend/internal/search/gaode"
-	"github.com/chenxuan520/roadmap/backend/internal/search/tianmap"
+	"github.com/chenxuan520/roadmap/backend/internal/logging"
+	"github.com/chenxuan520/roadmap/backend/internal/search"
 	"github.com/gin-gonic/gin"
 )
 
+// maxSearchLimit caps the limit query parameter.
+const maxSearchLimit = 50
+
 // SearchHandlers holds dependencies for search-related handlers.
 type SearchHandlers struct {
-	cfg config.Config
+	registry *search.Registry
 }
 
-// NewSearchHandlers creates a new instance of SearchHandlers.
-func NewSearchHandlers(cfg config.Config) *SearchHandlers {
-	return &SearchHandlers{cfg: cfg}
+// NewSearchHandlers creates a new instance of SearchHandlers. The registry is rebuilt on
+// config reloads, so provider keys and flags follow the active config.
+func NewSearchHandlers(registry *search.Registry) *SearchHandlers {
+	return &SearchHandlers{registry: registry}
 }
 
-// GaodeSearchHandler handles Gaode search requests.
-func (h *SearchHandlers) GaodeSearchHandler(c *gin.Context) {
-	query := c.Query("q")
-	results, err := gaode.Search(query, h.cfg.Search.Providers.Gaode.Key)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+// SearchHandler handles GET /api/search?provider=<name>&q=<query>[&limit=<n>].
+func (h *SearchHandlers) SearchHandler(c *gin.Context) {
+	h.search(c, c.Query("provider"), false)
+}
+
+// ProviderSearchHandler serves the legacy per-provider routes such as /api/gaode/search.
+// They keep returning Gaode results in GCJ-02, as they did before /api/search converted
+// every provider to WGS-84.
+func (h *SearchHandlers) ProviderSearchHandler(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.search(c, name, true)
 	}
-	c.JSON(http.StatusOK, results)
 }
 
-// BaiduSearchHandler handles Baidu search requests.
-func (h *SearchHandlers) BaiduSearchHandler(c *gin.Context) {
-	query := c.Query("q")
-	results, err := baidu.Search(query)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+func (h *SearchHandlers) search(c *gin.Context, name string, gcj02 bool) {
+	if name == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "缺少 provider 参数", Code: http.StatusBadRequest})
 		return
 	}
-	c.JSON(http.StatusOK, results)
+	if name == search.AllProviders {
+		h.federatedSearch(c)
+		return
+	}
+	provider, ok := h.registry.Get(name)
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "未知的搜索源: " + name, Code: http.StatusBadRequest})
+		return
+	}
+	if !provider.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Message: "搜索源未启用: " + name, Code: http.StatusServiceUnavailable})
+		return
+	}
+	opts, ok := searchOptions(c)
+	if !ok {
+		return
+	}
+	opts.GCJ02 = gcj02
+
+	// 请求的搜索源失败或熔断时按 search.fallback 依次尝试其他搜索源，跳过未启用以及未登录时要求登录的
+	_, authenticated := c.Get("username")
+	var failed, messages []string
+	for i, p := range h.registry.Chain(name) {
+		if i > 0 && (!p.Enabled() || (p.LoginRequired() && !authenticated)) {
+			continue
+		}
+		results, err := p.Search(c.Request.Context(), c.Query("q"), opts)
+		if err != nil {
+			failed = append(failed, p.Name())
+			messages = append(messages, p.Name()+": "+err.Error())
+			continue
+		}
+		if len(failed) > 0 {
+			logging.FromContext(c.Request.Context()).Warn("search provider failed, fell back", "providers", failed, "errors", messages, "served_by", p.Name())
+			c.Header("X-Search-Failed-Providers", strings.Join(failed, ","))
+		}
+		c.Header("X-Search-Provider", p.Name())
+		c.JSON(http.StatusOK, results)
+		return
+	}
+	c.JSON(http.StatusBadGateway, ErrorResponse{Message: "搜索失败: " + strings.Join(messages, "; "), Code: http.StatusBadGateway})
 }
 
-// TianmapSearchHandler handles Tianmap search requests.
-func (h *SearchHandlers) TianmapSearchHandler(c *gin.Context) {
-	query := c.Query("q")
-	results, err := tianmap.Search(query)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+// federatedSearch handles provider=all: every enabled provider is queried at once and the
+// merged results carry their sources. Providers that require a login are skipped for
+// anonymous requests. Failed providers are listed in X-Search-Failed-Providers; the request
+// only fails when every provider does.
+func (h *SearchHandlers) federatedSearch(c *gin.Context) {
+	opts, ok := searchOptions(c)
+	if !ok {
 		return
 	}
+	_, authenticated := c.Get("username")
+	var providers []search.Provider
+	for _, p := range h.registry.Providers() {
+		if p.Enabled() && (authenticated || !p.LoginRequired()) {
+			providers = append(providers, p)
+		}
+	}
+	if len(providers) == 0 {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Message: "没有可用的搜索源", Code: http.StatusServiceUnavailable})
+		return
+	}
+
+	results, failures := search.Federate(c.Request.Context(), providers, c.Query("q"), opts, h.registry.Federated())
+	if len(failures) > 0 {
+		failed := make([]string, 0, len(failures))
+		messages := make([]string, 0, len(failures))
+		for _, f := range failures {
+			failed = append(failed, f.Provider)
+			messages = append(messages, f.Provider+": "+f.Err.Error())
+		}
+		logging.FromContext(c.Request.Context()).Warn("federated search provider failed", "providers", failed, "errors", messages)
+		if len(failures) == len(providers) {
+			c.JSON(http.StatusBadGateway, ErrorResponse{Message: "搜索失败: " + strings.Join(messages, "; "), Code: http.StatusBadGateway})
+			return
+		}
+		c.Header("X-Search-Failed-Providers", strings.Join(failed, ","))
+	}
 	c.JSON(http.StatusOK, results)
 }
 
+// searchOptions parses the optional limit parameter, responding with 400 when it is invalid.
+func searchOptions(c *gin.Context) (search.Options, bool) {
+	var opts search.Options
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Message: "limit 必须是 1 到 " + strconv.Itoa(maxSearchLimit) + " 之间的整数",
+				Code:    http.StatusBadRequest,
+			})
+			return opts, false
+		}
+		opts.Limit = n
+	}
+	return opts, true
+}
+
+// SearchLoginRequired reports whether a request needs a login. name fixes the provider for
+// the legacy routes; when empty the provider query parameter is used. Unknown providers
+// return false and are rejected by the handler. provider=all authenticates only requests
+// that carry credentials; anonymous ones skip providers that require a login.
+func (h *SearchHandlers) SearchLoginRequired(name string) func(c *gin.Context) bool {
+	return func(c *gin.Context) bool {
+		provider := name
+		if provider == "" {
+			provider = c.Query("provider")
+		}
+		if provider == search.AllProviders {
+			return c.GetHeader("Authorization") != ""
+		}
+		p, ok := h.registry.Get(provider)
+		return ok && p.LoginRequired()
+	}
+}
+
 type ProviderStatus struct {
 	Name          string `json:"name"`
 	Enabled       bool   `json:"enabled"`
 	Label         string `json:"label"`
 	LoginRequired bool   `json:"login_required"`
+	// Circuit is the provider's circuit breaker state.
+	Circuit search.CircuitStatus `json:"circuit"`
 }
 
 // GetSearchProvidersHandler handles requests for search provider status.
 func (h *SearchHandlers) GetSearchProvidersHandler(c *gin.Context) {
-	// NOTE: For providers like baidu and tianmap that don't have a login_required config yet,
-	// the default value of a boolean in Go is false, which is the desired behavior.
-	providers := []ProviderStatus{
-		{Name: "tianmap", Enabled: true, Label: "天地图", LoginRequired: false},
-		{Name: "baidu", Enabled: true, Label: "百度", LoginRequired: false},
-		{
-			Name:          "gaode",
-			Enabled:       h.cfg.Search.Providers.Gaode.Key != "",
-			Label:         "高德",
-			LoginRequired: h.cfg.Search.Providers.Gaode.LoginRequired,
-		},
+	providers := []ProviderStatus{}
+	for _, p := range h.registry.Providers() {
+		providers = append(providers, ProviderStatus{
+			Name:          p.Name(),
+			Enabled:       p.Enabled(),
+			Label:         p.Label(),
+			LoginRequired: p.LoginRequired(),
+			Circuit:       h.registry.Circuit(p.Name()),
+		})
 	}
 	c.JSON(http.StatusOK, providers)
 }
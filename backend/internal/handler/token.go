@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/token"
+	"github.com/gin-gonic/gin"
+)
+
+// TokenHandler 包含了个人访问令牌管理相关的处理函数
+type TokenHandler struct {
+	tokenService token.Service
+}
+
+// NewTokenHandler 创建一个新的 TokenHandler 实例
+func NewTokenHandler(tokenService token.Service) *TokenHandler {
+	return &TokenHandler{
+		tokenService: tokenService,
+	}
+}
+
+func convertTokenToInfo(t *token.Token) TokenInfo {
+	return TokenInfo{
+		ID:         t.ID,
+		Name:       t.Name,
+		Scopes:     t.Scopes,
+		CreatedAt:  t.CreatedAt,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+	}
+}
+
+// sessionUser 返回通过JWT登录的用户名。令牌管理只允许登录会话操作，
+// 避免泄露的个人访问令牌被用来签发新令牌。
+func sessionUser(c *gin.Context) (string, bool) {
+	username := c.GetString("username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Message: "未提供认证信息",
+			Code:    http.StatusUnauthorized,
+		})
+		return "", false
+	}
+	if c.GetString("auth_type") != AuthTypeJWT {
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Message: "个人访问令牌不能用于管理令牌，请使用登录会话",
+			Code:    http.StatusForbidden,
+		})
+		return "", false
+	}
+	return username, true
+}
+
+// ListTokensHandler 列出当前用户的个人访问令牌
+func (h *TokenHandler) ListTokensHandler(c *gin.Context) {
+	username, ok := sessionUser(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.tokenService.List(username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Message: "获取令牌列表失败: " + err.Error(),
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	infos := make([]TokenInfo, len(tokens))
+	for i := range tokens {
+		infos[i] = convertTokenToInfo(&tokens[i])
+	}
+	c.JSON(http.StatusOK, ListTokensResponse{Tokens: infos})
+}
+
+// CreateTokenHandler 为当前用户签发一个新的个人访问令牌
+func (h *TokenHandler) CreateTokenHandler(c *gin.Context) {
+	username, ok := sessionUser(c)
+	if !ok {
+		return
+	}
+
+	var req CreateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "请求参数错误: " + err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+	if req.ExpiresInDays < 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "过期天数不能为负数",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		t := time.Now().UTC().Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour)
+		expiresAt = &t
+	}
+
+	plaintext, t, err := h.tokenService.Create(username, req.Name, req.Scopes, expiresAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "创建令牌失败: " + err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateTokenResponse{
+		Token: plaintext,
+		Info:  convertTokenToInfo(t),
+	})
+}
+
+// RevokeTokenHandler 吊销当前用户的一个个人访问令牌
+func (h *TokenHandler) RevokeTokenHandler(c *gin.Context) {
+	username, ok := sessionUser(c)
+	if !ok {
+		return
+	}
+
+	id := c.Param("id")
+	if err := h.tokenService.Revoke(username, id); err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, token.ErrNotFound) {
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, ErrorResponse{
+			Message: "吊销令牌失败: " + err.Error(),
+			Code:    statusCode,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, RevokeTokenResponse{
+		Message: fmt.Sprintf("令牌 %s 已吊销", id),
+	})
+}
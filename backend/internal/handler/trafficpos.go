@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
@@ -37,8 +38,17 @@ type TrafficPosData struct {
 
 var trafficPosData *TrafficPosData
 
+// trafficPosErr 记录最近一次加载的结果，供就绪检查使用
+var trafficPosErr = errors.New("交通位置数据尚未加载")
+
+// TrafficPosStatus 返回交通位置数据的加载状态，nil 表示已成功加载
+func TrafficPosStatus() error {
+	return trafficPosErr
+}
+
 // LoadTrafficPosData 加载机场和高铁站数据
-func LoadTrafficPosData(configPath string) error {
+func LoadTrafficPosData(configPath string) (err error) {
+	defer func() { trafficPosErr = err }()
 	trafficPosData = &TrafficPosData{
 		Airports: make(map[string][2]float64),
 		Stations: make(map[string][2]float64),
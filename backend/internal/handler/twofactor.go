@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/chenxuan520/roadmap/backend/internal/twofactor"
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorHandler 包含了两步验证管理相关的处理函数
+type TwoFactorHandler struct {
+	twoFactorService twofactor.Service
+}
+
+// NewTwoFactorHandler 创建一个新的 TwoFactorHandler 实例
+func NewTwoFactorHandler(twoFactorService twofactor.Service) *TwoFactorHandler {
+	return &TwoFactorHandler{
+		twoFactorService: twoFactorService,
+	}
+}
+
+// twoFactorError 将两步验证的业务错误映射为HTTP响应
+func twoFactorError(c *gin.Context, err error) {
+	statusCode := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, twofactor.ErrInvalidCode):
+		statusCode = http.StatusUnauthorized
+	case errors.Is(err, twofactor.ErrNotEnabled),
+		errors.Is(err, twofactor.ErrAlreadyEnabled),
+		errors.Is(err, twofactor.ErrNoPendingSecret):
+		statusCode = http.StatusConflict
+	}
+	c.JSON(statusCode, ErrorResponse{
+		Message: err.Error(),
+		Code:    statusCode,
+	})
+}
+
+// bindCode 解析请求体中的验证码
+func bindCode(c *gin.Context) (string, bool) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Message: "请提供验证码",
+			Code:    http.StatusBadRequest,
+		})
+		return "", false
+	}
+	return req.Code, true
+}
+
+// StatusHandler 返回当前用户的两步验证状态
+func (h *TwoFactorHandler) StatusHandler(c *gin.Context) {
+	username, ok := sessionUser(c)
+	if !ok {
+		return
+	}
+	status, err := h.twoFactorService.Status(username)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// EnrollHandler 生成新的TOTP密钥，等待用户用验证码确认
+func (h *TwoFactorHandler) EnrollHandler(c *gin.Context) {
+	username, ok := sessionUser(c)
+	if !ok {
+		return
+	}
+	secret, uri, err := h.twoFactorService.Enroll(username)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, EnrollTwoFactorResponse{Secret: secret, OTPAuthURI: uri})
+}
+
+// ActivateHandler 校验验证码并启用两步验证，返回一次性恢复码
+func (h *TwoFactorHandler) ActivateHandler(c *gin.Context) {
+	username, ok := sessionUser(c)
+	if !ok {
+		return
+	}
+	code, ok := bindCode(c)
+	if !ok {
+		return
+	}
+	codes, err := h.twoFactorService.Activate(username, code)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodesHandler 作废旧恢复码并生成新的一组
+func (h *TwoFactorHandler) RegenerateRecoveryCodesHandler(c *gin.Context) {
+	username, ok := sessionUser(c)
+	if !ok {
+		return
+	}
+	code, ok := bindCode(c)
+	if !ok {
+		return
+	}
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(username, code)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableHandler 校验验证码后停用两步验证
+func (h *TwoFactorHandler) DisableHandler(c *gin.Context) {
+	username, ok := sessionUser(c)
+	if !ok {
+		return
+	}
+	code, ok := bindCode(c)
+	if !ok {
+		return
+	}
+	if err := h.twoFactorService.Disable(username, code); err != nil {
+		twoFactorError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,167 @@
+// Package health 实现就绪检查：按组件执行检查并汇总状态，
+// 较慢的外部探测可以通过 Cached 复用最近一次的结果。
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 组件与整体状态
+const (
+	StatusOK          = "ok"
+	StatusFail        = "fail"
+	StatusDisabled    = "disabled"
+	StatusDegraded    = "degraded"    // 非关键组件失败，仍可提供服务
+	StatusUnavailable = "unavailable" // 关键组件失败，不应接收流量
+)
+
+// ErrDisabled 由检查函数返回，表示该组件未启用
+var ErrDisabled = errors.New("未启用")
+
+// Check 是一个组件检查。Critical 的组件失败时服务视为未就绪。
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) error
+}
+
+// Result 是单个组件的检查结果
+type Result struct {
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMs int64     `json:"latencyMs"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// Report 是全部组件的检查结果
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Ready 判断服务是否可以接收流量
+func (r Report) Ready() bool { return r.Status != StatusUnavailable }
+
+// Checker 并发执行一组检查
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+}
+
+// NewChecker 创建检查器，timeout 为单次检查的最长耗时
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+// Run 执行全部检查并汇总状态
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(c.checks))}
+	results := make([]Result, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for i, check := range c.checks {
+		res := results[i]
+		report.Checks[check.Name] = res
+		if res.Status != StatusFail {
+			continue
+		}
+		if check.Critical {
+			report.Status = StatusUnavailable
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Run(ctx)
+	res := Result{
+		Status:    StatusOK,
+		Critical:  check.Critical,
+		LatencyMs: time.Since(start).Milliseconds(),
+		CheckedAt: start.UTC(),
+	}
+	switch {
+	case errors.Is(err, ErrDisabled):
+		res.Status = StatusDisabled
+	case err != nil:
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// Cached 包装检查函数，在 ttl 内复用上一次的结果，避免每次探测都访问外部服务。
+// 并发调用时只有一个调用者真正执行检查。
+func Cached(ttl time.Duration, run func(ctx context.Context) error) func(ctx context.Context) error {
+	var (
+		mu      sync.Mutex
+		checked time.Time
+		last    error
+	)
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !checked.IsZero() && time.Since(checked) < ttl {
+			return last
+		}
+		last = run(ctx)
+		checked = time.Now()
+		return last
+	}
+}
+
+// DirWritable 检查目录存在且可写：创建并删除一个临时文件
+func DirWritable(dir string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		f, err := os.CreateTemp(dir, ".healthcheck-*")
+		if err != nil {
+			return fmt.Errorf("目录 %s 不可写: %w", filepath.Clean(dir), err)
+		}
+		name := f.Name()
+		f.Close()
+		if err := os.Remove(name); err != nil {
+			return fmt.Errorf("删除临时文件失败: %w", err)
+		}
+		return nil
+	}
+}
+
+// HTTPReachable 检查能否与 url 建立 HTTP 连接。服务端返回任何状态码都视为可达，
+// 只有网络错误（DNS、连接、超时）才算失败，避免把上游对探测请求的 4xx 误判为故障。
+func HTTPReachable(client *http.Client, url string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+}
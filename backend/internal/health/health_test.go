@@ -0,0 +1,121 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func ok(context.Context) error       { return nil }
+func fail(context.Context) error     { return errors.New("boom") }
+func disabled(context.Context) error { return ErrDisabled }
+
+func TestChecker_AggregatesStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []Check
+		want   string
+	}{
+		{"全部正常", []Check{{Name: "a", Critical: true, Run: ok}, {Name: "b", Run: disabled}}, StatusOK},
+		{"非关键组件失败", []Check{{Name: "a", Critical: true, Run: ok}, {Name: "b", Run: fail}}, StatusDegraded},
+		{"关键组件失败", []Check{{Name: "a", Critical: true, Run: fail}, {Name: "b", Run: fail}}, StatusUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := NewChecker(time.Second, tt.checks...).Run(context.Background())
+			if report.Status != tt.want {
+				t.Errorf("状态 = %s, 期望 %s", report.Status, tt.want)
+			}
+			if report.Ready() != (tt.want != StatusUnavailable) {
+				t.Errorf("Ready() 与状态不一致")
+			}
+		})
+	}
+
+	report := NewChecker(time.Second,
+		Check{Name: "a", Run: fail},
+		Check{Name: "b", Run: disabled},
+	).Run(context.Background())
+	if r := report.Checks["a"]; r.Status != StatusFail || r.Error != "boom" {
+		t.Errorf("失败的组件应包含错误信息: %+v", r)
+	}
+	if r := report.Checks["b"]; r.Status != StatusDisabled || r.Error != "" {
+		t.Errorf("未启用的组件状态应为 disabled: %+v", r)
+	}
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	report := NewChecker(20*time.Millisecond, Check{Name: "slow", Critical: true, Run: slow}).Run(context.Background())
+	if report.Status != StatusUnavailable {
+		t.Fatalf("超时的关键组件应导致未就绪: %+v", report)
+	}
+}
+
+func TestCached(t *testing.T) {
+	calls := 0
+	run := Cached(time.Hour, func(context.Context) error {
+		calls++
+		return errors.New("unreachable")
+	})
+	for i := 0; i < 3; i++ {
+		if err := run(context.Background()); err == nil {
+			t.Fatal("应返回缓存的错误")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("缓存期内只应探测一次, 实际 %d 次", calls)
+	}
+
+	calls = 0
+	run = Cached(0, func(context.Context) error { calls++; return nil })
+	run(context.Background())
+	run(context.Background())
+	if calls != 2 {
+		t.Errorf("缓存过期后应重新探测, 实际 %d 次", calls)
+	}
+}
+
+func TestDirWritable(t *testing.T) {
+	dir := t.TempDir()
+	if err := DirWritable(dir)(context.Background()); err != nil {
+		t.Fatalf("可写目录检查失败: %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("检查后不应留下临时文件: %v", entries)
+	}
+	if err := DirWritable(filepath.Join(dir, "missing"))(context.Background()); err == nil {
+		t.Error("不存在的目录应检查失败")
+	}
+}
+
+func TestHTTPReachable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden) // 上游拒绝探测请求也算可达
+	}))
+	defer srv.Close()
+	client := &http.Client{Timeout: time.Second}
+	if err := HTTPReachable(client, srv.URL)(context.Background()); err != nil {
+		t.Errorf("返回 403 的服务应视为可达: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	if err := HTTPReachable(client, "http://"+addr)(context.Background()); err == nil {
+		t.Error("无法连接的地址应检查失败")
+	}
+}
@@ -0,0 +1,279 @@
+// Package metrics 实现了一个最小化的指标注册表，以 Prometheus 文本格式
+// （text/plain; version=0.0.4）输出计数器与直方图，不依赖 Prometheus 客户端库。
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType 是 Prometheus 文本格式的 Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefBuckets 是与 Prometheus 客户端一致的默认直方图分桶（秒）
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// collector 是可以输出到 /metrics 的一组指标
+type collector interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+// Registry 保存已注册的指标
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry 创建一个空的注册表
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.collectors {
+		if existing.name() == c.name() {
+			panic("metrics: 重复注册的指标 " + c.name())
+		}
+	}
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo 以 Prometheus 文本格式输出全部指标，指标按名称排序
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].name() < collectors[j].name() })
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// vec 是按标签值分组的指标序列的公共部分
+type vec struct {
+	metricName string
+	help       string
+	labels     []string
+
+	mu     sync.Mutex
+	series map[string][]string // 序列键 -> 标签值
+}
+
+func (v *vec) name() string { return v.metricName }
+
+// key 返回标签值对应的序列键，标签数量不符时 panic（属于编程错误）
+func (v *vec) key(values []string) string {
+	if len(values) != len(v.labels) {
+		panic("metrics: " + v.metricName + " 的标签数量不匹配")
+	}
+	return strings.Join(values, "\xff")
+}
+
+// sortedKeys 返回排序后的序列键，调用方需持有锁
+func (v *vec) sortedKeys() []string {
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (v *vec) writeHeader(w *bufio.Writer, typ string) {
+	w.WriteString("# HELP " + v.metricName + " " + escapeHelp(v.help) + "\n")
+	w.WriteString("# TYPE " + v.metricName + " " + typ + "\n")
+}
+
+// CounterVec 是带标签的单调递增计数器
+type CounterVec struct {
+	vec
+	values map[string]float64
+}
+
+// NewCounterVec 创建并注册计数器
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		vec:    vec{metricName: name, help: help, labels: labels, series: map[string][]string{}},
+		values: map[string]float64{},
+	}
+	r.register(c)
+	return c
+}
+
+// Inc 将指定标签的计数加 1
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 将指定标签的计数增加 v（v 不能为负）
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: 计数器不能减少")
+	}
+	k := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.series[k]; !ok {
+		c.series[k] = append([]string(nil), labelValues...)
+	}
+	c.values[k] += v
+}
+
+// Value 返回指定标签的当前计数，主要用于测试
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	k := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[k]
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeHeader(w, "counter")
+	for _, k := range c.sortedKeys() {
+		writeSample(w, c.metricName, c.labels, c.series[k], "", "", c.values[k])
+	}
+}
+
+// HistogramVec 是带标签的直方图
+type HistogramVec struct {
+	vec
+	buckets []float64
+	values  map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64 // 每个分桶（不累计）的观测次数，最后一项为 +Inf
+	sum    float64
+	count  uint64
+}
+
+// NewHistogramVec 创建并注册直方图，buckets 为空时使用 DefBuckets
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &HistogramVec{
+		vec:     vec{metricName: name, help: help, labels: labels, series: map[string][]string{}},
+		buckets: buckets,
+		values:  map[string]*histogram{},
+	}
+	r.register(h)
+	return h
+}
+
+// Observe 记录一次观测值
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	k := h.key(labelValues)
+	i := sort.SearchFloat64s(h.buckets, v) // 第一个 >= v 的分桶
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.values[k]
+	if !ok {
+		h.series[k] = append([]string(nil), labelValues...)
+		s = &histogram{counts: make([]uint64, len(h.buckets)+1)}
+		h.values[k] = s
+	}
+	s.counts[i]++
+	s.sum += v
+	s.count++
+}
+
+// Count 返回指定标签的观测次数，主要用于测试
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	k := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.values[k]; ok {
+		return s.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.writeHeader(w, "histogram")
+	for _, k := range h.sortedKeys() {
+		s, values := h.values[k], h.series[k]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			writeSample(w, h.metricName+"_bucket", h.labels, values, "le", formatFloat(upper), float64(cumulative))
+		}
+		writeSample(w, h.metricName+"_bucket", h.labels, values, "le", "+Inf", float64(s.count))
+		writeSample(w, h.metricName+"_sum", h.labels, values, "", "", s.sum)
+		writeSample(w, h.metricName+"_count", h.labels, values, "", "", float64(s.count))
+	}
+}
+
+// writeSample 输出一行样本，extraName 非空时追加一个额外标签（直方图的 le）
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraName, extraValue string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(label + `="` + escapeLabel(values[i]) + `"`)
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraName + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
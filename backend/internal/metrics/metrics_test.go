@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("test_requests_total", "请求数", "route", "status")
+	latency := r.NewHistogramVec("test_duration_seconds", "耗时", []float64{0.1, 1}, "route")
+
+	requests.Inc("/b", "200")
+	requests.Add(2, "/a", "500")
+	requests.Inc("/a", "500")
+	latency.Observe(0.05, `/x"y`)
+	latency.Observe(0.1, `/x"y`)
+	latency.Observe(3, `/x"y`)
+
+	var sb strings.Builder
+	if _, err := r.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_duration_seconds 耗时
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{route="/x\"y",le="0.1"} 2
+test_duration_seconds_bucket{route="/x\"y",le="1"} 2
+test_duration_seconds_bucket{route="/x\"y",le="+Inf"} 3
+test_duration_seconds_sum{route="/x\"y"} 3.15
+test_duration_seconds_count{route="/x\"y"} 3
+# HELP test_requests_total 请求数
+# TYPE test_requests_total counter
+test_requests_total{route="/a",status="500"} 3
+test_requests_total{route="/b",status="200"} 1
+`
+	if got := sb.String(); got != want {
+		t.Errorf("输出不符合预期:\n%s\n期望:\n%s", got, want)
+	}
+	if got := requests.Value("/a", "500"); got != 3 {
+		t.Errorf("Value = %v, 期望 3", got)
+	}
+	if got := latency.Count(`/x"y`); got != 3 {
+		t.Errorf("Count = %d, 期望 3", got)
+	}
+}
+
+func TestRegistry_Misuse(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("dup_total", "x", "a")
+
+	mustPanic := func(name string, fn func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s 应当 panic", name)
+			}
+		}()
+		fn()
+	}
+	mustPanic("重复注册", func() { r.NewCounterVec("dup_total", "x") })
+	mustPanic("标签数量不匹配", func() { c.Inc("a", "b") })
+	mustPanic("计数器减少", func() { c.Add(-1, "a") })
+}
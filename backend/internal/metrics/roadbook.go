@@ -0,0 +1,43 @@
+package metrics
+
+// Default 是服务使用的全局注册表，/metrics 输出其中的全部指标
+var Default = NewRegistry()
+
+// AI 流式响应通常持续数秒到数分钟，使用更宽的分桶
+var aiBuckets = []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300}
+
+// 服务指标。标签只使用有限取值（路由模板、服务商名、操作名），避免序列数量膨胀。
+var (
+	HTTPRequests = Default.NewCounterVec("roadbook_http_requests_total",
+		"HTTP 请求总数", "method", "route", "status")
+	HTTPRequestDuration = Default.NewHistogramVec("roadbook_http_request_duration_seconds",
+		"HTTP 请求处理耗时（秒）", nil, "method", "route")
+
+	SearchRequests = Default.NewCounterVec("roadbook_search_requests_total",
+		"地图搜索上游调用次数，result 为 ok 或 error", "provider", "result")
+	SearchDuration = Default.NewHistogramVec("roadbook_search_duration_seconds",
+		"地图搜索上游调用耗时（秒）", nil, "provider")
+	SearchCacheRequests = Default.NewCounterVec("roadbook_search_cache_requests_total",
+		"地图搜索缓存查询次数，result 为 hit（命中）、miss（调用上游）或 shared（等待相同查询的上游调用）", "provider", "result")
+
+	AIStreamDuration = Default.NewHistogramVec("roadbook_ai_stream_duration_seconds",
+		"AI 对话从发起请求到流式响应结束的耗时（秒），result 为 ok、error 或 interrupted", aiBuckets, "model", "result")
+	AITokens = Default.NewCounterVec("roadbook_ai_tokens_total",
+		"AI 对话消耗的 token 数，type 为 prompt 或 completion，只统计服务商返回的 usage", "model", "type")
+	AIStreamChunks = Default.NewCounterVec("roadbook_ai_stream_chunks_total",
+		"AI 流式响应中包含内容的片段数，服务商未返回 usage 时可用于粗略估计输出量", "model")
+
+	PlanRepositoryDuration = Default.NewHistogramVec("roadbook_plan_repository_duration_seconds",
+		"计划仓库操作耗时（秒），result 为 ok 或 error", nil, "op", "result")
+
+	RateLimitRejections = Default.NewCounterVec("roadbook_ratelimit_rejections_total",
+		"被限流拒绝的请求数", "policy")
+)
+
+// Result 将错误转换为 result 标签值
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,230 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultCompressMinSize 小于该大小的响应不压缩，压缩后往往更大
+const defaultCompressMinSize = 1024
+
+// encoder 是 gzip.Writer 与 brotli.Writer 共同的方法
+type encoder interface {
+	io.Writer
+	Flush() error
+	Close() error
+	Reset(w io.Writer)
+}
+
+// Compress 对支持 brotli 或 gzip 的客户端压缩 JSON、文本等响应，两者都支持时优先使用 brotli。
+// 以下响应原样返回：已设置 Content-Encoding 的（如预压缩的前端文件）、SSE 流式响应（AI 对话需要逐条送达）、
+// 不足 min_size_bytes 的小响应以及图片等不可压缩的类型。
+func Compress(cfg config.CompressionConfig) gin.HandlerFunc {
+	level := cfg.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	minSize := cfg.MinSizeBytes
+	if minSize == 0 {
+		minSize = defaultCompressMinSize
+	}
+	brotliLevel := cfg.BrotliLevel
+	if brotliLevel == 0 {
+		brotliLevel = brotli.DefaultCompression
+	}
+	pools := map[string]*sync.Pool{
+		"br": {New: func() interface{} {
+			return brotli.NewWriterLevel(nil, brotliLevel)
+		}},
+		"gzip": {New: func() interface{} {
+			zw, _ := gzip.NewWriterLevel(nil, level)
+			return zw
+		}},
+	}
+
+	return func(c *gin.Context) {
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if c.Request.Method == http.MethodHead || encoding == "" {
+			// 压缩过的响应都带有 Vary: Accept-Encoding，共享缓存不会把它们交给这类客户端
+			c.Next()
+			return
+		}
+		w := &compressWriter{ResponseWriter: c.Writer, encoding: encoding, pool: pools[encoding], minSize: minSize, status: http.StatusOK}
+		c.Writer = w
+		defer func() {
+			w.finish()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// negotiateEncoding 按 br、gzip 的顺序选择客户端接受的编码（q=0 表示拒绝），都不接受时返回空。
+// "*" 只当作 gzip，不把 brotli 发给没有明确声明支持它的客户端
+func negotiateEncoding(acceptEncoding string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := strings.ReplaceAll(params, " ", "")
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	if accepted["br"] {
+		return "br"
+	}
+	if gz, ok := accepted["gzip"]; ok && gz || !ok && accepted["*"] {
+		return "gzip"
+	}
+	return ""
+}
+
+// compressible 判断内容类型是否值得压缩
+func compressible(contentType string) bool {
+	ct, _, _ := strings.Cut(contentType, ";")
+	ct = strings.TrimSpace(strings.ToLower(ct))
+	if ct == "text/event-stream" {
+		return false
+	}
+	return strings.HasPrefix(ct, "text/") ||
+		strings.HasSuffix(ct, "json") ||
+		strings.HasSuffix(ct, "javascript") ||
+		strings.HasSuffix(ct, "xml")
+}
+
+// compressWriter 先缓冲响应体，达到 minSize 或处理器主动 Flush 时才决定是否压缩
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string // "br" 或 "gzip"
+	pool     *sync.Pool
+	minSize  int
+
+	status  int
+	buf     bytes.Buffer
+	decided bool
+	zw      encoder // 决定压缩后非空
+	size    int     // 未压缩的字节数
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if !w.decided {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow 推迟到决定是否压缩之后，届时才能确定 Content-Encoding 等响应头
+func (w *compressWriter) WriteHeaderNow() {}
+
+func (w *compressWriter) Status() int {
+	if w.decided {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *compressWriter) Written() bool {
+	return w.decided || w.buf.Len() > 0
+}
+
+func (w *compressWriter) Size() int {
+	if !w.Written() {
+		return -1
+	}
+	return w.size
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	w.size += len(data)
+	if !w.decided {
+		w.buf.Write(data)
+		if w.buf.Len() < w.minSize {
+			return len(data), nil
+		}
+		w.decide(true)
+		return len(data), w.flushBuffer()
+	}
+	if w.zw != nil {
+		return w.zw.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush 用于流式响应：此时尚未达到 minSize 也按内容类型决定，之后逐块压缩并立即发送
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(true)
+		w.flushBuffer()
+	}
+	if w.zw != nil {
+		w.zw.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide 根据状态码与响应头决定是否压缩，并写出响应头。large 表示响应体足够大
+func (w *compressWriter) decide(large bool) {
+	w.decided = true
+	h := w.ResponseWriter.Header()
+	eligible := h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type"))
+	if eligible || w.status == http.StatusNotModified {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if eligible && large && bodyAllowed(w.status) {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		// 压缩后的字节不同，强 ETag 改为弱 ETag；If-None-Match 按弱比较仍能命中
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		w.zw = w.pool.Get().(encoder)
+		w.zw.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *compressWriter) flushBuffer() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.zw != nil {
+		_, err = w.zw.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+// finish 在处理器返回后写出剩余数据
+func (w *compressWriter) finish() {
+	if !w.decided {
+		if w.buf.Len() == 0 && w.status == http.StatusOK {
+			// 处理器没有写任何内容，交给 gin 按默认方式结束响应
+			w.decided = true
+			return
+		}
+		w.decide(false)
+		w.flushBuffer()
+	}
+	if w.zw != nil {
+		w.zw.Close()
+		w.pool.Put(w.zw)
+		w.zw = nil
+	}
+}
+
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
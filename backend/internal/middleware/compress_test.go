@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+var largeJSON = `{"plan": "` + strings.Repeat("北京 上海 ", 500) + `"}`
+
+func newCompressRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Compress(config.CompressionConfig{Enabled: true}))
+	r.GET("/large", func(c *gin.Context) { c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(largeJSON)) })
+	r.GET("/small", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+	r.GET("/image", func(c *gin.Context) { c.Data(http.StatusOK, "image/png", []byte(largeJSON)) })
+	r.GET("/precompressed", func(c *gin.Context) {
+		c.Header("Content-Encoding", "br")
+		c.Data(http.StatusOK, "text/html", []byte(largeJSON))
+	})
+	r.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Writer.WriteString("data: hi\n\n")
+		c.Writer.Flush()
+	})
+	r.GET("/plan", ETag("private, no-cache"), func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(largeJSON))
+	})
+	return r
+}
+
+func getWithHeaders(r *gin.Engine, path string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func gunzip(t *testing.T, data []byte) string {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("响应不是有效的 gzip: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestCompress_GzipsLargeResponses(t *testing.T) {
+	r := newCompressRouter()
+	gz := map[string]string{"Accept-Encoding": "gzip, deflate"}
+
+	w := getWithHeaders(r, "/large", gz)
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("大的 JSON 响应应压缩, Content-Encoding=%q", w.Header().Get("Content-Encoding"))
+	}
+	if w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("应设置 Vary: Accept-Encoding, 得到 %q", w.Header().Get("Vary"))
+	}
+	if w.Body.Len() >= len(largeJSON) {
+		t.Errorf("压缩后应更小: %d >= %d", w.Body.Len(), len(largeJSON))
+	}
+	if got := gunzip(t, w.Body.Bytes()); got != largeJSON {
+		t.Error("解压后的内容不一致")
+	}
+
+	for path, header := range map[string]map[string]string{
+		"/large":         {"Accept-Encoding": "gzip;q=0, identity"},
+		"/small":         gz,
+		"/image":         gz,
+		"/precompressed": gz,
+		"/stream":        gz,
+	} {
+		w := getWithHeaders(r, path, header)
+		if enc := w.Header().Get("Content-Encoding"); enc == "gzip" {
+			t.Errorf("%s: 不应压缩", path)
+		}
+		if w.Code != http.StatusOK || w.Body.Len() == 0 {
+			t.Errorf("%s: 响应应原样返回, 得到 %d", path, w.Code)
+		}
+	}
+	if w := getWithHeaders(r, "/stream", gz); w.Body.String() != "data: hi\n\n" {
+		t.Errorf("SSE 响应应原样返回, 得到 %q", w.Body.String())
+	}
+}
+
+func TestCompress_PrefersBrotli(t *testing.T) {
+	r := newCompressRouter()
+
+	w := getWithHeaders(r, "/large", map[string]string{"Accept-Encoding": "gzip, deflate, br"})
+	if w.Header().Get("Content-Encoding") != "br" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("客户端支持 br 时应优先使用 brotli, Content-Encoding=%q", w.Header().Get("Content-Encoding"))
+	}
+	body, err := io.ReadAll(brotli.NewReader(w.Body))
+	if err != nil {
+		t.Fatalf("响应不是有效的 brotli: %v", err)
+	}
+	if string(body) != largeJSON {
+		t.Error("解压后的内容不一致")
+	}
+
+	for header, want := range map[string]string{
+		"gzip, br;q=0": "gzip",
+		"*":            "gzip",
+		"br, *;q=0":    "br",
+		"*, gzip;q=0":  "",
+		"identity":     "",
+	} {
+		if got := getWithHeaders(r, "/large", map[string]string{"Accept-Encoding": header}).Header().Get("Content-Encoding"); got != want {
+			t.Errorf("Accept-Encoding: %s 应返回 %q, 得到 %q", header, want, got)
+		}
+	}
+}
+
+func TestCompress_WithETag(t *testing.T) {
+	r := newCompressRouter()
+
+	w := getWithHeaders(r, "/plan", map[string]string{"Accept-Encoding": "gzip"})
+	etag := w.Header().Get("ETag")
+	if w.Header().Get("Content-Encoding") != "gzip" || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("压缩后的响应应使用弱 ETag, Content-Encoding=%q ETag=%q", w.Header().Get("Content-Encoding"), etag)
+	}
+
+	w = getWithHeaders(r, "/plan", map[string]string{"Accept-Encoding": "gzip", "If-None-Match": etag})
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("弱 ETag 也应命中 If-None-Match, 得到 %d", w.Code)
+	}
+	if w := getWithHeaders(r, "/plan", map[string]string{"If-None-Match": strings.TrimPrefix(etag, "W/")}); w.Code != http.StatusNotModified {
+		t.Errorf("未压缩的客户端使用强 ETag 应返回 304, 得到 %d", w.Code)
+	}
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// CORS 的默认值，对应 cors 配置中留空的字段
+var (
+	defaultCORSMethods        = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	defaultCORSHeaders        = []string{"Content-Type", "Authorization", "X-Request-ID", "traceparent", "tracestate"}
+	defaultCORSExposedHeaders = []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After", "X-Request-ID", "X-Search-Provider", "X-Search-Failed-Providers"}
+)
+
+// corsPolicy 是根据一份配置预先计算好的 CORS 规则
+type corsPolicy struct {
+	cfg       *config.Config // 生成该规则的配置快照，配置热加载后据此判断是否需要重新计算
+	exact     map[string]bool
+	wildcards []wildcardOrigin
+	allowNull bool
+	methods   string
+	headers   string
+	exposed   string
+	maxAge    string
+}
+
+// wildcardOrigin 表示 https://*.example.com 形式的来源，匹配任意层级的子域名，但不匹配 example.com 本身
+type wildcardOrigin struct {
+	prefix string // "https://"
+	suffix string // ".example.com"，带端口时为 ".example.com:8443"
+}
+
+func (w wildcardOrigin) match(origin string) bool {
+	if !strings.HasPrefix(origin, w.prefix) || !strings.HasSuffix(origin, w.suffix) {
+		return false
+	}
+	sub := origin[len(w.prefix) : len(origin)-len(w.suffix)]
+	return sub != "" && !strings.ContainsAny(sub, "/:@?#")
+}
+
+func newCORSPolicy(cfg *config.Config) *corsPolicy {
+	p := &corsPolicy{
+		cfg:       cfg,
+		exact:     make(map[string]bool),
+		allowNull: cfg.AllowNullOriginForDev,
+		methods:   joinOrDefault(cfg.CORS.AllowedMethods, defaultCORSMethods),
+		headers:   joinOrDefault(cfg.CORS.AllowedHeaders, defaultCORSHeaders),
+		exposed:   joinOrDefault(cfg.CORS.ExposedHeaders, defaultCORSExposedHeaders),
+	}
+	if cfg.CORS.MaxAgeSeconds > 0 {
+		p.maxAge = strconv.Itoa(cfg.CORS.MaxAgeSeconds)
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		if scheme, rest, ok := strings.Cut(origin, "://*."); ok {
+			p.wildcards = append(p.wildcards, wildcardOrigin{prefix: scheme + "://", suffix: "." + rest})
+		} else {
+			p.exact[origin] = true
+		}
+	}
+	return p
+}
+
+func joinOrDefault(values, defaults []string) string {
+	if len(values) == 0 {
+		values = defaults
+	}
+	return strings.Join(values, ", ")
+}
+
+// allowed 判断来源是否在白名单中，"null" 只在开发模式下允许（file:// 页面）
+func (p *corsPolicy) allowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if origin == "null" {
+		return p.allowNull
+	}
+	if p.exact[origin] {
+		return true
+	}
+	for _, w := range p.wildcards {
+		if w.match(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS 按配置处理跨域请求。允许的来源会收到 Access-Control-Allow-Origin 等响应头；
+// 预检请求（OPTIONS）在来源允许时返回 204，否则返回 403。
+// current 在每次请求时调用，配置热加载后自动使用新的来源列表。
+func CORS(current func() *config.Config) gin.HandlerFunc {
+	var cached atomic.Pointer[corsPolicy]
+	policy := func() *corsPolicy {
+		cfg := current()
+		if p := cached.Load(); p != nil && p.cfg == cfg {
+			return p
+		}
+		p := newCORSPolicy(cfg)
+		cached.Store(p)
+		return p
+	}
+
+	return func(c *gin.Context) {
+		p := policy()
+		origin := c.Request.Header.Get("Origin")
+		isAllowed := p.allowed(origin)
+		preflight := c.Request.Method == http.MethodOptions
+
+		// 响应内容随 Origin 变化，避免共享缓存把一个来源的响应交给另一个来源
+		h := c.Writer.Header()
+		h.Add("Vary", "Origin")
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if isAllowed {
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Set("Access-Control-Allow-Methods", p.methods)
+			h.Set("Access-Control-Allow-Headers", p.headers)
+			h.Set("Access-Control-Allow-Credentials", "true")
+			h.Set("Access-Control-Expose-Headers", p.exposed)
+			if preflight && p.maxAge != "" {
+				h.Set("Access-Control-Max-Age", p.maxAge)
+			}
+		}
+
+		if preflight {
+			if isAllowed {
+				c.AbortWithStatus(http.StatusNoContent)
+			} else {
+				c.AbortWithStatus(http.StatusForbidden)
+			}
+			return
+		}
+
+		c.Next()
+	}
+}
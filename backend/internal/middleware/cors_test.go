@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+func newCORSRouter(current func() *config.Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(CORS(current))
+	r.GET("/api/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func doCORS(r *gin.Engine, method, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/ping", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORS_Origins(t *testing.T) {
+	cfg := &config.Config{
+		AllowedOrigins:        []string{"https://roadbook.example.com", "https://*.preview.example.com"},
+		AllowNullOriginForDev: true,
+	}
+	r := newCORSRouter(func() *config.Config { return cfg })
+
+	for origin, allowed := range map[string]bool{
+		"https://roadbook.example.com":          true,
+		"https://pr-12.preview.example.com":     true,
+		"https://a.b.preview.example.com":       true,
+		"null":                                  true,
+		"https://preview.example.com":           false, // 通配符不匹配域名本身
+		"http://pr-12.preview.example.com":      false, // 协议不同
+		"https://evil.com/.preview.example.com": false,
+		"https://evilpreview.example.com":       false,
+		"https://roadbook.example.com.evil.com": false,
+		"":                                      false,
+	} {
+		w := doCORS(r, http.MethodGet, origin)
+		if w.Code != http.StatusOK {
+			t.Errorf("%q: 非预检请求不应被拦截, 得到 %d", origin, w.Code)
+		}
+		got := w.Header().Get("Access-Control-Allow-Origin")
+		if allowed && got != origin {
+			t.Errorf("%q: 应允许, Access-Control-Allow-Origin=%q", origin, got)
+		}
+		if !allowed && got != "" {
+			t.Errorf("%q: 不应允许, Access-Control-Allow-Origin=%q", origin, got)
+		}
+		if w.Header().Get("Vary") != "Origin" {
+			t.Errorf("%q: 应设置 Vary: Origin, 得到 %q", origin, w.Header().Values("Vary"))
+		}
+	}
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	cfg := &config.Config{
+		AllowedOrigins: []string{"https://roadbook.example.com"},
+		CORS: config.CORSConfig{
+			AllowedMethods: []string{"GET", "POST"},
+			AllowedHeaders: []string{"Content-Type", "X-Custom"},
+			ExposedHeaders: []string{"X-Request-ID"},
+			MaxAgeSeconds:  600,
+		},
+	}
+	r := newCORSRouter(func() *config.Config { return cfg })
+
+	w := doCORS(r, http.MethodOptions, "https://roadbook.example.com")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("允许来源的预检请求应返回 204, 得到 %d", w.Code)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Methods":     "GET, POST",
+		"Access-Control-Allow-Headers":     "Content-Type, X-Custom",
+		"Access-Control-Expose-Headers":    "X-Request-ID",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "600",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, 期望 %q", header, got, want)
+		}
+	}
+	if vary := w.Header().Values("Vary"); len(vary) != 3 {
+		t.Errorf("预检响应应随 Origin 与请求的方法、头部变化, Vary=%q", vary)
+	}
+
+	if w := doCORS(r, http.MethodOptions, "https://evil.com"); w.Code != http.StatusForbidden {
+		t.Errorf("未允许来源的预检请求应返回 403, 得到 %d", w.Code)
+	}
+	// Max-Age 只在预检响应中出现
+	if got := doCORS(r, http.MethodGet, "https://roadbook.example.com").Header().Get("Access-Control-Max-Age"); got != "" {
+		t.Errorf("普通请求不应设置 Access-Control-Max-Age, 得到 %q", got)
+	}
+}
+
+func TestCORS_DefaultsAndReload(t *testing.T) {
+	cfg := &config.Config{AllowedOrigins: []string{"https://a.example.com"}}
+	r := newCORSRouter(func() *config.Config { return cfg })
+
+	w := doCORS(r, http.MethodOptions, "https://a.example.com")
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST, PUT, DELETE, OPTIONS" {
+		t.Errorf("未配置时应使用默认方法, 得到 %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Max-Age"); got != "" {
+		t.Errorf("max_age_seconds 为 0 时不应设置 Access-Control-Max-Age, 得到 %q", got)
+	}
+
+	// 配置热加载后使用新的来源列表
+	cfg = &config.Config{AllowedOrigins: []string{"https://b.example.com"}}
+	if w := doCORS(r, http.MethodOptions, "https://a.example.com"); w.Code != http.StatusForbidden {
+		t.Errorf("移除的来源应被拒绝, 得到 %d", w.Code)
+	}
+	if w := doCORS(r, http.MethodOptions, "https://b.example.com"); w.Code != http.StatusNoContent {
+		t.Errorf("新增的来源应被允许, 得到 %d", w.Code)
+	}
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag 为 200 响应计算强 ETag（响应体的 SHA-256），请求的 If-None-Match 命中时返回 304 且不发送响应体，
+// 用于计划内容等较大且很少变化的 JSON。cacheControl 非空且处理器未设置 Cache-Control 时一并设置。
+// 响应在内存中缓冲，不能用于流式响应。
+func ETag(cacheControl string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+		w := &etagWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		h := w.Header()
+		if w.status == http.StatusOK && w.buf.Len() > 0 {
+			sum := sha256.Sum256(w.buf.Bytes())
+			etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+			h.Set("ETag", etag)
+			if cacheControl != "" && h.Get("Cache-Control") == "" {
+				h.Set("Cache-Control", cacheControl)
+			}
+			if etagMatch(c.GetHeader("If-None-Match"), etag) {
+				h.Del("Content-Type")
+				h.Del("Content-Length")
+				c.Writer.WriteHeader(http.StatusNotModified)
+				c.Writer.WriteHeaderNow()
+				return
+			}
+		}
+		c.Writer.WriteHeader(w.status)
+		if w.buf.Len() > 0 {
+			c.Writer.Write(w.buf.Bytes())
+		} else if w.headerWritten {
+			c.Writer.WriteHeaderNow()
+		}
+	}
+}
+
+// etagMatch 按 If-None-Match 的弱比较规则判断：忽略 W/ 前缀，"*" 匹配任意值
+func etagMatch(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// etagWriter 缓冲状态码与响应体，处理器返回后再决定发送 304 还是完整响应
+type etagWriter struct {
+	gin.ResponseWriter
+	status        int
+	headerWritten bool
+	buf           bytes.Buffer
+}
+
+func (w *etagWriter) WriteHeader(code int) { w.status = code }
+
+func (w *etagWriter) WriteHeaderNow() { w.headerWritten = true }
+
+func (w *etagWriter) Status() int { return w.status }
+
+func (w *etagWriter) Written() bool { return w.headerWritten || w.buf.Len() > 0 }
+
+func (w *etagWriter) Size() int {
+	if !w.Written() {
+		return -1
+	}
+	return w.buf.Len()
+}
+
+func (w *etagWriter) Write(data []byte) (int, error) { return w.buf.Write(data) }
+
+func (w *etagWriter) WriteString(s string) (int, error) { return w.buf.WriteString(s) }
+
+// Flush 不透传，响应需要完整缓冲后才能计算 ETag
+func (w *etagWriter) Flush() {}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	content := "v1"
+	r := gin.New()
+	r.GET("/plans/:id", ETag("private, no-cache"), func(c *gin.Context) {
+		if c.Param("id") == "missing" {
+			c.JSON(http.StatusNotFound, gin.H{"message": "计划未找到"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"content": content})
+	})
+	get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/plans/a", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "v1") {
+		t.Fatalf("首次请求应返回完整内容, 得到 %d %s", w.Code, w.Body.String())
+	}
+	if !strings.HasPrefix(etag, `"`) {
+		t.Errorf("应返回强 ETag, 得到 %q", etag)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "private, no-cache" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+
+	for _, header := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		w := get("/plans/a", header)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("If-None-Match: %s 应返回 304, 得到 %d", header, w.Code)
+		}
+		if w.Header().Get("ETag") != etag {
+			t.Errorf("304 响应应带有 ETag")
+		}
+	}
+
+	// 内容变化后 ETag 随之变化
+	content = "v2"
+	if w := get("/plans/a", etag); w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("内容变化后应返回新内容与新 ETag, 得到 %d", w.Code)
+	}
+
+	// 错误响应不带 ETag
+	w = get("/plans/missing", "*")
+	if w.Code != http.StatusNotFound || w.Header().Get("ETag") != "" || w.Body.Len() == 0 {
+		t.Errorf("错误响应应原样返回且不带 ETag, 得到 %d ETag=%q", w.Code, w.Header().Get("ETag"))
+	}
+}
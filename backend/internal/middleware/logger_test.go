@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chenxuan520/roadmap/backend/internal/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// captureLogs 将全局日志器替换为写入缓冲区的 JSON 日志器，测试结束后恢复
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+// logLines 解析缓冲区中的每一行 JSON 日志
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("日志不是合法的 JSON: %q", line)
+		}
+		lines = append(lines, m)
+	}
+	return lines
+}
+
+func newLoggedRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestLogger(), Recovery())
+	r.GET("/plans/:id", func(c *gin.Context) {
+		c.Set("username", "alice")
+		logging.FromContext(c.Request.Context()).Info("handler")
+		c.String(http.StatusOK, "hello")
+	})
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	return r
+}
+
+func TestRequestLogger_PropagatesRequestID(t *testing.T) {
+	buf := captureLogs(t)
+	r := newLoggedRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/plans/42", nil)
+	req.Header.Set(RequestIDHeader, "upstream-id-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if got := w.Header().Get(RequestIDHeader); got != "upstream-id-1" {
+		t.Fatalf("应沿用上游的请求ID, 得到 %q", got)
+	}
+
+	lines := logLines(t, buf)
+	if len(lines) != 2 {
+		t.Fatalf("期望 2 条日志（处理函数与访问日志）, 得到 %d", len(lines))
+	}
+	for _, line := range lines {
+		if line["request_id"] != "upstream-id-1" {
+			t.Errorf("日志缺少请求ID: %v", line)
+		}
+	}
+
+	access := lines[1]
+	want := map[string]interface{}{
+		"msg":    "request",
+		"method": "GET",
+		"route":  "/plans/:id",
+		"path":   "/plans/42",
+		"status": float64(200),
+		"bytes":  float64(5),
+		"user":   "alice",
+	}
+	for k, v := range want {
+		if access[k] != v {
+			t.Errorf("访问日志字段 %s = %v, 期望 %v", k, access[k], v)
+		}
+	}
+	if _, ok := access["latency_ms"]; !ok {
+		t.Error("访问日志缺少 latency_ms")
+	}
+}
+
+func TestRequestLogger_GeneratesRequestID(t *testing.T) {
+	captureLogs(t)
+	r := newLoggedRouter()
+
+	for _, incoming := range []string{"", "bad id with spaces", strings.Repeat("a", maxRequestIDLength+1)} {
+		req := httptest.NewRequest(http.MethodGet, "/plans/1", nil)
+		if incoming != "" {
+			req.Header.Set(RequestIDHeader, incoming)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		got := w.Header().Get(RequestIDHeader)
+		if got == "" || got == incoming || len(got) != 32 {
+			t.Errorf("传入 %q 时应生成新的请求ID, 得到 %q", incoming, got)
+		}
+	}
+}
+
+func TestRecovery_LogsPanicWithRequestID(t *testing.T) {
+	buf := captureLogs(t)
+	r := newLoggedRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set(RequestIDHeader, "panic-id")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("panic 后应返回 500, 得到 %d", w.Code)
+	}
+	lines := logLines(t, buf)
+	if len(lines) != 2 || lines[0]["msg"] != "panic recovered" || lines[0]["request_id"] != "panic-id" {
+		t.Fatalf("应记录带请求ID的 panic 日志: %v", lines)
+	}
+	if lines[1]["level"] != "ERROR" || lines[1]["status"] != float64(500) {
+		t.Errorf("5xx 的访问日志应为 ERROR 级别: %v", lines[1])
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics 记录每个请求的次数与耗时。route 标签使用路由模板（如 /api/v1/plans/:id），
+// 未匹配的请求统一记为 unmatched，method 标签只取常见方法、其余记为 other，避免任意路径或方法产生大量序列。
+// 需要注册在 Recovery 之前，否则 panic 会越过这里，对应的 500 不会被记录。
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := methodLabel(c.Request.Method)
+		metrics.HTTPRequests.Inc(method, route, strconv.Itoa(c.Writer.Status()))
+		metrics.HTTPRequestDuration.Observe(time.Since(start).Seconds(), method, route)
+	}
+}
+
+// methodLabel 把请求方法映射到有限的取值，客户端可以发送任意方法名
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodHead, http.MethodOptions:
+		return method
+	}
+	return "other"
+}
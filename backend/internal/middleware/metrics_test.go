@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetrics_UsesRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Metrics())
+	r.GET("/metrics-test/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	before := metrics.HTTPRequests.Value("GET", "/metrics-test/:id", "204")
+	unmatched := metrics.HTTPRequests.Value("GET", "unmatched", "404")
+	for _, path := range []string{"/metrics-test/1", "/metrics-test/2", "/nope"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := metrics.HTTPRequests.Value("GET", "/metrics-test/:id", "204") - before; got != 2 {
+		t.Errorf("同一路由模板应计数 2 次, 得到 %v", got)
+	}
+	if got := metrics.HTTPRequests.Value("GET", "unmatched", "404") - unmatched; got != 1 {
+		t.Errorf("未匹配的路径应记为 unmatched, 得到 %v", got)
+	}
+}
+
+func TestMetrics_BoundsMethodLabel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Metrics())
+
+	before := metrics.HTTPRequests.Value("other", "unmatched", "404")
+	for _, method := range []string{"BOGUS", "X-RANDOM-1", "get"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/metrics-method", nil))
+	}
+
+	if got := metrics.HTTPRequests.Value("other", "unmatched", "404") - before; got != 3 {
+		t.Errorf("未知的请求方法应记为 other, 得到 %v", got)
+	}
+	if got := metrics.HTTPRequests.Value("BOGUS", "unmatched", "404"); got != 0 {
+		t.Errorf("不应为任意方法名创建序列, 得到 %v", got)
+	}
+}
+
+func TestMetrics_CountsRecoveredPanics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Metrics(), Recovery())
+	r.GET("/metrics-panic", func(c *gin.Context) { panic("boom") })
+
+	before := metrics.HTTPRequests.Value("GET", "/metrics-panic", "500")
+	observed := metrics.HTTPRequestDuration.Count("GET", "/metrics-panic")
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics-panic", nil))
+
+	if got := metrics.HTTPRequests.Value("GET", "/metrics-panic", "500") - before; got != 1 {
+		t.Errorf("panic 转成的 500 应计入请求数, 得到 %v", got)
+	}
+	if got := metrics.HTTPRequestDuration.Count("GET", "/metrics-panic") - observed; got != 1 {
+		t.Errorf("panic 的请求应记录耗时, 得到 %v", got)
+	}
+}
+
+func TestRateLimit_CountsRejections(t *testing.T) {
+	r := newLimitedRouter(t, config.RateLimitConfig{
+		Policies: map[string]config.RateLimitPolicy{"metrics-test": {Rate: 0.01, Burst: 1}},
+	}, "metrics-test")
+
+	before := metrics.RateLimitRejections.Value("metrics-test")
+	doRequest(r, "")
+	doRequest(r, "")
+	doRequest(r, "")
+	if got := metrics.RateLimitRejections.Value("metrics-test") - before; got != 2 {
+		t.Errorf("应记录 2 次限流拒绝, 得到 %v", got)
+	}
+}
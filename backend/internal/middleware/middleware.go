@@ -4,88 +4,15 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
-	"sync"
-	"time"
 
 	"github.com/chenxuan520/roadmap/backend/internal/auth"
 	"github.com/chenxuan520/roadmap/backend/internal/handler" // 导入 handler 包以使用 ErrorResponse
+	"github.com/chenxuan520/roadmap/backend/internal/token"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/time/rate"
 )
 
-// 定义每个IP的限流器存储（惰性删除，避免内存增长）
-type ipLimiterEntry struct {
-	limiter	*rate.Limiter
-	lastSeen	time.Time
-}
-
-var (
-	ipRateLimiters	= make(map[string]*ipLimiterEntry)
-	mu			sync.Mutex
-	lastCleanup	time.Time
-)
-
-// 每个IP限流器的存活时长（无访问则淘汰）
-const ipLimiterTTL = 1 * time.Minute
-// 惰性删除触发的最小间隔，避免每次请求都全量扫描
-const cleanupInterval = 1 * time.Minute
-// 当map尺寸超过阈值时强制执行一次惰性清理（防御极端情况）
-const maxLimiters = 10000
-
-// 惰性清理：仅在满足条件时扫描并删除过期项
-func lazyCleanupIfNeeded(now time.Time) {
-	if lastCleanup.IsZero() || now.Sub(lastCleanup) >= cleanupInterval || len(ipRateLimiters) > maxLimiters {
-		cutoff := now.Add(-ipLimiterTTL)
-		for ip, entry := range ipRateLimiters {
-			if entry.lastSeen.Before(cutoff) {
-				delete(ipRateLimiters, ip)
-			}
-		}
-		lastCleanup = now
-	}
-}
-
-// GetIPRateLimiter 获取指定IP的限流器，如果不存在则创建一个（含惰性删除）
-func GetIPRateLimiter(ip string) *rate.Limiter {
-	mu.Lock()
-	defer mu.Unlock()
-
-	now := time.Now()
-	lazyCleanupIfNeeded(now)
-
-	entry, exists := ipRateLimiters[ip]
-	if !exists {
-		// 每秒1次请求，突发量1
-		limiter := rate.NewLimiter(rate.Every(time.Second), 1)
-		entry = &ipLimiterEntry{limiter: limiter, lastSeen: now}
-		ipRateLimiters[ip] = entry
-	} else {
-		// 更新最后访问时间
-		entry.lastSeen = now
-	}
-	return entry.limiter
-}
-
-// RateLimitMiddleware 是一个IP限流中间件
-func RateLimitMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		clientIP := c.ClientIP()
-		limiter := GetIPRateLimiter(clientIP)
-
-		if !limiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, handler.ErrorResponse{
-				Message: "请求过于频繁，请稍后再试。",
-				Code:    http.StatusTooManyRequests,
-			})
-			c.Abort()
-			return
-		}
-		c.Next()
-	}
-}
-
-// JWTAuthMiddleware 是一个JWT认证中间件
-func JWTAuthMiddleware(authService auth.Authenticator) gin.HandlerFunc {
+// JWTAuthMiddleware 是一个认证中间件，同时接受JWT与个人访问令牌
+func JWTAuthMiddleware(authService auth.Authenticator, tokenService token.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -108,6 +35,37 @@ func JWTAuthMiddleware(authService auth.Authenticator) gin.HandlerFunc {
 		}
 
 		tokenString := parts[1]
+
+		// 个人访问令牌：校验哈希并携带权限范围
+		if token.IsToken(tokenString) {
+			t, err := tokenService.Verify(tokenString)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, handler.ErrorResponse{
+					Message: fmt.Sprintf("无效的认证令牌: %s", err.Error()),
+					Code:    http.StatusUnauthorized,
+				})
+				c.Abort()
+				return
+			}
+			// 令牌继承所属用户当前的角色，用户被删除后令牌随之失效
+			role := authService.Role(t.Username)
+			if role == "" {
+				c.JSON(http.StatusUnauthorized, handler.ErrorResponse{
+					Message: "无效的认证令牌: 令牌所属用户不存在",
+					Code:    http.StatusUnauthorized,
+				})
+				c.Abort()
+				return
+			}
+			c.Set("username", t.Username)
+			c.Set("role", role)
+			c.Set("auth_type", handler.AuthTypeToken)
+			c.Set("token_id", t.ID)
+			c.Set("scopes", t.Scopes)
+			c.Next()
+			return
+		}
+
 		claims, err := authService.ParseToken(tokenString)
 		if err != nil {
 			statusCode := http.StatusUnauthorized
@@ -124,6 +82,47 @@ func JWTAuthMiddleware(authService auth.Authenticator) gin.HandlerFunc {
 
 		// 将用户信息存储在Context中，以便后续处理函数使用
 		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
+		c.Set("auth_type", handler.AuthTypeJWT)
 		c.Next()
 	}
 }
+
+// RequireScope 要求个人访问令牌具备指定权限范围；JWT登录会话拥有全部权限，直接放行
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_type") != handler.AuthTypeToken {
+			c.Next()
+			return
+		}
+		scopes, _ := c.Get("scopes")
+		granted, _ := scopes.([]string)
+		for _, s := range granted {
+			if s == scope {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, handler.ErrorResponse{
+			Message: fmt.Sprintf("个人访问令牌缺少权限范围: %s", scope),
+			Code:    http.StatusForbidden,
+		})
+		c.Abort()
+	}
+}
+
+// RequireRole 要求当前用户的角色不低于 min，需放在 JWTAuthMiddleware 之后
+func RequireRole(min string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if role != "" && auth.RoleAtLeast(role, min) {
+			c.Next()
+			return
+		}
+		c.JSON(http.StatusForbidden, handler.ErrorResponse{
+			Message: fmt.Sprintf("权限不足，需要 %s 角色", min),
+			Code:    http.StatusForbidden,
+		})
+		c.Abort()
+	}
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/handler"
+	"github.com/chenxuan520/roadmap/backend/internal/metrics"
+	"github.com/chenxuan520/roadmap/backend/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// 限流键的类型
+const (
+	RateLimitKeyIP    = "ip"
+	RateLimitKeyUser  = "user"
+	RateLimitKeyToken = "token"
+)
+
+// RateLimitOff 作为路由组的策略名时表示不限流
+const RateLimitOff = "off"
+
+// DefaultRateLimitPolicies 内置的限流策略，可在配置文件中按名称覆盖
+var DefaultRateLimitPolicies = map[string]config.RateLimitPolicy{
+	"login":  {Rate: 1, Burst: 1, Key: RateLimitKeyIP},     // 每秒1次，与原有登录限流一致
+	"search": {Rate: 5, Burst: 10, Key: RateLimitKeyIP},    // 地图搜索代理
+	"ai":     {Rate: 0.1, Burst: 3, Key: RateLimitKeyUser}, // AI对话按用户计费，每分钟6次
+	"share":  {Rate: 2, Burst: 10, Key: RateLimitKeyIP},    // 公开分享链接
+}
+
+// policyLimiter 是一个已创建的策略及其限流器
+type policyLimiter struct {
+	name    string
+	policy  config.RateLimitPolicy
+	limiter ratelimit.Limiter
+}
+
+// RateLimits 管理全部限流策略及路由组与策略的对应关系
+type RateLimits struct {
+	limiters map[string]*policyLimiter
+	routes   map[string]string
+}
+
+// NewRateLimits 根据配置在 store 中创建限流器。使用同一策略的路由组共享额度。
+func NewRateLimits(cfg config.RateLimitConfig, store ratelimit.Store) (*RateLimits, error) {
+	policies := make(map[string]config.RateLimitPolicy, len(DefaultRateLimitPolicies)+len(cfg.Policies))
+	for name, p := range DefaultRateLimitPolicies {
+		policies[name] = p
+	}
+	for name, p := range cfg.Policies {
+		if name == RateLimitOff {
+			return nil, fmt.Errorf("限流策略名 %q 为保留字", name)
+		}
+		policies[name] = p
+	}
+
+	rl := &RateLimits{
+		limiters: make(map[string]*policyLimiter, len(policies)),
+		routes:   cfg.Routes,
+	}
+	for name, p := range policies {
+		if p.Key == "" {
+			p.Key = RateLimitKeyIP
+		}
+		if p.Key != RateLimitKeyIP && p.Key != RateLimitKeyUser && p.Key != RateLimitKeyToken {
+			return nil, fmt.Errorf("限流策略 %s 的 key 无效: %s", name, p.Key)
+		}
+		if p.Rate <= 0 || p.Burst <= 0 {
+			return nil, fmt.Errorf("限流策略 %s 的 rate 与 burst 必须大于0", name)
+		}
+		rl.limiters[name] = &policyLimiter{
+			name:    name,
+			policy:  p,
+			limiter: store.Limiter(name, ratelimit.Policy{Rate: p.Rate, Burst: p.Burst}),
+		}
+	}
+	for route, name := range cfg.Routes {
+		if _, ok := rl.limiters[name]; !ok && name != RateLimitOff {
+			return nil, fmt.Errorf("路由组 %s 引用了不存在的限流策略: %s", route, name)
+		}
+	}
+	return rl, nil
+}
+
+// For 返回指定路由组的限流中间件
+func (rl *RateLimits) For(route string) gin.HandlerFunc {
+	name := route
+	if mapped, ok := rl.routes[route]; ok {
+		name = mapped
+	}
+	pl, ok := rl.limiters[name]
+	if !ok {
+		// 关闭限流，或路由组没有对应的策略
+		return func(c *gin.Context) { c.Next() }
+	}
+	return rateLimitHandler(pl)
+}
+
+// rateLimitKey 根据策略选择限流键。需要用户信息的策略应放在认证中间件之后。
+func rateLimitKey(c *gin.Context, keyType string) string {
+	switch keyType {
+	case RateLimitKeyToken:
+		if id := c.GetString("token_id"); id != "" {
+			return "token:" + id
+		}
+		fallthrough
+	case RateLimitKeyUser:
+		if username := c.GetString("username"); username != "" {
+			return "user:" + username
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// ceilSeconds 将时长向上取整为秒
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// rateLimitHandler 按策略限流，并输出 RateLimit-* 响应头（IETF draft-ietf-httpapi-ratelimit-headers）
+func rateLimitHandler(pl *policyLimiter) gin.HandlerFunc {
+	policy := pl.policy
+	window := ceilSeconds(ratelimit.Policy{Rate: policy.Rate, Burst: policy.Burst}.Window())
+	policyHeader := fmt.Sprintf("%d;w=%d", policy.Burst, window)
+
+	return func(c *gin.Context) {
+		// 限流后端出错时放行，后端自身负责降级与记录日志
+		res, err := pl.limiter.Take(c.Request.Context(), rateLimitKey(c, policy.Key))
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("RateLimit-Policy", policyHeader)
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+		if !res.Allowed {
+			retry := ceilSeconds(res.RetryAfter)
+			if retry < 1 {
+				retry = 1
+			}
+			h.Set("Retry-After", strconv.Itoa(retry))
+			metrics.RateLimitRejections.Inc(pl.name)
+			c.JSON(http.StatusTooManyRequests, handler.ErrorResponse{
+				Message: "请求过于频繁，请稍后再试。",
+				Code:    http.StatusTooManyRequests,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// newLimitedRouter 创建一个挂载指定路由组限流的测试路由，可通过 X-Test-User 模拟已登录用户
+func newLimitedRouter(t *testing.T, cfg config.RateLimitConfig, route string) *gin.Engine {
+	t.Helper()
+	rl, err := NewRateLimits(cfg, ratelimit.NewMemoryStore())
+	if err != nil {
+		t.Fatalf("创建限流器失败: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", func(c *gin.Context) {
+		if user := c.GetHeader("X-Test-User"); user != "" {
+			c.Set("username", user)
+		}
+		c.Next()
+	}, rl.For(route), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func doRequest(r *gin.Engine, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	if user != "" {
+		req.Header.Set("X-Test-User", user)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit_HeadersAndRetryAfter(t *testing.T) {
+	r := newLimitedRouter(t, config.RateLimitConfig{
+		Policies: map[string]config.RateLimitPolicy{"custom": {Rate: 0.5, Burst: 2}},
+	}, "custom")
+
+	w := doRequest(r, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("第一次请求应通过, 得到 %d", w.Code)
+	}
+	if got := w.Header().Get("RateLimit-Limit"); got != "2" {
+		t.Errorf("RateLimit-Limit = %q, 期望 2", got)
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "1" {
+		t.Errorf("RateLimit-Remaining = %q, 期望 1", got)
+	}
+	if got := w.Header().Get("RateLimit-Policy"); got != "2;w=4" {
+		t.Errorf("RateLimit-Policy = %q, 期望 2;w=4", got)
+	}
+
+	doRequest(r, "")
+	w = doRequest(r, "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("超出突发量后应返回 429, 得到 %d", w.Code)
+	}
+	if got := w.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("RateLimit-Remaining = %q, 期望 0", got)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, 期望 2", got)
+	}
+}
+
+func TestRateLimit_KeyByUser(t *testing.T) {
+	r := newLimitedRouter(t, config.RateLimitConfig{
+		Policies: map[string]config.RateLimitPolicy{"per-user": {Rate: 0.01, Burst: 1, Key: RateLimitKeyUser}},
+	}, "per-user")
+
+	if w := doRequest(r, "alice"); w.Code != http.StatusOK {
+		t.Fatalf("alice 第一次请求应通过, 得到 %d", w.Code)
+	}
+	if w := doRequest(r, "alice"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("alice 第二次请求应被限流, 得到 %d", w.Code)
+	}
+	// 同一IP下的其他用户不受影响
+	if w := doRequest(r, "bob"); w.Code != http.StatusOK {
+		t.Fatalf("bob 不应受 alice 的限流影响, 得到 %d", w.Code)
+	}
+	// 未登录请求回退为按IP限流
+	if w := doRequest(r, ""); w.Code != http.StatusOK {
+		t.Fatalf("匿名请求应使用独立的IP额度, 得到 %d", w.Code)
+	}
+}
+
+func TestRateLimit_RouteMapping(t *testing.T) {
+	cfg := config.RateLimitConfig{
+		Policies: map[string]config.RateLimitPolicy{"strict": {Rate: 0.01, Burst: 1}},
+		Routes:   map[string]string{"share": "strict", "search": RateLimitOff},
+	}
+
+	r := newLimitedRouter(t, cfg, "share")
+	doRequest(r, "")
+	if w := doRequest(r, ""); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("share 应使用 strict 策略, 得到 %d", w.Code)
+	}
+
+	r = newLimitedRouter(t, cfg, "search")
+	for i := 0; i < 50; i++ {
+		if w := doRequest(r, ""); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("search 已关闭限流, 第 %d 次请求得到 %d", i+1, w.Code)
+		}
+	}
+}
+
+func TestNewRateLimits_InvalidConfig(t *testing.T) {
+	cases := map[string]config.RateLimitConfig{
+		"无效的key":  {Policies: map[string]config.RateLimitPolicy{"p": {Rate: 1, Burst: 1, Key: "cookie"}}},
+		"rate为0":  {Policies: map[string]config.RateLimitPolicy{"p": {Rate: 0, Burst: 1}}},
+		"burst为0": {Policies: map[string]config.RateLimitPolicy{"p": {Rate: 1, Burst: 0}}},
+		"策略不存在":   {Routes: map[string]string{"ai": "missing"}},
+		"保留名":     {Policies: map[string]config.RateLimitPolicy{RateLimitOff: {Rate: 1, Burst: 1}}},
+	}
+	for name, cfg := range cases {
+		if _, err := NewRateLimits(cfg, ratelimit.NewMemoryStore()); err == nil {
+			t.Errorf("%s: 期望返回错误", name)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chenxuan520/roadmap/backend/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// serveWithRole 模拟认证中间件写入角色后调用 RequireRole
+func serveWithRole(role, min string) int {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", func(c *gin.Context) {
+		if role != "" {
+			c.Set("role", role)
+		}
+		c.Next()
+	}, RequireRole(min), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w.Code
+}
+
+func TestRequireRole(t *testing.T) {
+	cases := []struct {
+		role, min string
+		want      int
+	}{
+		{auth.RoleAdmin, auth.RoleAdmin, http.StatusOK},
+		{auth.RoleMember, auth.RoleAdmin, http.StatusForbidden},
+		{auth.RoleMember, auth.RoleMember, http.StatusOK},
+		{auth.RoleReadOnly, auth.RoleMember, http.StatusForbidden},
+		{auth.RoleReadOnly, auth.RoleReadOnly, http.StatusOK},
+		{"", auth.RoleReadOnly, http.StatusForbidden}, // 未经认证
+	}
+	for _, tc := range cases {
+		if got := serveWithRole(tc.role, tc.min); got != tc.want {
+			t.Errorf("角色 %q 访问需要 %q 的路由: 得到 %d, 期望 %d", tc.role, tc.min, got, tc.want)
+		}
+	}
+}
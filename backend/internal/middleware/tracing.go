@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/chenxuan520/roadmap/backend/internal/logging"
+	"github.com/chenxuan520/roadmap/backend/internal/tracing"
+	"github.com/gin-gonic/gin"
+)
+
+// Tracing 为每个请求创建服务端 span，并沿用请求头 traceparent 中的上游链路。
+// 请求级日志器会附带 trace_id，便于从日志跳转到对应的链路。应注册在 RequestLogger 之后、Recovery 之前。
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx := tracing.Extract(c.Request.Context(), c.Request.Header)
+		ctx, span := tracing.Start(ctx, c.Request.Method+" "+route, tracing.SpanKindServer,
+			tracing.String("http.request.method", c.Request.Method),
+			tracing.String("http.route", route),
+			tracing.String("url.path", c.Request.URL.Path),
+			tracing.String("client.address", c.ClientIP()),
+		)
+		if span == nil {
+			c.Next()
+			return
+		}
+		defer span.End()
+
+		sc := span.SpanContext()
+		logger := logging.FromContext(ctx).With("trace_id", sc.TraceID.String(), "span_id", sc.SpanID.String())
+		c.Request = c.Request.WithContext(logging.NewContext(ctx, logger))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(tracing.Int("http.response.status_code", status))
+		if username := c.GetString("username"); username != "" {
+			span.SetAttributes(tracing.String("enduser.id", username))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetError(http.StatusText(status))
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/logging"
+	"github.com/chenxuan520/roadmap/backend/internal/tracing"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTracing_ContinuesUpstreamTrace(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer collector.Close()
+	p, err := tracing.NewProvider(config.TracingConfig{Enabled: true, Endpoint: collector.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracing.SetProvider(p)
+	defer p.Shutdown(context.Background())
+
+	buf := captureLogs(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestLogger(), Tracing())
+	var downstream http.Header
+	r.GET("/search", func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Info("handler")
+		// 模拟调用上游：子 span 的 traceparent 应沿用同一个 trace id
+		ctx, span := tracing.Start(c.Request.Context(), "upstream", tracing.SpanKindClient)
+		downstream = http.Header{}
+		tracing.Inject(ctx, downstream)
+		span.End()
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/search", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := logLines(t, buf)
+	if len(lines) == 0 || lines[0]["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("处理函数的日志应带有上游的 trace_id: %v", lines)
+	}
+	got := downstream.Get(tracing.TraceparentHeader)
+	if len(got) != 55 || got[3:35] != "4bf92f3577b34da6a3ce929d0e0e4736" || got[36:52] == "00f067aa0ba902b7" {
+		t.Errorf("下游请求应携带同一 trace 下新的 span id, 得到 %q", got)
+	}
+}
@@ -0,0 +1,396 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// PendingTTL 是一次授权流程（跳转到IdP再回调）的最长等待时间
+	PendingTTL = 10 * time.Minute
+	// 待完成授权流程的数量上限，防止被恶意刷登录入口撑爆内存
+	maxPending = 10000
+	// 遇到未知 kid 时重新拉取 JWKS 的最小间隔
+	jwksRefreshInterval = time.Minute
+)
+
+var (
+	ErrUnknownState   = errors.New("登录状态无效或已过期，请重新登录")
+	ErrUserNotAllowed = errors.New("该身份提供方账号未绑定任何用户")
+)
+
+// providerMetadata 是 OpenID Provider 发现文档中用到的字段
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// IDTokenClaims 定义了 ID Token 中需要校验和使用的声明
+type IDTokenClaims struct {
+	Email             string `json:"email,omitempty"`
+	EmailVerified     *bool  `json:"email_verified,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Nonce             string `json:"nonce,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// pendingLogin 记录一次尚未完成的授权流程
+type pendingLogin struct {
+	verifier  string
+	nonce     string
+	expiresAt time.Time
+}
+
+// Client 实现了带 PKCE 的授权码流程
+type Client struct {
+	cfg        config.OIDCConfig
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	metadata      *providerMetadata
+	keys          map[string]interface{} // kid -> 公钥
+	keysFetchedAt time.Time
+	pending       map[string]*pendingLogin // state -> 授权流程
+	lastCleanup   time.Time
+}
+
+// NewClient 创建一个 OIDC 客户端。发现文档在首次使用时才会拉取。
+func NewClient(cfg config.OIDCConfig) *Client {
+	return &Client{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		keys:       make(map[string]interface{}),
+		pending:    make(map[string]*pendingLogin),
+	}
+}
+
+func randomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func (c *Client) scopes() string {
+	if len(c.cfg.Scopes) == 0 {
+		return "openid email profile"
+	}
+	scopes := c.cfg.Scopes
+	for _, s := range scopes {
+		if s == "openid" {
+			return strings.Join(scopes, " ")
+		}
+	}
+	return strings.Join(append([]string{"openid"}, scopes...), " ")
+}
+
+// AuthCodeURL 生成跳转到身份提供方的授权地址，并记录本次流程的 state、nonce 与 PKCE verifier。
+// 返回的 state 需要由调用方绑定到发起登录的浏览器（如 Cookie），回调时核对，防止登录CSRF。
+func (c *Client) AuthCodeURL(ctx context.Context) (authURL, state string, err error) {
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err = randomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	c.mu.Lock()
+	now := time.Now()
+	c.lazyCleanupIfNeeded(now)
+	if len(c.pending) >= maxPending {
+		c.mu.Unlock()
+		return "", "", errors.New("待完成的登录请求过多，请稍后再试")
+	}
+	c.pending[state] = &pendingLogin{verifier: verifier, nonce: nonce, expiresAt: now.Add(PendingTTL)}
+	c.mu.Unlock()
+
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", "", fmt.Errorf("无效的授权端点: %w", err)
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", c.cfg.ClientID)
+	q.Set("redirect_uri", c.cfg.RedirectURL)
+	q.Set("scope", c.scopes())
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), state, nil
+}
+
+// lazyCleanupIfNeeded 惰性删除过期的授权流程，调用方需持有锁
+func (c *Client) lazyCleanupIfNeeded(now time.Time) {
+	if now.Sub(c.lastCleanup) < time.Minute && len(c.pending) < maxPending {
+		return
+	}
+	for state, p := range c.pending {
+		if now.After(p.expiresAt) {
+			delete(c.pending, state)
+		}
+	}
+	c.lastCleanup = now
+}
+
+// takePending 取出并删除 state 对应的授权流程，保证每个 state 只能使用一次
+func (c *Client) takePending(state string) (*pendingLogin, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.pending[state]
+	if !ok {
+		return nil, ErrUnknownState
+	}
+	delete(c.pending, state)
+	if time.Now().After(p.expiresAt) {
+		return nil, ErrUnknownState
+	}
+	return p, nil
+}
+
+// Exchange 用授权码换取 ID Token 并完成校验
+func (c *Client) Exchange(ctx context.Context, code, state string) (*IDTokenClaims, error) {
+	if code == "" || state == "" {
+		return nil, errors.New("缺少 code 或 state 参数")
+	}
+	p, err := c.takePending(state)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURL)
+	form.Set("client_id", c.cfg.ClientID)
+	form.Set("code_verifier", p.verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("创建令牌请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.ClientSecret != "" {
+		// RFC 6749 2.3.1: client_secret_basic 需要先做 URL 编码
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求令牌端点失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("读取令牌响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("令牌端点返回错误状态 %d: %s", resp.StatusCode, truncate(string(body)))
+	}
+
+	var tokenResp struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return nil, fmt.Errorf("解析令牌响应失败: %w", err)
+	}
+	if tokenResp.IDToken == "" {
+		return nil, errors.New("令牌响应中缺少 id_token")
+	}
+
+	return c.verifyIDToken(ctx, meta, tokenResp.IDToken, p.nonce)
+}
+
+// verifyIDToken 校验 ID Token 的签名、签发者、受众、有效期与 nonce
+func (c *Client) verifyIDToken(ctx context.Context, meta *providerMetadata, raw, nonce string) (*IDTokenClaims, error) {
+	claims := &IDTokenClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}))
+	_, err := parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return c.publicKey(ctx, meta, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ID Token 校验失败: %w", err)
+	}
+
+	if claims.Issuer != meta.Issuer {
+		return nil, fmt.Errorf("ID Token 签发者不匹配: %s", claims.Issuer)
+	}
+	if !claims.VerifyAudience(c.cfg.ClientID, true) {
+		return nil, errors.New("ID Token 受众不匹配")
+	}
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("ID Token 缺少过期时间")
+	}
+	if claims.Nonce == "" || claims.Nonce != nonce {
+		return nil, errors.New("ID Token nonce 不匹配")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("ID Token 缺少 sub")
+	}
+	return claims, nil
+}
+
+// ResolveUser 将身份提供方的用户映射为 roadbook 用户名。
+// sub 与 preferred_username 可能由用户自行设置（如注册时取名 admin），必须在 user_mapping 中显式映射；
+// 只有 email_verified 为 true 的邮箱才允许直接匹配同名用户。
+func (c *Client) ResolveUser(claims *IDTokenClaims, users map[string]config.UserCredentials) (string, error) {
+	var value string
+	directMatch := false
+	switch c.cfg.UsernameClaim {
+	case "sub":
+		value = claims.Subject
+	case "preferred_username":
+		value = claims.PreferredUsername
+	case "", "email":
+		// 缺少 email_verified 声明时无法确认邮箱归属，按未验证处理
+		if claims.EmailVerified == nil || !*claims.EmailVerified {
+			return "", errors.New("身份提供方账号的邮箱未验证")
+		}
+		value = claims.Email
+		directMatch = true
+	default:
+		return "", fmt.Errorf("不支持的 username_claim: %s", c.cfg.UsernameClaim)
+	}
+	if value == "" {
+		return "", ErrUserNotAllowed
+	}
+
+	if mapped, ok := c.cfg.UserMapping[value]; ok {
+		if _, exists := users[mapped]; !exists {
+			return "", fmt.Errorf("映射的用户 %s 不存在", mapped)
+		}
+		return mapped, nil
+	}
+	if _, exists := users[value]; exists && directMatch {
+		return value, nil
+	}
+	return "", ErrUserNotAllowed
+}
+
+// discover 拉取并缓存发现文档
+func (c *Client) discover(ctx context.Context) (*providerMetadata, error) {
+	c.mu.Lock()
+	meta := c.metadata
+	c.mu.Unlock()
+	if meta != nil {
+		return meta, nil
+	}
+
+	issuer := strings.TrimSuffix(c.cfg.Issuer, "/")
+	var fetched providerMetadata
+	if err := c.getJSON(ctx, issuer+"/.well-known/openid-configuration", &fetched); err != nil {
+		return nil, fmt.Errorf("获取 OIDC 发现文档失败: %w", err)
+	}
+	if strings.TrimSuffix(fetched.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("发现文档中的 issuer 不匹配: %s", fetched.Issuer)
+	}
+	if fetched.AuthorizationEndpoint == "" || fetched.TokenEndpoint == "" || fetched.JWKSURI == "" {
+		return nil, errors.New("发现文档缺少必要的端点")
+	}
+
+	c.mu.Lock()
+	c.metadata = &fetched
+	c.mu.Unlock()
+	return &fetched, nil
+}
+
+// publicKey 根据 kid 查找验签公钥，遇到未知 kid 时（限频）重新拉取 JWKS 以支持密钥轮换
+func (c *Client) publicKey(ctx context.Context, meta *providerMetadata, kid string) (interface{}, error) {
+	c.mu.Lock()
+	key := c.lookupKey(kid)
+	canRefresh := time.Since(c.keysFetchedAt) >= jwksRefreshInterval || len(c.keys) == 0
+	c.mu.Unlock()
+	if key != nil {
+		return key, nil
+	}
+	if !canRefresh {
+		return nil, fmt.Errorf("未知的签名密钥: %s", kid)
+	}
+
+	var set jwkSet
+	if err := c.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("获取 JWKS 失败: %w", err)
+	}
+	keys := set.publicKeys()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys = keys
+	c.keysFetchedAt = time.Now()
+	if key := c.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("未知的签名密钥: %s", kid)
+}
+
+// lookupKey 调用方需持有锁。未声明 kid 且只有一把密钥时直接使用该密钥。
+func (c *Client) lookupKey(kid string) interface{} {
+	if kid == "" && len(c.keys) == 1 {
+		for _, k := range c.keys {
+			return k
+		}
+	}
+	return c.keys[kid]
+}
+
+func (c *Client) getJSON(ctx context.Context, rawURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+func truncate(s string) string {
+	if len(s) > 200 {
+		return s[:200] + "..."
+	}
+	return s
+}
@@ -0,0 +1,233 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// mockIdP 是一个最小化的本地身份提供方，实现发现文档、JWKS 与令牌端点
+type mockIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	mu sync.Mutex
+	// code -> 授权时记录的参数
+	codes map[string]authorization
+	// 用于篡改签发内容的钩子
+	mutate func(claims *IDTokenClaims)
+}
+
+type authorization struct {
+	challenge string
+	nonce     string
+	claims    IDTokenClaims
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("生成RSA密钥失败: %v", err)
+	}
+	idp := &mockIdP{key: key, kid: "test-key", codes: make(map[string]authorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(providerMetadata{
+			Issuer:                idp.server.URL,
+			AuthorizationEndpoint: idp.server.URL + "/authorize",
+			TokenEndpoint:         idp.server.URL + "/token",
+			JWKSURI:               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jwkSet{Keys: []jwk{{
+			Kty: "RSA",
+			Kid: idp.kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "bad form", http.StatusBadRequest)
+			return
+		}
+		idp.mu.Lock()
+		a, ok := idp.codes[r.PostForm.Get("code")]
+		delete(idp.codes, r.PostForm.Get("code"))
+		idp.mu.Unlock()
+		if !ok {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		// 校验 PKCE
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != a.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		claims := a.claims
+		claims.Nonce = a.nonce
+		claims.Issuer = idp.server.URL
+		claims.Audience = jwt.ClaimStrings{"roadbook"}
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+		claims.IssuedAt = jwt.NewNumericDate(time.Now())
+		if idp.mutate != nil {
+			idp.mutate(&claims)
+		}
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, &claims)
+		tok.Header["kid"] = idp.kid
+		signed, err := tok.SignedString(idp.key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "token_type": "Bearer"})
+	})
+	idp.server = httptest.NewServer(mux)
+	return idp
+}
+
+// authorize 模拟用户在 IdP 页面完成登录，返回回调中的 code 与 state
+func (idp *mockIdP) authorize(t *testing.T, authURL string, claims IDTokenClaims) (string, string) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("解析授权地址失败: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Fatalf("授权地址缺少 PKCE 参数: %s", authURL)
+	}
+	if !strings.Contains(q.Get("scope"), "openid") {
+		t.Fatalf("授权地址缺少 openid scope: %s", authURL)
+	}
+	code := "code-" + q.Get("state")[:8]
+	idp.mu.Lock()
+	idp.codes[code] = authorization{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), claims: claims}
+	idp.mu.Unlock()
+	return code, q.Get("state")
+}
+
+func newTestClient(idp *mockIdP, mapping map[string]string) *Client {
+	return NewClient(config.OIDCConfig{
+		Enabled:     true,
+		Issuer:      idp.server.URL,
+		ClientID:    "roadbook",
+		RedirectURL: "http://localhost/api/v1/oidc/callback",
+		UserMapping: mapping,
+	})
+}
+
+var testUsers = map[string]config.UserCredentials{"admin": {Salt: "s", Hash: "h"}}
+
+func TestClient_FullFlow(t *testing.T) {
+	idp := newMockIdP(t)
+	defer idp.server.Close()
+	client := newTestClient(idp, map[string]string{"alice@example.com": "admin"})
+	ctx := context.Background()
+
+	authURL, _, err := client.AuthCodeURL(ctx)
+	if err != nil {
+		t.Fatalf("生成授权地址失败: %v", err)
+	}
+	verified := true
+	code, state := idp.authorize(t, authURL, IDTokenClaims{
+		Email:            "alice@example.com",
+		EmailVerified:    &verified,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "alice-sub"},
+	})
+
+	claims, err := client.Exchange(ctx, code, state)
+	if err != nil {
+		t.Fatalf("授权码换取失败: %v", err)
+	}
+	username, err := client.ResolveUser(claims, testUsers)
+	if err != nil {
+		t.Fatalf("映射用户失败: %v", err)
+	}
+	if username != "admin" {
+		t.Errorf("期望映射为 admin, 得到 %s", username)
+	}
+
+	// state 只能使用一次
+	if _, err := client.Exchange(ctx, code, state); err != ErrUnknownState {
+		t.Errorf("期望重复使用 state 返回 %v, 得到 %v", ErrUnknownState, err)
+	}
+}
+
+func TestClient_RejectsTamperedToken(t *testing.T) {
+	idp := newMockIdP(t)
+	defer idp.server.Close()
+	client := newTestClient(idp, nil)
+	ctx := context.Background()
+
+	cases := map[string]func(*IDTokenClaims){
+		"wrong nonce":    func(c *IDTokenClaims) { c.Nonce = "other" },
+		"wrong audience": func(c *IDTokenClaims) { c.Audience = jwt.ClaimStrings{"someone-else"} },
+		"wrong issuer":   func(c *IDTokenClaims) { c.Issuer = "https://evil.example.com" },
+		"expired":        func(c *IDTokenClaims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) },
+	}
+	for name, mutate := range cases {
+		idp.mutate = mutate
+		authURL, _, err := client.AuthCodeURL(ctx)
+		if err != nil {
+			t.Fatalf("%s: 生成授权地址失败: %v", name, err)
+		}
+		code, state := idp.authorize(t, authURL, IDTokenClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: "x"}})
+		if _, err := client.Exchange(ctx, code, state); err == nil {
+			t.Errorf("%s: 期望校验失败，但成功了", name)
+		}
+	}
+}
+
+func TestClient_ResolveUser(t *testing.T) {
+	client := NewClient(config.OIDCConfig{UserMapping: map[string]string{"ghost@example.com": "nobody"}})
+	verified, unverified := true, false
+
+	if _, err := client.ResolveUser(&IDTokenClaims{Email: "admin", EmailVerified: &unverified}, testUsers); err == nil {
+		t.Error("期望未验证邮箱被拒绝")
+	}
+	if _, err := client.ResolveUser(&IDTokenClaims{Email: "admin"}, testUsers); err == nil {
+		t.Error("期望缺少 email_verified 的邮箱按未验证处理")
+	}
+	if u, err := client.ResolveUser(&IDTokenClaims{Email: "admin", EmailVerified: &verified}, testUsers); err != nil || u != "admin" {
+		t.Errorf("期望已验证且与用户名相同的邮箱直接匹配, 得到 %s, %v", u, err)
+	}
+	if _, err := client.ResolveUser(&IDTokenClaims{Email: "stranger@example.com", EmailVerified: &verified}, testUsers); err != ErrUserNotAllowed {
+		t.Errorf("期望未映射用户返回 %v, 得到 %v", ErrUserNotAllowed, err)
+	}
+	if _, err := client.ResolveUser(&IDTokenClaims{Email: "ghost@example.com", EmailVerified: &verified}, testUsers); err == nil {
+		t.Error("期望映射到不存在用户时返回错误")
+	}
+
+	subClient := NewClient(config.OIDCConfig{UsernameClaim: "sub", UserMapping: map[string]string{"123": "admin"}})
+	if u, err := subClient.ResolveUser(&IDTokenClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: "123"}}, testUsers); err != nil || u != "admin" {
+		t.Errorf("期望按 sub 映射为 admin, 得到 %s, %v", u, err)
+	}
+	if _, err := subClient.ResolveUser(&IDTokenClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: "admin"}}, testUsers); err != ErrUserNotAllowed {
+		t.Errorf("期望未映射的 sub 即使与用户名相同也被拒绝, 得到 %v", err)
+	}
+
+	// 身份提供方的用户名可以自行设置，不能直接匹配 roadbook 用户
+	nameClient := NewClient(config.OIDCConfig{UsernameClaim: "preferred_username"})
+	if _, err := nameClient.ResolveUser(&IDTokenClaims{PreferredUsername: "admin"}, testUsers); err != ErrUserNotAllowed {
+		t.Errorf("期望未映射的 preferred_username 被拒绝, 得到 %v", err)
+	}
+}
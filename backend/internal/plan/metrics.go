@@ -0,0 +1,50 @@
+package plan
+
+import (
+	"context"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/metrics"
+)
+
+// metricsRepository 为 Repository 记录每次操作的耗时与结果
+type metricsRepository struct {
+	next Repository
+}
+
+// WithMetrics 返回记录操作耗时的 Repository 包装
+func WithMetrics(next Repository) Repository {
+	return &metricsRepository{next: next}
+}
+
+func observe(op string, start time.Time, err error) {
+	metrics.PlanRepositoryDuration.Observe(time.Since(start).Seconds(), op, metrics.Result(err))
+}
+
+func (r *metricsRepository) Save(ctx context.Context, plan *Plan) error {
+	start := time.Now()
+	err := r.next.Save(ctx, plan)
+	observe("save", start, err)
+	return err
+}
+
+func (r *metricsRepository) FindByID(ctx context.Context, id string) (*Plan, error) {
+	start := time.Now()
+	p, err := r.next.FindByID(ctx, id)
+	observe("find_by_id", start, err)
+	return p, err
+}
+
+func (r *metricsRepository) FindAll(ctx context.Context) ([]PlanSummary, error) {
+	start := time.Now()
+	summaries, err := r.next.FindAll(ctx)
+	observe("find_all", start, err)
+	return summaries, err
+}
+
+func (r *metricsRepository) Delete(ctx context.Context, id string) error {
+	start := time.Now()
+	err := r.next.Delete(ctx, id)
+	observe("delete", start, err)
+	return err
+}
@@ -1,14 +1,15 @@
 package plan
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/chenxuan520/roadmap/backend/internal/logging"
 	"github.com/google/uuid" // 使用 uuid 生成唯一ID
 )
 
@@ -17,12 +18,12 @@ var (
 	fileExt = ".json"
 )
 
-// Repository 定义了计划存储的接口
+// Repository 定义了计划存储的接口。ctx 携带请求级日志器，用于关联同一请求的日志。
 type Repository interface {
-	Save(plan *Plan) error
-	FindByID(id string) (*Plan, error)
-	FindAll() ([]PlanSummary, error)
-	Delete(id string) error
+	Save(ctx context.Context, plan *Plan) error
+	FindByID(ctx context.Context, id string) (*Plan, error)
+	FindAll(ctx context.Context) ([]PlanSummary, error)
+	Delete(ctx context.Context, id string) error
 }
 
 // fileRepository 是 Repository 接口的文件系统实现
@@ -42,7 +43,7 @@ func NewFileRepository() (Repository, error) {
 }
 
 // Save 保存一个计划。如果计划ID为空，则生成新的ID并设置创建时间；否则更新计划。
-func (r *fileRepository) Save(plan *Plan) error {
+func (r *fileRepository) Save(ctx context.Context, plan *Plan) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -71,7 +72,7 @@ func (r *fileRepository) Save(plan *Plan) error {
 }
 
 // FindByID 根据ID查找并返回一个计划
-func (r *fileRepository) FindByID(id string) (*Plan, error) {
+func (r *fileRepository) FindByID(ctx context.Context, id string) (*Plan, error) {
 	// 防御路径遍历攻击
 	if filepath.Base(id) != id {
 		return nil, fmt.Errorf("无效的计划ID: %s", id)
@@ -97,7 +98,7 @@ func (r *fileRepository) FindByID(id string) (*Plan, error) {
 }
 
 // FindAll 查找并返回所有计划的摘要信息
-func (r *fileRepository) FindAll() ([]PlanSummary, error) {
+func (r *fileRepository) FindAll(ctx context.Context) ([]PlanSummary, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -118,14 +119,14 @@ func (r *fileRepository) FindAll() ([]PlanSummary, error) {
 		data, err := os.ReadFile(filePath)
 		if err != nil {
 			// 如果单个文件读取失败，记录错误并跳过，不影响其他计划
-			log.Printf("警告: 读取计划文件 %s 失败: %v\n", entry.Name(), err)
+			logging.FromContext(ctx).Warn("读取计划文件失败", "file", entry.Name(), "error", err)
 			continue
 		}
 
 		var plan Plan
 		err = json.Unmarshal(data, &plan)
 		if err != nil {
-			log.Printf("警告: 反序列化计划文件 %s 失败: %v\n", entry.Name(), err)
+			logging.FromContext(ctx).Warn("反序列化计划文件失败", "file", entry.Name(), "error", err)
 			continue
 		}
 
@@ -143,7 +144,7 @@ func (r *fileRepository) FindAll() ([]PlanSummary, error) {
 }
 
 // Delete 根据ID删除一个计划
-func (r *fileRepository) Delete(id string) error {
+func (r *fileRepository) Delete(ctx context.Context, id string) error {
 	// 防御路径遍历攻击
 	if filepath.Base(id) != id {
 		return fmt.Errorf("无效的计划ID: %s", id)
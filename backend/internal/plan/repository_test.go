@@ -1,6 +1,7 @@
 package plan
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -35,6 +36,7 @@ func setupTestEnv(t *testing.T) (Repository, func()) {
 }
 
 func TestFileRepository_SaveAndFind(t *testing.T) {
+	ctx := context.Background()
 	repo, cleanup := setupTestEnv(t)
 	defer cleanup()
 
@@ -54,7 +56,7 @@ func TestFileRepository_SaveAndFind(t *testing.T) {
 		Content:     planContent,
 	}
 
-	err = repo.Save(newPlan)
+	err = repo.Save(ctx, newPlan)
 	if err != nil {
 		t.Fatalf("保存新计划失败: %v", err)
 	}
@@ -69,7 +71,7 @@ func TestFileRepository_SaveAndFind(t *testing.T) {
 	}
 
 	// 根据ID查找计划
-	foundPlan, err := repo.FindByID(newPlan.ID)
+	foundPlan, err := repo.FindByID(ctx, newPlan.ID)
 	if err != nil {
 		t.Fatalf("根据ID查找计划失败: %v", err)
 	}
@@ -102,7 +104,7 @@ func TestFileRepository_SaveAndFind(t *testing.T) {
 	oldUpdatedAt := foundPlan.UpdatedAt
 	time.Sleep(1 * time.Millisecond) // 确保更新时间不同
 
-	err = repo.Save(foundPlan)
+	err = repo.Save(ctx, foundPlan)
 	if err != nil {
 		t.Fatalf("更新计划失败: %v", err)
 	}
@@ -111,7 +113,7 @@ func TestFileRepository_SaveAndFind(t *testing.T) {
 	}
 
 	// 重新查找并验证更新
-	reFoundPlan, err := repo.FindByID(foundPlan.ID)
+	reFoundPlan, err := repo.FindByID(ctx, foundPlan.ID)
 	if err != nil {
 		t.Fatalf("重新查找更新后的计划失败: %v", err)
 	}
@@ -127,6 +129,7 @@ func TestFileRepository_SaveAndFind(t *testing.T) {
 }
 
 func TestFileRepository_FindAll(t *testing.T) {
+	ctx := context.Background()
 	repo, cleanup := setupTestEnv(t)
 	defer cleanup()
 
@@ -139,17 +142,17 @@ func TestFileRepository_FindAll(t *testing.T) {
 	plan1 := &Plan{Name: "计划A", Description: "描述A", StartTime: "20250101", EndTime: "20250102", Labels: []string{"tag1"}, Content: planContent1}
 	plan2 := &Plan{Name: "计划B", Description: "描述B", StartTime: "20250201", EndTime: "20250203", Labels: []string{"tag2"}, Content: planContent1}
 
-	err = repo.Save(plan1)
+	err = repo.Save(ctx, plan1)
 	if err != nil {
 		t.Fatalf("保存计划1失败: %v", err)
 	}
 	time.Sleep(1 * time.Millisecond) // 确保创建时间不同
-	err = repo.Save(plan2)
+	err = repo.Save(ctx, plan2)
 	if err != nil {
 		t.Fatalf("保存计划2失败: %v", err)
 	}
 
-	summaries, err := repo.FindAll()
+	summaries, err := repo.FindAll(ctx)
 	if err != nil {
 		t.Fatalf("查找所有计划失败: %v", err)
 	}
@@ -175,6 +178,7 @@ func TestFileRepository_FindAll(t *testing.T) {
 }
 
 func TestFileRepository_Delete(t *testing.T) {
+	ctx := context.Background()
 	repo, cleanup := setupTestEnv(t)
 	defer cleanup()
 
@@ -185,18 +189,18 @@ func TestFileRepository_Delete(t *testing.T) {
 
 	planContent := json.RawMessage(`{}`)
 	newPlan := &Plan{Name: "待删除计划", Description: "描述", StartTime: "20250101", EndTime: "20250102", Labels: []string{}, Content: planContent}
-	err = repo.Save(newPlan)
+	err = repo.Save(ctx, newPlan)
 	if err != nil {
 		t.Fatalf("保存计划失败: %v", err)
 	}
 
-	err = repo.Delete(newPlan.ID)
+	err = repo.Delete(ctx, newPlan.ID)
 	if err != nil {
 		t.Fatalf("删除计划失败: %v", err)
 	}
 
 	// 验证计划是否已被删除
-	_, err = repo.FindByID(newPlan.ID)
+	_, err = repo.FindByID(ctx, newPlan.ID)
 	if err == nil {
 		t.Error("期望计划已被删除，但仍然找到")
 	}
@@ -206,7 +210,7 @@ func TestFileRepository_Delete(t *testing.T) {
 	}
 
 	// 尝试删除一个不存在的计划
-	err = repo.Delete("non-existent-id")
+	err = repo.Delete(ctx, "non-existent-id")
 	if err == nil {
 		t.Error("期望删除不存在计划时返回错误，但未返回")
 	}
@@ -217,6 +221,7 @@ func TestFileRepository_Delete(t *testing.T) {
 }
 
 func TestFileRepository_FindByID_NotFound(t *testing.T) {
+	ctx := context.Background()
 	repo, cleanup := setupTestEnv(t)
 	defer cleanup()
 
@@ -225,7 +230,7 @@ func TestFileRepository_FindByID_NotFound(t *testing.T) {
 		t.Fatalf("创建仓库失败: %v", err)
 	}
 
-	_, err = repo.FindByID("non-existent-id")
+	_, err = repo.FindByID(ctx, "non-existent-id")
 	if err == nil {
 		t.Error("期望查找不存在计划时返回错误，但未返回")
 	}
@@ -248,6 +253,7 @@ func TestFileRepository_NewFileRepository_DirExists(t *testing.T) {
 }
 
 func TestFileRepository_FindAll_CorruptedFile(t *testing.T) {
+	ctx := context.Background()
 	_, cleanup := setupTestEnv(t)
 	defer cleanup()
 
@@ -259,7 +265,7 @@ func TestFileRepository_FindAll_CorruptedFile(t *testing.T) {
 	// 写入一个有效计划
 	validPlan := &Plan{ID: "valid-plan", Name: "Valid Plan", Description: "Valid", CreatedAt: time.Now().UTC(), Content: json.RawMessage(`{}`)}
 	filepath.Join(dataDir, validPlan.ID+fileExt) // Ensure dataDir is correctly set for test
-	err = repo.Save(validPlan)
+	err = repo.Save(ctx, validPlan)
 	if err != nil {
 		t.Fatalf("保存有效计划失败: %v", err)
 	}
@@ -271,7 +277,7 @@ func TestFileRepository_FindAll_CorruptedFile(t *testing.T) {
 		t.Fatalf("写入损坏文件失败: %v", err)
 	}
 
-	summaries, err := repo.FindAll()
+	summaries, err := repo.FindAll(ctx)
 	if err != nil {
 		t.Fatalf("期望FindAll能跳过损坏文件，但返回错误: %v", err)
 	}
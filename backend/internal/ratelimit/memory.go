@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// 限流器的存活时长下限（无访问则淘汰）
+const ipLimiterTTL = 1 * time.Minute
+
+// 惰性删除触发的最小间隔，避免每次请求都全量扫描
+const cleanupInterval = 1 * time.Minute
+
+// 当map尺寸超过阈值时强制执行一次惰性清理（防御极端情况）
+const maxLimiters = 10000
+
+// 定义每个限流键的限流器（惰性删除，避免内存增长）
+type ipLimiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// memoryLimiter 在进程内为每个限流键维护一个令牌桶
+type memoryLimiter struct {
+	policy Policy
+	// ttl 不小于令牌桶从空到满所需的时间，保证淘汰一个条目不会让调用方提前获得额度
+	ttl time.Duration
+	now func() time.Time
+
+	mu          sync.Mutex
+	limiters    map[string]*ipLimiterEntry
+	lastCleanup time.Time
+}
+
+// NewMemory 创建进程内的令牌桶限流器。多副本部署时每个副本各自计数。
+func NewMemory(policy Policy) Limiter {
+	return newMemory(policy)
+}
+
+func newMemory(policy Policy) *memoryLimiter {
+	ttl := ipLimiterTTL
+	if refill := policy.Window(); refill > ttl {
+		ttl = refill
+	}
+	return &memoryLimiter{
+		policy:   policy,
+		ttl:      ttl,
+		now:      time.Now,
+		limiters: make(map[string]*ipLimiterEntry),
+	}
+}
+
+// lazyCleanupIfNeeded 惰性清理：仅在满足条件时扫描并删除过期项，调用方需持有锁
+func (m *memoryLimiter) lazyCleanupIfNeeded(now time.Time) {
+	if m.lastCleanup.IsZero() || now.Sub(m.lastCleanup) >= cleanupInterval || len(m.limiters) > maxLimiters {
+		cutoff := now.Add(-m.ttl)
+		for key, entry := range m.limiters {
+			if entry.lastSeen.Before(cutoff) {
+				delete(m.limiters, key)
+			}
+		}
+		m.lastCleanup = now
+	}
+}
+
+// get 获取指定键的限流器，如果不存在则创建一个（含惰性删除）
+func (m *memoryLimiter) get(key string, now time.Time) *rate.Limiter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lazyCleanupIfNeeded(now)
+
+	entry, exists := m.limiters[key]
+	if !exists {
+		entry = &ipLimiterEntry{limiter: rate.NewLimiter(rate.Limit(m.policy.Rate), m.policy.Burst), lastSeen: now}
+		m.limiters[key] = entry
+	} else {
+		// 更新最后访问时间
+		entry.lastSeen = now
+	}
+	return entry.limiter
+}
+
+// Take 尝试为指定键消耗一个令牌
+func (m *memoryLimiter) Take(_ context.Context, key string) (Result, error) {
+	now := m.now()
+	limiter := m.get(key, now)
+	allowed := limiter.AllowN(now, 1)
+	tokens := limiter.TokensAt(now)
+
+	res := Result{Allowed: allowed, Limit: m.policy.Burst}
+	if tokens > 0 {
+		res.Remaining = int(math.Floor(tokens))
+	}
+	res.Reset = m.durationFor(float64(m.policy.Burst) - tokens)
+	if !allowed {
+		res.RetryAfter = m.durationFor(1 - tokens)
+	}
+	return res, nil
+}
+
+// durationFor 计算补充指定数量令牌所需的时间
+func (m *memoryLimiter) durationFor(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / m.policy.Rate * float64(time.Second))
+}
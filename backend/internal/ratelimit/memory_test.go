@@ -0,0 +1,169 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newTestStore 创建一个独立的限流器存储，避免测试之间相互影响
+func newTestStore() *memoryLimiter {
+	return newMemory(Policy{Rate: 1, Burst: 1})
+}
+
+func TestLazyCleanupTTLRemovesExpiredEntries(t *testing.T) {
+	s := newTestStore()
+
+	// 创建两个IP：一个过期，一个活跃
+	now := time.Now()
+	s.get("expired", time.Now())
+	s.get("active", time.Now())
+
+	s.mu.Lock()
+	// 让 expired 变为过期（lastSeen 超过 TTL）
+	if e := s.limiters["expired"]; e != nil {
+		e.lastSeen = now.Add(-(ipLimiterTTL + time.Second))
+	} else {
+		s.mu.Unlock()
+		t.Fatalf("expected 'expired' entry to exist")
+	}
+	// active 设为当前
+	if a := s.limiters["active"]; a != nil {
+		a.lastSeen = now
+	} else {
+		s.mu.Unlock()
+		t.Fatalf("expected 'active' entry to exist")
+	}
+	// 上次清理设为很久以前，确保触发清理
+	s.lastCleanup = now.Add(-time.Hour)
+	s.mu.Unlock()
+
+	// 触发惰性清理：任意获取一个限流器即可
+	_ = s.get("trigger", time.Now())
+
+	// 验证：expired 被删除，active 与 trigger 保留
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.limiters["expired"]; ok {
+		t.Fatalf("expected 'expired' to be cleaned up, but still present")
+	}
+	if _, ok := s.limiters["active"]; !ok {
+		t.Fatalf("expected 'active' to remain, but missing")
+	}
+	if _, ok := s.limiters["trigger"]; !ok {
+		t.Fatalf("expected 'trigger' to be created, but missing")
+	}
+}
+
+func TestCleanupIntervalPreventsFrequentScans(t *testing.T) {
+	s := newTestStore()
+	now := time.Now()
+	s.get("expired", time.Now())
+
+	s.mu.Lock()
+	if e := s.limiters["expired"]; e != nil {
+		e.lastSeen = now.Add(-(ipLimiterTTL + time.Second))
+	} else {
+		s.mu.Unlock()
+		t.Fatalf("expected 'expired' entry to exist")
+	}
+	// 设置上次清理为刚刚，清理间隔未到
+	s.lastCleanup = now
+	s.mu.Unlock()
+
+	// 触发一次获取，不应清理（因为 cleanupInterval 未到）
+	_ = s.get("trigger1", time.Now())
+
+	s.mu.Lock()
+	if _, ok := s.limiters["expired"]; !ok {
+		s.mu.Unlock()
+		t.Fatalf("expected 'expired' NOT to be cleaned due to cleanupInterval, but it was removed")
+	}
+	// 强制让上次清理时间过期，以便下一次触发清理
+	s.lastCleanup = now.Add(-(cleanupInterval + time.Second))
+	s.mu.Unlock()
+
+	// 再次触发，此时应进行清理
+	_ = s.get("trigger2", time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.limiters["expired"]; ok {
+		t.Fatalf("expected 'expired' to be cleaned after interval passed, but still present")
+	}
+}
+
+func TestMaxLimitersThresholdTriggersCleanup(t *testing.T) {
+	s := newTestStore()
+	now := time.Now()
+
+	// 阻止基于时间的清理（让 lastCleanup 处于新鲜状态）
+	s.mu.Lock()
+	s.lastCleanup = now
+	s.mu.Unlock()
+
+	// 构造超出阈值的过期条目
+	for i := 0; i < maxLimiters+100; i++ {
+		ip := "expired-" + strconv.Itoa(i)
+		_ = s.get(ip, time.Now())
+		s.mu.Lock()
+		if e := s.limiters[ip]; e != nil {
+			e.lastSeen = now.Add(-(ipLimiterTTL + time.Second))
+		}
+		s.mu.Unlock()
+	}
+
+	// 触发获取，应因为 len > maxLimiters 而执行清理
+	_ = s.get("trigger", time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.limiters) > maxLimiters+1 { // +1 因为包含 trigger
+		t.Fatalf("expected cleanup to reduce map size, size=%d exceeds threshold %d", len(s.limiters), maxLimiters+1)
+	}
+}
+
+func TestMemoryTake(t *testing.T) {
+	m := newMemory(Policy{Rate: 0.5, Burst: 2})
+	clock := time.Now()
+	m.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	res, _ := m.Take(ctx, "a")
+	if !res.Allowed || res.Remaining != 1 || res.Limit != 2 || res.Reset != 2*time.Second {
+		t.Fatalf("第一次请求结果不符合预期: %+v", res)
+	}
+	m.Take(ctx, "a")
+	res, _ = m.Take(ctx, "a")
+	if res.Allowed || res.Remaining != 0 || res.RetryAfter != 2*time.Second {
+		t.Fatalf("超出突发量后应被拒绝: %+v", res)
+	}
+	if res, _ := m.Take(ctx, "b"); !res.Allowed {
+		t.Fatal("其他键不应受影响")
+	}
+
+	clock = clock.Add(2 * time.Second)
+	if res, _ := m.Take(ctx, "a"); !res.Allowed {
+		t.Fatal("补充令牌后应放行")
+	}
+}
+
+func TestMemoryStore_SharesLimitersByName(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	p := Policy{Rate: 0.01, Burst: 2}
+
+	a, b := store.Limiter("x", p), store.Limiter("x", p)
+	a.Take(ctx, "k")
+	a.Take(ctx, "k")
+	if res, _ := b.Take(ctx, "k"); res.Allowed {
+		t.Fatalf("同名限流器应共享计数: %+v", res)
+	}
+	if res, _ := store.Limiter("y", p).Take(ctx, "k"); !res.Allowed {
+		t.Error("不同名称的限流器应分别计数")
+	}
+	if res, _ := store.Limiter("x", Policy{Rate: 0.01, Burst: 5}).Take(ctx, "k"); !res.Allowed || res.Limit != 5 {
+		t.Errorf("策略改变后应按新策略重新计数: %+v", res)
+	}
+}
@@ -0,0 +1,66 @@
+// Package ratelimit 提供HTTP限流中间件使用的限流后端。
+// 内存实现为进程内令牌桶；Redis 实现把滑动窗口计数保存在共享的 Redis
+// （或兼容 Redis 协议的服务）中，多副本部署时限额依然有效。
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Policy 描述一个令牌桶：每秒补充 Rate 个令牌，最多容纳 Burst 个。
+// 基于窗口的后端在每个 Window() 内最多放行 Burst 次请求。
+type Policy struct {
+	Rate  float64
+	Burst int
+}
+
+// Window 返回令牌桶从空到满所需的时间
+func (p Policy) Window() time.Duration {
+	return time.Duration(float64(p.Burst) / p.Rate * float64(time.Second))
+}
+
+// Result 描述一次限流判断的结果，用于生成响应头
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset 额度完全恢复所需时间
+	Reset time.Duration
+	// RetryAfter 被拒绝时，建议的等待时间
+	RetryAfter time.Duration
+}
+
+// Limiter 判断指定键的请求是否放行。被拒绝的请求不消耗额度。
+type Limiter interface {
+	Take(ctx context.Context, key string) (Result, error)
+}
+
+// Store 为每个命名策略创建限流器。同一 Store 创建的同名限流器共享计数；
+// 内存实现在同名策略的 Rate 或 Burst 改变时重新开始计数。
+type Store interface {
+	Limiter(name string, policy Policy) Limiter
+}
+
+// memoryStore 是 Store 的进程内实现，按名称缓存限流器
+type memoryStore struct {
+	mu       sync.Mutex
+	limiters map[string]*memoryLimiter
+}
+
+// NewMemoryStore 返回进程内的 Store，单副本部署时使用
+func NewMemoryStore() Store {
+	return &memoryStore{limiters: make(map[string]*memoryLimiter)}
+}
+
+func (s *memoryStore) Limiter(name string, policy Policy) Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m, ok := s.limiters[name]; ok && m.policy == policy {
+		return m
+	}
+	m := newMemory(policy)
+	s.limiters[name] = m
+	return m
+}
@@ -0,0 +1,172 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultKeyPrefix Redis 中限流计数键的默认前缀
+const DefaultKeyPrefix = "roadbook:ratelimit:"
+
+// errorLogInterval 限制 Redis 不可用时的日志频率
+const errorLogInterval = time.Minute
+
+// RedisStore 把限流计数保存在 Redis 中，多个后端副本共享同一份额度
+type RedisStore struct {
+	client *redisClient
+	prefix string
+
+	mu         sync.Mutex
+	lastErrLog time.Time
+}
+
+// NewRedisStore 创建基于 Redis 的 Store。连接按需建立，Redis 不可用时
+// 各限流器退回到进程内令牌桶，保证限流不会因为 Redis 故障而失效。
+func NewRedisStore(opts RedisOptions, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = DefaultKeyPrefix
+	}
+	return &RedisStore{client: newRedisClient(opts), prefix: prefix}
+}
+
+// Limiter 返回指定策略的限流器
+func (s *RedisStore) Limiter(name string, policy Policy) Limiter {
+	return &redisLimiter{
+		store:    s,
+		prefix:   s.prefix + name + ":",
+		policy:   policy,
+		window:   policy.Window(),
+		fallback: newMemory(policy),
+		now:      time.Now,
+	}
+}
+
+// Ping 检查 Redis 是否可用
+func (s *RedisStore) Ping(ctx context.Context) error {
+	replies, err := s.client.pipeline(ctx, [][]string{{"PING"}})
+	if err != nil {
+		return err
+	}
+	if e, ok := replies[0].(respError); ok {
+		return e
+	}
+	return nil
+}
+
+// Close 关闭空闲连接
+func (s *RedisStore) Close() {
+	s.client.close()
+}
+
+// logError 记录 Redis 错误，每个时间间隔最多输出一次
+func (s *RedisStore) logError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.lastErrLog) < errorLogInterval {
+		return
+	}
+	s.lastErrLog = time.Now()
+	slog.Warn("Redis 限流不可用，暂时使用进程内限流", "error", err)
+}
+
+// redisLimiter 使用滑动窗口计数：当前窗口的计数加上上一窗口计数按剩余比例加权，
+// 每个窗口最多放行 Burst 次请求。窗口长度为令牌桶从空到满的时间。
+type redisLimiter struct {
+	store    *RedisStore
+	prefix   string
+	policy   Policy
+	window   time.Duration
+	fallback *memoryLimiter
+	now      func() time.Time
+}
+
+// Take 判断请求是否放行，Redis 出错时使用进程内限流器
+func (l *redisLimiter) Take(ctx context.Context, key string) (Result, error) {
+	res, err := l.take(ctx, key)
+	if err != nil {
+		l.store.logError(err)
+		return l.fallback.Take(ctx, key)
+	}
+	return res, nil
+}
+
+func (l *redisLimiter) take(ctx context.Context, key string) (Result, error) {
+	now := l.now()
+	windowMs := l.window.Milliseconds()
+	if windowMs <= 0 {
+		windowMs = 1
+	}
+	nowMs := now.UnixNano() / int64(time.Millisecond)
+	index := nowMs / windowMs
+	elapsed := float64(nowMs-index*windowMs) / float64(windowMs) // 当前窗口已过去的比例
+
+	current := l.prefix + key + ":" + strconv.FormatInt(index, 10)
+	previous := l.prefix + key + ":" + strconv.FormatInt(index-1, 10)
+
+	replies, err := l.store.client.pipeline(ctx, [][]string{
+		{"INCR", current},
+		{"PEXPIRE", current, strconv.FormatInt(2*windowMs, 10)},
+		{"GET", previous},
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	curr, err := replyInt(replies[0])
+	if err != nil {
+		return Result{}, err
+	}
+	prev, err := replyInt(replies[2])
+	if err != nil {
+		return Result{}, err
+	}
+
+	limit := float64(l.policy.Burst)
+	weighted := float64(prev) * (1 - elapsed)
+	res := Result{Limit: l.policy.Burst, Allowed: weighted+float64(curr) <= limit}
+	if !res.Allowed {
+		// 被拒绝的请求不计入额度
+		curr--
+		if _, err := l.store.client.pipeline(ctx, [][]string{{"DECR", current}}); err != nil {
+			l.store.logError(err)
+		}
+		res.RetryAfter = l.retryAfter(float64(prev), float64(curr), elapsed)
+	}
+
+	if remaining := math.Floor(limit - weighted - float64(curr)); remaining > 0 {
+		res.Remaining = int(remaining)
+	}
+	switch {
+	case curr > 0:
+		res.Reset = l.fraction(2 - elapsed) // 当前窗口的计数在下一个窗口结束时完全失效
+	case prev > 0:
+		res.Reset = l.fraction(1 - elapsed)
+	}
+	return res, nil
+}
+
+// retryAfter 估算加权计数降到可再放行一次请求所需的时间
+func (l *redisLimiter) retryAfter(prev, curr, elapsed float64) time.Duration {
+	allowance := float64(l.policy.Burst) - 1
+	if curr <= allowance {
+		if prev <= 0 {
+			return 0
+		}
+		// prev * (1 - (elapsed + t)) + curr <= allowance
+		t := 1 - (allowance-curr)/prev - elapsed
+		if t < 0 {
+			t = 0
+		}
+		return l.fraction(t)
+	}
+	// 需要等到下一个窗口，当前计数成为上一窗口计数后按比例衰减
+	return l.fraction(1 - elapsed + (1 - allowance/curr))
+}
+
+// fraction 将窗口比例换算为时长
+func (l *redisLimiter) fraction(f float64) time.Duration {
+	return time.Duration(f * float64(l.window))
+}
@@ -0,0 +1,209 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis 是一个只支持限流所需命令的 RESP 服务，用来代替真实的 Redis
+type fakeRedis struct {
+	ln       net.Listener
+	password string
+
+	mu   sync.Mutex
+	data map[string]int64
+	ttl  map[string]int64 // 记录 PEXPIRE 的参数，便于断言
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	f := &fakeRedis{ln: ln, password: password, data: map[string]int64{}, ttl: map[string]int64{}}
+	go f.serve()
+	t.Cleanup(func() { ln.Close() })
+	return f
+}
+
+func (f *fakeRedis) addr() string { return f.ln.Addr().String() }
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i], _ = item.(string)
+		}
+		if len(args) == 0 {
+			return
+		}
+
+		cmd := strings.ToUpper(args[0])
+		var out string
+		switch {
+		case cmd == "AUTH":
+			if args[len(args)-1] == f.password {
+				authed = true
+				out = "+OK\r\n"
+			} else {
+				out = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			out = "-NOAUTH Authentication required.\r\n"
+		default:
+			out = f.exec(cmd, args[1:])
+		}
+		if _, err := conn.Write([]byte(out)); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) exec(cmd string, args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch cmd {
+	case "PING":
+		return "+PONG\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "INCR":
+		f.data[args[0]]++
+		return ":" + strconv.FormatInt(f.data[args[0]], 10) + "\r\n"
+	case "DECR":
+		f.data[args[0]]--
+		return ":" + strconv.FormatInt(f.data[args[0]], 10) + "\r\n"
+	case "PEXPIRE":
+		ms, _ := strconv.ParseInt(args[1], 10, 64)
+		f.ttl[args[0]] = ms
+		return ":1\r\n"
+	case "GET":
+		v, ok := f.data[args[0]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		s := strconv.FormatInt(v, 10)
+		return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+	}
+	return "-ERR unknown command\r\n"
+}
+
+// newTestRedisLimiter 创建使用固定时钟的 Redis 限流器
+func newTestRedisLimiter(store *RedisStore, policy Policy, clock *time.Time) *redisLimiter {
+	l := store.Limiter("test", policy).(*redisLimiter)
+	l.now = func() time.Time { return *clock }
+	return l
+}
+
+func TestRedisLimiter_SharedAcrossReplicas(t *testing.T) {
+	srv := newFakeRedis(t, "secret")
+	policy := Policy{Rate: 1, Burst: 3} // 每 3 秒窗口 3 次
+	clock := time.Unix(1699999998, 0)   // 恰好位于窗口起点
+
+	// 两个副本各自持有独立的 Store，但连接同一个 Redis
+	a := newTestRedisLimiter(NewRedisStore(RedisOptions{Addr: srv.addr(), Password: "secret"}, ""), policy, &clock)
+	b := newTestRedisLimiter(NewRedisStore(RedisOptions{Addr: srv.addr(), Password: "secret"}, ""), policy, &clock)
+	ctx := context.Background()
+
+	for i, l := range []*redisLimiter{a, b, a} {
+		res, err := l.take(ctx, "ip:1.1.1.1")
+		if err != nil {
+			t.Fatalf("第 %d 次请求出错: %v", i+1, err)
+		}
+		if !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("第 %d 次请求应放行且剩余 %d: %+v", i+1, 2-i, res)
+		}
+	}
+	res, err := b.take(ctx, "ip:1.1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Allowed {
+		t.Fatal("两个副本合计超过限额后应被拒绝")
+	}
+	if res.RetryAfter != 4*time.Second {
+		t.Errorf("RetryAfter = %s, 期望 4s", res.RetryAfter)
+	}
+
+	srv.mu.Lock()
+	key := DefaultKeyPrefix + "test:ip:1.1.1.1:" + strconv.FormatInt(1699999998/3, 10)
+	count, ttl := srv.data[key], srv.ttl[key]
+	srv.mu.Unlock()
+	if count != 3 {
+		t.Errorf("被拒绝的请求不应计数, 得到 %d", count)
+	}
+	if ttl != 6000 {
+		t.Errorf("计数键应在两个窗口后过期, 得到 %dms", ttl)
+	}
+
+	// 进入下一个窗口的一半：上一窗口计数按 50% 计入
+	clock = clock.Add(3*time.Second + 1500*time.Millisecond)
+	res, _ = a.take(ctx, "ip:1.1.1.1")
+	if !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("滑动窗口应放行一次且剩余 0: %+v", res)
+	}
+	if res, _ = a.take(ctx, "ip:1.1.1.1"); res.Allowed {
+		t.Fatalf("滑动窗口内超过限额应被拒绝: %+v", res)
+	}
+}
+
+func TestRedisLimiter_FallbackWhenUnavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close() // 得到一个没有服务监听的地址
+
+	store := NewRedisStore(RedisOptions{Addr: addr, Timeout: 50 * time.Millisecond}, "")
+	l := store.Limiter("test", Policy{Rate: 0.01, Burst: 1})
+	ctx := context.Background()
+
+	if res, err := l.Take(ctx, "k"); err != nil || !res.Allowed {
+		t.Fatalf("Redis 不可用时应使用进程内限流放行首个请求: %+v, %v", res, err)
+	}
+	if res, _ := l.Take(ctx, "k"); res.Allowed {
+		t.Fatal("退回进程内限流后仍应限制请求")
+	}
+	if err := store.Ping(ctx); err == nil {
+		t.Fatal("Ping 应返回连接错误")
+	}
+}
+
+func TestRedisStore_AuthFailure(t *testing.T) {
+	srv := newFakeRedis(t, "secret")
+	store := NewRedisStore(RedisOptions{Addr: srv.addr(), Password: "wrong"}, "")
+	if err := store.Ping(context.Background()); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Fatalf("密码错误时应返回认证错误, 得到 %v", err)
+	}
+
+	store = NewRedisStore(RedisOptions{Addr: srv.addr(), Password: "secret", DB: 2}, "")
+	if err := store.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping 失败: %v", err)
+	}
+}
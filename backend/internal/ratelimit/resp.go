@@ -0,0 +1,238 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// 连接池与超时的默认值
+const (
+	defaultPoolSize = 10
+	defaultTimeout  = 200 * time.Millisecond
+)
+
+// respError 是服务端返回的错误回复（"-ERR ..."）
+type respError string
+
+func (e respError) Error() string { return "redis: " + string(e) }
+
+// RedisOptions 描述 Redis 连接参数
+type RedisOptions struct {
+	Addr     string
+	Username string // Redis 6 ACL 用户名，可为空
+	Password string
+	DB       int
+	PoolSize int
+	Timeout  time.Duration // 单次往返（含建连）的超时
+}
+
+// redisClient 是一个最小化的 RESP2 客户端，只实现限流需要的命令与流水线。
+// 只依赖 Redis 协议，KeyDB、Dragonfly、Valkey 等兼容服务均可使用。
+type redisClient struct {
+	opts RedisOptions
+	pool chan *redisConn
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// newRedisClient 创建客户端，连接按需建立
+func newRedisClient(opts RedisOptions) *redisClient {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = defaultPoolSize
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	return &redisClient{opts: opts, pool: make(chan *redisConn, opts.PoolSize)}
+}
+
+// dial 建立连接并完成认证与选库
+func (c *redisClient) dial(ctx context.Context) (*redisConn, error) {
+	d := net.Dialer{Timeout: c.opts.Timeout}
+	conn, err := d.DialContext(ctx, "tcp", c.opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	rc := &redisConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+
+	var setup [][]string
+	if c.opts.Password != "" {
+		if c.opts.Username != "" {
+			setup = append(setup, []string{"AUTH", c.opts.Username, c.opts.Password})
+		} else {
+			setup = append(setup, []string{"AUTH", c.opts.Password})
+		}
+	}
+	if c.opts.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.opts.DB)})
+	}
+	if len(setup) > 0 {
+		replies, err := rc.pipeline(c.deadline(ctx), setup)
+		if err == nil {
+			for _, r := range replies {
+				if e, ok := r.(respError); ok {
+					err = e
+					break
+				}
+			}
+		}
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis 初始化连接失败: %w", err)
+		}
+	}
+	return rc, nil
+}
+
+func (c *redisClient) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(c.opts.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		return d
+	}
+	return deadline
+}
+
+// pipeline 一次性发送多条命令并按顺序读取回复。
+// 单条命令的错误回复以 respError 形式放在结果中，网络错误则直接返回。
+func (c *redisClient) pipeline(ctx context.Context, cmds [][]string) ([]interface{}, error) {
+	var rc *redisConn
+	select {
+	case rc = <-c.pool:
+	default:
+		var err error
+		if rc, err = c.dial(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	replies, err := rc.pipeline(c.deadline(ctx), cmds)
+	if err != nil {
+		// 连接状态未知，直接丢弃
+		rc.conn.Close()
+		return nil, err
+	}
+	select {
+	case c.pool <- rc:
+	default:
+		rc.conn.Close()
+	}
+	return replies, nil
+}
+
+// close 关闭池中的空闲连接
+func (c *redisClient) close() {
+	for {
+		select {
+		case rc := <-c.pool:
+			rc.conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+func (rc *redisConn) pipeline(deadline time.Time, cmds [][]string) ([]interface{}, error) {
+	if err := rc.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	for _, cmd := range cmds {
+		writeCommand(rc.w, cmd)
+	}
+	if err := rc.w.Flush(); err != nil {
+		return nil, err
+	}
+	replies := make([]interface{}, len(cmds))
+	for i := range cmds {
+		reply, err := readReply(rc.r)
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+// writeCommand 以 RESP 数组的形式写入一条命令
+func writeCommand(w *bufio.Writer, args []string) {
+	w.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		w.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n")
+		w.WriteString(arg)
+		w.WriteString("\r\n")
+	}
+}
+
+// readReply 读取一条 RESP2 回复。返回值类型：string（简单字符串/批量字符串）、
+// int64（整数）、nil（空回复）、respError（错误回复）、[]interface{}（数组）。
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: 无效的回复 %q", line)
+	}
+	body := line[1 : len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return body, nil
+	case '-':
+		return respError(body), nil
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: 未知的回复类型 %q", line[0])
+}
+
+// replyInt 将 INCR/GET 等命令的回复转换为整数，空回复视为 0
+func replyInt(reply interface{}) (int64, error) {
+	switch v := reply.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		return v, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	case respError:
+		return 0, v
+	}
+	return 0, fmt.Errorf("redis: 非预期的回复 %v", reply)
+}
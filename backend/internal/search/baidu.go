@@ -0,0 +1,28 @@
+package search
+
+import (
+	"context"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/domain"
+	"github.com/chenxuan520/roadmap/backend/internal/search/baidu"
+)
+
+func init() {
+	Register("baidu", func(cfg config.SearchProviderConfig) Provider { return baiduProvider{cfg} })
+}
+
+// baiduProvider scrapes the map.baidu.com web endpoint and needs no key.
+type baiduProvider struct {
+	cfg config.SearchProviderConfig
+}
+
+func (baiduProvider) Name() string          { return "baidu" }
+func (baiduProvider) Label() string         { return "百度" }
+func (p baiduProvider) Enabled() bool       { return !p.cfg.Disabled }
+func (p baiduProvider) LoginRequired() bool { return p.cfg.LoginRequired }
+
+func (baiduProvider) Search(ctx context.Context, query string, opts Options) ([]domain.NominatimResult, error) {
+	results, err := baidu.Search(ctx, query)
+	return limit(results, opts), err
+}
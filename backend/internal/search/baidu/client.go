@@ -1,6 +1,7 @@
 package baidu
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,13 +13,28 @@ import (
 
 	"github.com/chenxuan520/roadmap/backend/internal/coord"
 	"github.com/chenxuan520/roadmap/backend/internal/domain"
+	"github.com/chenxuan520/roadmap/backend/internal/logging"
+	"github.com/chenxuan520/roadmap/backend/internal/tracing"
 )
 
-func Search(query string) ([]domain.NominatimResult, error) {
+// client keeps connections to map.baidu.com alive between searches.
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// Search queries the Baidu map web endpoint. ctx carries the request-scoped logger
+// and cancels the upstream call when the client goes away.
+func Search(ctx context.Context, query string) (results []domain.NominatimResult, err error) {
 	if query == "" {
 		return []domain.NominatimResult{}, nil
 	}
 
+	ctx, span := tracing.Start(ctx, "baidu.Search", tracing.SpanKindClient,
+		tracing.String("search.provider", "baidu"), tracing.String("server.address", "map.baidu.com"))
+	defer func() {
+		span.RecordError(err)
+		span.SetAttributes(tracing.Int("search.results", len(results)))
+		span.End()
+	}()
+
 	baiduURL := "https://map.baidu.com/"
 	params := url.Values{}
 	params.Set("newmap", "1")
@@ -31,16 +47,20 @@ func Search(query string) ([]domain.NominatimResult, error) {
 	params.Set("rn", "10")
 	params.Set("ie", "utf-8")
 
-	req, _ := http.NewRequest("GET", baiduURL+"?"+params.Encode(), nil)
+	req, _ := http.NewRequestWithContext(ctx, "GET", baiduURL+"?"+params.Encode(), nil)
 	req.Header.Set("Referer", "https://map.baidu.com/")
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	tracing.Inject(ctx, req.Header)
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
+		logging.FromContext(ctx).Warn("search upstream request failed", "provider", "baidu", "latency_ms", time.Since(start).Milliseconds(), "error", err)
 		return nil, fmt.Errorf("upstream error: %w", err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(tracing.Int("http.response.status_code", resp.StatusCode))
+	logging.FromContext(ctx).Debug("search upstream responded", "provider", "baidu", "status", resp.StatusCode, "latency_ms", time.Since(start).Milliseconds())
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -74,7 +94,7 @@ func Search(query string) ([]domain.NominatimResult, error) {
 		contentList = append(contentList, currentCity)
 	}
 
-	results := []domain.NominatimResult{}
+	results = []domain.NominatimResult{}
 
 	for i, itemInterface := range contentList {
 		item, ok := itemInterface.(map[string]interface{})
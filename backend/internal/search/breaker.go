@@ -0,0 +1,138 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/domain"
+)
+
+// ErrCircuitOpen is returned without calling the upstream while a provider's breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+const (
+	defaultFailureThreshold = 5
+	defaultCooldown         = 30 * time.Second
+)
+
+// Breaker states reported by CircuitStatus.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// CircuitStatus is a snapshot of a provider's circuit breaker.
+type CircuitStatus struct {
+	State string `json:"state"`
+	// ConsecutiveFailures counts failed calls since the last success.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	// OpenUntil is when an open breaker lets the next trial call through.
+	OpenUntil *time.Time `json:"open_until,omitempty"`
+}
+
+// breaker opens after threshold consecutive failures and rejects calls for the cooldown.
+// Afterwards a single trial call is let through (half-open): success closes the breaker,
+// failure opens it for another cooldown. Breakers outlive config reloads, only their
+// settings change.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool // a half-open trial call is in flight
+
+	now func() time.Time
+}
+
+func newBreaker(cfg config.CircuitBreakerConfig) *breaker {
+	b := &breaker{now: time.Now}
+	b.configure(cfg)
+	return b
+}
+
+func (b *breaker) configure(cfg config.CircuitBreakerConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.threshold = cfg.FailureThreshold
+	if b.threshold <= 0 {
+		b.threshold = defaultFailureThreshold
+	}
+	b.cooldown = time.Duration(cfg.CooldownSeconds) * time.Second
+	if b.cooldown <= 0 {
+		b.cooldown = defaultCooldown
+	}
+}
+
+// allow reports whether a call may go upstream and whether it is the half-open trial call.
+func (b *breaker) allow() (ok, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true, false
+	}
+	if b.now().Before(b.openUntil) || b.probing {
+		return false, false
+	}
+	b.probing = true
+	return true, true
+}
+
+// record feeds the outcome of an allowed call back into the breaker. Calls cancelled by
+// the client say nothing about the upstream and are ignored.
+func (b *breaker) record(probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if err == nil {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+}
+
+func (b *breaker) status() CircuitStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := CircuitStatus{State: CircuitClosed, ConsecutiveFailures: b.failures}
+	if b.failures < b.threshold {
+		return s
+	}
+	if b.now().Before(b.openUntil) {
+		until := b.openUntil
+		s.State, s.OpenUntil = CircuitOpen, &until
+	} else {
+		s.State = CircuitHalfOpen
+	}
+	return s
+}
+
+// guarded puts a breaker in front of a provider.
+type guarded struct {
+	Provider
+	breaker *breaker
+}
+
+func (p guarded) Search(ctx context.Context, query string, opts Options) ([]domain.NominatimResult, error) {
+	ok, probe := p.breaker.allow()
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", p.Name(), ErrCircuitOpen)
+	}
+	results, err := p.Provider.Search(ctx, query, opts)
+	p.breaker.record(probe, err)
+	return results, err
+}
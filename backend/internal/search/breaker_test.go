@@ -0,0 +1,147 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/domain"
+)
+
+// flakyProvider 按 err 返回结果并记录被调用的次数
+type flakyProvider struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (p *flakyProvider) Name() string        { return p.name }
+func (p *flakyProvider) Label() string       { return p.name }
+func (p *flakyProvider) Enabled() bool       { return true }
+func (p *flakyProvider) LoginRequired() bool { return false }
+func (p *flakyProvider) Search(ctx context.Context, query string, opts Options) ([]domain.NominatimResult, error) {
+	p.calls++
+	return nil, p.err
+}
+
+func TestBreaker_OpensAndRecovers(t *testing.T) {
+	now := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	b := newBreaker(config.CircuitBreakerConfig{FailureThreshold: 2, CooldownSeconds: 10})
+	b.now = func() time.Time { return now }
+	upstream := &flakyProvider{name: "baidu", err: errors.New("baidu api returned html")}
+	p := guarded{Provider: upstream, breaker: b}
+	ctx := context.Background()
+
+	p.Search(ctx, "q", Options{})
+	if s := b.status(); s.State != CircuitClosed || s.ConsecutiveFailures != 1 {
+		t.Fatalf("未达到阈值时应保持关闭: %+v", s)
+	}
+	p.Search(ctx, "q", Options{})
+	s := b.status()
+	if s.State != CircuitOpen || s.OpenUntil == nil || !s.OpenUntil.Equal(now.Add(10*time.Second)) {
+		t.Fatalf("连续失败达到阈值后应熔断: %+v", s)
+	}
+
+	if _, err := p.Search(ctx, "q", Options{}); !errors.Is(err, ErrCircuitOpen) || upstream.calls != 2 {
+		t.Fatalf("熔断期间不应调用上游, err=%v calls=%d", err, upstream.calls)
+	}
+
+	// 冷却结束后只放行一次试探请求，失败则重新熔断
+	now = now.Add(11 * time.Second)
+	if s := b.status(); s.State != CircuitHalfOpen {
+		t.Fatalf("冷却结束后应为半开状态: %+v", s)
+	}
+	p.Search(ctx, "q", Options{})
+	if upstream.calls != 3 || b.status().State != CircuitOpen {
+		t.Fatalf("试探失败应重新熔断, calls=%d state=%s", upstream.calls, b.status().State)
+	}
+
+	now = now.Add(11 * time.Second)
+	upstream.err = nil
+	if _, err := p.Search(ctx, "q", Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.status(); s.State != CircuitClosed || s.ConsecutiveFailures != 0 {
+		t.Errorf("试探成功后应恢复: %+v", s)
+	}
+}
+
+func TestBreaker_SingleProbe(t *testing.T) {
+	b := newBreaker(config.CircuitBreakerConfig{FailureThreshold: 1})
+	b.record(false, errors.New("boom"))
+	b.openUntil = time.Time{} // 冷却已结束
+
+	if ok, probe := b.allow(); !ok || !probe {
+		t.Fatal("冷却结束后应放行一次试探请求")
+	}
+	if ok, _ := b.allow(); ok {
+		t.Error("试探请求返回前不应放行其他请求")
+	}
+	// 客户端取消的请求不计入失败，但要释放试探名额
+	b.record(true, context.Canceled)
+	if s := b.status(); s.ConsecutiveFailures != 1 {
+		t.Errorf("取消的请求不应计入失败: %+v", s)
+	}
+	if ok, probe := b.allow(); !ok || !probe {
+		t.Error("试探请求被取消后应允许新的试探")
+	}
+}
+
+func TestRegistry_ChainAndReload(t *testing.T) {
+	factory := func(name string) Factory {
+		return func(cfg config.SearchProviderConfig) Provider { return fakeProvider{name, cfg} }
+	}
+	regs := []registration{{"baidu", factory("baidu")}, {"gaode", factory("gaode")}, {"tianmap", factory("tianmap")}}
+	cfg := config.SearchConfig{
+		Fallback:       []string{"baidu", "gaode", "tianmap"},
+		CircuitBreaker: config.CircuitBreakerConfig{FailureThreshold: 1},
+	}
+	r, err := newRegistry(cfg, regs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := func(chain []Provider) []string {
+		var out []string
+		for _, p := range chain {
+			out = append(out, p.Name())
+		}
+		return out
+	}
+	for name, want := range map[string]string{
+		"baidu":   "baidu,gaode,tianmap",
+		"gaode":   "gaode,tianmap",
+		"tianmap": "tianmap",
+	} {
+		if got := names(r.Chain(name)); strings.Join(got, ",") != want {
+			t.Errorf("Chain(%q) = %v, 期望 %s", name, got, want)
+		}
+	}
+	if chain := r.Chain("nope"); chain != nil {
+		t.Errorf("未知搜索源不应有链: %v", names(chain))
+	}
+
+	// 熔断状态在配置重新加载后保留
+	r.breakers["baidu"].record(false, errors.New("boom"))
+	commit, err := r.PrepareReload(&config.Config{Search: config.SearchConfig{
+		Fallback:       []string{"tianmap"},
+		CircuitBreaker: config.CircuitBreakerConfig{FailureThreshold: 1},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit()
+	if s := r.Circuit("baidu"); s.State != CircuitOpen {
+		t.Errorf("重新加载后熔断状态应保留: %+v", s)
+	}
+	if got := strings.Join(names(r.Chain("baidu")), ","); got != "baidu,tianmap" {
+		t.Errorf("不在链中的搜索源应依次尝试整条链, 得到 %s", got)
+	}
+
+	if _, err := r.PrepareReload(&config.Config{Search: config.SearchConfig{Fallback: []string{"bing"}}}); err == nil {
+		t.Error("未注册的备用搜索源应拒绝重新加载")
+	}
+}
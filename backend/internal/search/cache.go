@@ -0,0 +1,265 @@
+package search
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/domain"
+	"github.com/chenxuan520/roadmap/backend/internal/metrics"
+)
+
+const (
+	defaultCacheEntries = 1000
+	defaultCacheTTL     = 10 * time.Minute
+)
+
+// errSearchAborted is what callers waiting on a coalesced search see if the search
+// panicked instead of returning.
+var errSearchAborted = errors.New("search aborted")
+
+// Cache is an LRU cache of search results with a TTL, keyed by provider and normalized
+// query. Concurrent misses for the same key are coalesced into a single upstream call.
+// Only successful searches are cached.
+type Cache struct {
+	mu       sync.Mutex
+	max      int
+	ttl      time.Duration
+	file     string
+	lru      *list.List // front is most recently used
+	entries  map[string]*list.Element
+	inflight map[string]*flight
+
+	now func() time.Time
+}
+
+type cacheEntry struct {
+	Key     string                   `json:"key"`
+	Results []domain.NominatimResult `json:"results"`
+	Expires time.Time                `json:"expires"`
+}
+
+// flight is an upstream call that other callers for the same key wait on.
+type flight struct {
+	done    chan struct{}
+	results []domain.NominatimResult
+	err     error
+}
+
+// NewCache creates a cache from cfg. When cfg.File is set, entries saved by a previous
+// Save that have not expired yet are loaded from it; a file that cannot be decoded is
+// ignored.
+func NewCache(cfg config.SearchCacheConfig) (*Cache, error) {
+	c := &Cache{
+		max:      cfg.MaxEntries,
+		ttl:      time.Duration(cfg.TTLSeconds) * time.Second,
+		file:     cfg.File,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+		inflight: make(map[string]*flight),
+		now:      time.Now,
+	}
+	if c.max <= 0 {
+		c.max = defaultCacheEntries
+	}
+	if c.ttl <= 0 {
+		c.ttl = defaultCacheTTL
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// cacheKey ignores case and repeated whitespace, so "北京 南站" and " 北京  南站" share an entry.
+func cacheKey(provider, query string) string {
+	return provider + "\x00" + strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+// Do returns the cached results for provider and query, or calls fetch and caches what it
+// returns. While a fetch for the same key is running, other callers wait for its result
+// instead of calling the upstream themselves.
+func (c *Cache) Do(ctx context.Context, provider, query string, fetch func(ctx context.Context) ([]domain.NominatimResult, error)) ([]domain.NominatimResult, error) {
+	key := cacheKey(provider, query)
+	for {
+		c.mu.Lock()
+		if results, ok := c.get(key); ok {
+			c.mu.Unlock()
+			metrics.SearchCacheRequests.Inc(provider, "hit")
+			return results, nil
+		}
+		f, waiting := c.inflight[key]
+		if !waiting {
+			f = &flight{done: make(chan struct{}), err: errSearchAborted}
+			c.inflight[key] = f
+		}
+		c.mu.Unlock()
+
+		if !waiting {
+			metrics.SearchCacheRequests.Inc(provider, "miss")
+			c.fetch(key, f, func() ([]domain.NominatimResult, error) { return fetch(ctx) })
+			return f.results, f.err
+		}
+
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		// The caller that went upstream gave up (client gone or its own deadline passed);
+		// this caller still has time, so it tries again itself.
+		if isContextError(f.err) && ctx.Err() == nil {
+			continue
+		}
+		metrics.SearchCacheRequests.Inc(provider, "shared")
+		return f.results, f.err
+	}
+}
+
+func (c *Cache) fetch(key string, f *flight, fetch func() ([]domain.NominatimResult, error)) {
+	defer func() {
+		c.mu.Lock()
+		delete(c.inflight, key)
+		if f.err == nil {
+			c.add(key, f.results)
+		}
+		c.mu.Unlock()
+		close(f.done)
+	}()
+	f.results, f.err = fetch()
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// get returns a live entry and marks it recently used. The caller holds c.mu.
+func (c *Cache) get(key string) ([]domain.NominatimResult, bool) {
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if !c.now().Before(e.Expires) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return e.Results, true
+}
+
+// add stores results and evicts the least recently used entries beyond the limit. The
+// caller holds c.mu.
+func (c *Cache) add(key string, results []domain.NominatimResult) {
+	c.insert(&cacheEntry{Key: key, Results: results, Expires: c.now().Add(c.ttl)})
+}
+
+func (c *Cache) insert(e *cacheEntry) {
+	if el, ok := c.entries[e.Key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[e.Key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.max {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).Key)
+	}
+}
+
+// Len returns the number of cached queries, including expired ones not yet evicted.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Save writes the unexpired entries to search.cache.file, if set. The file is replaced
+// atomically so a crash never leaves a truncated cache behind.
+func (c *Cache) Save() error {
+	if c.file == "" {
+		return nil
+	}
+	c.mu.Lock()
+	now := c.now()
+	entries := make([]*cacheEntry, 0, c.lru.Len())
+	// Oldest first, so loading re-inserts them in the same LRU order.
+	for el := c.lru.Back(); el != nil; el = el.Prev() {
+		if e := el.Value.(*cacheEntry); now.Before(e.Expires) {
+			entries = append(entries, e)
+		}
+	}
+	data, err := json.Marshal(entries)
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("encode search cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.file), 0755); err != nil {
+		return fmt.Errorf("create search cache directory: %w", err)
+	}
+	tmp := c.file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write search cache: %w", err)
+	}
+	if err := os.Rename(tmp, c.file); err != nil {
+		return fmt.Errorf("write search cache: %w", err)
+	}
+	return nil
+}
+
+func (c *Cache) load() error {
+	if c.file == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read search cache: %w", err)
+	}
+	var entries []*cacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		// A damaged cache only costs upstream calls; it must not keep the server from starting.
+		slog.Warn("ignoring unreadable search cache", "file", c.file, "error", err)
+		return nil
+	}
+	now := c.now()
+	for _, e := range entries {
+		if now.Before(e.Expires) {
+			c.insert(e)
+		}
+	}
+	return nil
+}
+
+// cached serves a provider's searches through the cache. The full result list is cached
+// and opts.Limit applied afterwards, so one entry serves every limit. GCJ-02 results are
+// cached apart from converted ones.
+type cached struct {
+	Provider
+	cache *Cache
+}
+
+func (p cached) Search(ctx context.Context, query string, opts Options) ([]domain.NominatimResult, error) {
+	name := p.Name()
+	if opts.GCJ02 {
+		name += "@gcj02"
+	}
+	results, err := p.cache.Do(ctx, name, query, func(ctx context.Context) ([]domain.NominatimResult, error) {
+		return p.Provider.Search(ctx, query, Options{GCJ02: opts.GCJ02})
+	})
+	return limit(results, opts), err
+}
@@ -0,0 +1,228 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/domain"
+	"github.com/chenxuan520/roadmap/backend/internal/metrics"
+)
+
+// countingFetch 返回一条以查询命名的结果并记录上游调用次数
+func countingFetch(calls *int32, name string) func(context.Context) ([]domain.NominatimResult, error) {
+	return func(context.Context) ([]domain.NominatimResult, error) {
+		atomic.AddInt32(calls, 1)
+		return []domain.NominatimResult{{DisplayName: name}}, nil
+	}
+}
+
+func TestCache_HitMissAndExpiry(t *testing.T) {
+	now := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	c, err := NewCache(config.SearchCacheConfig{TTLSeconds: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+	var calls int32
+	hits, misses := metrics.SearchCacheRequests.Value("cache-hit-test", "hit"), metrics.SearchCacheRequests.Value("cache-hit-test", "miss")
+
+	c.Do(ctx, "cache-hit-test", "北京 南站", countingFetch(&calls, "北京南站"))
+	results, _ := c.Do(ctx, "cache-hit-test", "  北京   南站 ", countingFetch(&calls, "其他"))
+	if calls != 1 || len(results) != 1 || results[0].DisplayName != "北京南站" {
+		t.Fatalf("只有空白不同的查询应命中缓存, calls=%d results=%v", calls, results)
+	}
+	c.Do(ctx, "other-provider", "北京 南站", countingFetch(&calls, "北京南站"))
+	if calls != 2 {
+		t.Errorf("不同搜索源不应共享缓存, calls=%d", calls)
+	}
+	hits = metrics.SearchCacheRequests.Value("cache-hit-test", "hit") - hits
+	misses = metrics.SearchCacheRequests.Value("cache-hit-test", "miss") - misses
+	if hits != 1 || misses != 1 {
+		t.Errorf("命中与未命中次数错误: hit=%v miss=%v", hits, misses)
+	}
+
+	now = now.Add(61 * time.Second)
+	c.Do(ctx, "cache-hit-test", "北京 南站", countingFetch(&calls, "北京南站"))
+	if calls != 3 {
+		t.Errorf("过期后应重新调用上游, calls=%d", calls)
+	}
+
+	// 失败的结果不缓存
+	failing := func(context.Context) ([]domain.NominatimResult, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errors.New("boom")
+	}
+	c.Do(ctx, "cache-hit-test", "失败", failing)
+	c.Do(ctx, "cache-hit-test", "失败", failing)
+	if calls != 5 {
+		t.Errorf("失败的查询不应缓存, calls=%d", calls)
+	}
+}
+
+// datumProvider 返回的结果标明调用时是否要求保留 GCJ-02 坐标
+type datumProvider struct {
+	fakeProvider
+	calls *int32
+}
+
+func (p datumProvider) Search(ctx context.Context, query string, opts Options) ([]domain.NominatimResult, error) {
+	atomic.AddInt32(p.calls, 1)
+	if opts.GCJ02 {
+		return []domain.NominatimResult{{DisplayName: "gcj02"}}, nil
+	}
+	return []domain.NominatimResult{{DisplayName: "wgs84"}}, nil
+}
+
+func TestCached_SeparatesGCJ02Results(t *testing.T) {
+	c, err := NewCache(config.SearchCacheConfig{TTLSeconds: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls int32
+	p := cached{Provider: datumProvider{fakeProvider: fakeProvider{name: "cache-datum-test"}, calls: &calls}, cache: c}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		results, _ := p.Search(ctx, "天安门", Options{})
+		if len(results) != 1 || results[0].DisplayName != "wgs84" {
+			t.Fatalf("默认应返回 WGS-84 结果, 得到 %v", results)
+		}
+		results, _ = p.Search(ctx, "天安门", Options{GCJ02: true})
+		if len(results) != 1 || results[0].DisplayName != "gcj02" {
+			t.Fatalf("GCJ02 为 true 时应返回 GCJ-02 结果而不是缓存的 WGS-84 结果, 得到 %v", results)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("两种坐标系应各自缓存, 期望上游调用 2 次, 实际 %d 次", calls)
+	}
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c, _ := NewCache(config.SearchCacheConfig{MaxEntries: 2})
+	ctx := context.Background()
+	var calls int32
+
+	c.Do(ctx, "p", "a", countingFetch(&calls, "a"))
+	c.Do(ctx, "p", "b", countingFetch(&calls, "b"))
+	c.Do(ctx, "p", "a", countingFetch(&calls, "a")) // a 变为最近使用
+	c.Do(ctx, "p", "c", countingFetch(&calls, "c")) // 淘汰 b
+	if c.Len() != 2 || calls != 3 {
+		t.Fatalf("条目数应不超过上限, len=%d calls=%d", c.Len(), calls)
+	}
+	c.Do(ctx, "p", "a", countingFetch(&calls, "a"))
+	if calls != 3 {
+		t.Error("最近使用的条目不应被淘汰")
+	}
+	c.Do(ctx, "p", "b", countingFetch(&calls, "b"))
+	if calls != 4 {
+		t.Error("最久未使用的条目应被淘汰")
+	}
+}
+
+func TestCache_CoalescesConcurrentMisses(t *testing.T) {
+	c, _ := NewCache(config.SearchCacheConfig{})
+	var calls int32
+	release := make(chan struct{})
+	fetch := func(context.Context) ([]domain.NominatimResult, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return []domain.NominatimResult{{DisplayName: "天安门"}}, nil
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	results := make([][]domain.NominatimResult, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = c.Do(context.Background(), "coalesce-test", "天安门", fetch)
+		}(i)
+	}
+	// 上游调用开始后稍等片刻，让其余请求都在等待它
+	for deadline := time.Now().Add(time.Second); atomic.LoadInt32(&calls) == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("并发的相同查询只应调用一次上游, calls=%d", calls)
+	}
+	for i, r := range results {
+		if len(r) != 1 {
+			t.Errorf("请求 %d 应得到共享的结果: %v", i, r)
+		}
+	}
+}
+
+func TestCache_WaiterRetriesWhenLeaderGivesUp(t *testing.T) {
+	c, _ := NewCache(config.SearchCacheConfig{})
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	go c.Do(leaderCtx, "p", "q", func(ctx context.Context) ([]domain.NominatimResult, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	<-started
+
+	done := make(chan []domain.NominatimResult)
+	go func() {
+		results, _ := c.Do(context.Background(), "p", "q", func(context.Context) ([]domain.NominatimResult, error) {
+			return []domain.NominatimResult{{DisplayName: "q"}}, nil
+		})
+		done <- results
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case results := <-done:
+		if len(results) != 1 {
+			t.Errorf("发起请求的客户端离开后, 等待者应自己调用上游: %v", results)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("等待者没有返回")
+	}
+}
+
+func TestCache_Persistence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "data", "search_cache.json")
+	cfg := config.SearchCacheConfig{File: file, TTLSeconds: 60}
+	c, err := NewCache(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls int32
+	c.Do(context.Background(), "p", "故宫", countingFetch(&calls, "故宫"))
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := NewCache(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, _ := restored.Do(context.Background(), "p", "故宫", countingFetch(&calls, "不应调用"))
+	if calls != 1 || len(results) != 1 || results[0].DisplayName != "故宫" {
+		t.Errorf("重启后应从文件恢复缓存, calls=%d results=%v", calls, results)
+	}
+
+	// 损坏的缓存文件被忽略，不影响启动
+	if err := os.WriteFile(file, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := NewCache(cfg); err != nil || c.Len() != 0 {
+		t.Errorf("损坏的缓存文件应被忽略, err=%v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/domain"
+)
+
+// AllProviders is the provider name that fans a query out to every enabled provider.
+const AllProviders = "all"
+
+const (
+	defaultFederatedTimeout    = 3 * time.Second
+	defaultDedupDistanceMeters = 200.0
+	// minNameSimilarity is the bigram Dice coefficient above which two names are the same place.
+	minNameSimilarity = 0.6
+)
+
+// Result is a search result annotated with the providers that returned it.
+type Result struct {
+	domain.NominatimResult
+	// Source is the provider whose entry was kept when duplicates were merged.
+	Source string `json:"source"`
+	// Sources lists every provider that returned the place.
+	Sources []string `json:"sources"`
+}
+
+// Failure records a provider that failed or timed out during a federated search.
+type Failure struct {
+	Provider string
+	Err      error
+}
+
+// Federate queries providers concurrently, each bounded by cfg.TimeoutMillis, then merges
+// results that have similar names and lie within cfg.DedupDistanceMeters of each other and
+// ranks them. Failing providers are reported next to whatever the others returned.
+// opts.Limit applies to the merged list.
+func Federate(ctx context.Context, providers []Provider, query string, opts Options, cfg config.FederatedSearchConfig) ([]Result, []Failure) {
+	timeout := defaultFederatedTimeout
+	if cfg.TimeoutMillis > 0 {
+		timeout = time.Duration(cfg.TimeoutMillis) * time.Millisecond
+	}
+	responses := make([][]domain.NominatimResult, len(providers))
+	errs := make([]error, len(providers))
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func(i int, p Provider) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			responses[i], errs[i] = p.Search(ctx, query, Options{})
+		}(i, p)
+	}
+	wg.Wait()
+
+	var failures []Failure
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, Failure{Provider: providers[i].Name(), Err: err})
+			responses[i] = nil
+		}
+	}
+
+	distance := defaultDedupDistanceMeters
+	if cfg.DedupDistanceMeters > 0 {
+		distance = cfg.DedupDistanceMeters
+	}
+	names := make([]string, len(providers))
+	for i, p := range providers {
+		names[i] = p.Name()
+	}
+	return merge(names, responses, query, distance, opts.Limit), failures
+}
+
+// candidate is one provider result being merged.
+type candidate struct {
+	result   domain.NominatimResult
+	provider string
+	rank     int // position in the provider's own list
+	name     string
+	lat, lon float64
+	located  bool
+}
+
+type group struct {
+	best    candidate
+	sources []string
+	score   float64
+}
+
+// merge deduplicates and ranks the per-provider result lists. Results are visited rank by
+// rank across providers so each provider's top hits come first, and the first entry seen
+// for a place is kept as its representative.
+func merge(providers []string, responses [][]domain.NominatimResult, query string, distance float64, limit int) []Result {
+	var groups []*group
+	for rank := 0; ; rank++ {
+		more := false
+		for i, results := range responses {
+			if rank >= len(results) {
+				continue
+			}
+			more = true
+			c := newCandidate(results[rank], providers[i], rank)
+			if g := findDuplicate(groups, c, distance); g != nil {
+				if !containsString(g.sources, c.provider) {
+					g.sources = append(g.sources, c.provider)
+				}
+				continue
+			}
+			groups = append(groups, &group{best: c, sources: []string{c.provider}})
+		}
+		if !more {
+			break
+		}
+	}
+
+	q := normalizeName(query)
+	for _, g := range groups {
+		g.score = score(g, q)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].score > groups[j].score })
+
+	if limit > 0 && len(groups) > limit {
+		groups = groups[:limit]
+	}
+	merged := make([]Result, 0, len(groups))
+	for _, g := range groups {
+		merged = append(merged, Result{NominatimResult: g.best.result, Source: g.best.provider, Sources: g.sources})
+	}
+	return merged
+}
+
+// score ranks a merged place: the provider's own importance and rank, a bonus for every
+// additional provider that agrees, and a bonus when the name matches the query.
+func score(g *group, query string) float64 {
+	s := g.best.result.Importance - 0.02*float64(g.best.rank)
+	s += 0.1 * float64(len(g.sources)-1)
+	switch {
+	case query == "":
+	case g.best.name == query:
+		s += 0.1
+	case strings.Contains(g.best.name, query):
+		s += 0.05
+	}
+	return s
+}
+
+func newCandidate(r domain.NominatimResult, provider string, rank int) candidate {
+	c := candidate{result: r, provider: provider, rank: rank}
+	// Providers format display_name as "name, address" or "name (phone)"; only the name is compared.
+	name, _, _ := strings.Cut(r.DisplayName, ",")
+	name, _, _ = strings.Cut(name, " (")
+	c.name = normalizeName(name)
+	lat, latErr := strconv.ParseFloat(r.Lat, 64)
+	lon, lonErr := strconv.ParseFloat(r.Lon, 64)
+	c.lat, c.lon, c.located = lat, lon, latErr == nil && lonErr == nil
+	return c
+}
+
+func findDuplicate(groups []*group, c candidate, distance float64) *group {
+	if !c.located {
+		return nil
+	}
+	for _, g := range groups {
+		if !g.best.located || distanceMeters(g.best.lat, g.best.lon, c.lat, c.lon) > distance {
+			continue
+		}
+		if similarNames(g.best.name, c.name) {
+			return g
+		}
+	}
+	return nil
+}
+
+// normalizeName lowercases s and drops spaces, punctuation and symbols, so "北京南站" and
+// "北京 南站" compare equal.
+func normalizeName(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// similarNames reports whether two normalized names likely refer to the same place. It is
+// only consulted for results that are already close to each other.
+func similarNames(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	if a == b {
+		return true
+	}
+	return dice(a, b) >= minNameSimilarity
+}
+
+// dice computes the Sørensen–Dice coefficient of the rune bigrams of a and b.
+func dice(a, b string) float64 {
+	ba, bb := bigrams(a), bigrams(b)
+	if len(ba) == 0 || len(bb) == 0 {
+		return 0
+	}
+	counts := make(map[string]int, len(ba))
+	for _, g := range ba {
+		counts[g]++
+	}
+	shared := 0
+	for _, g := range bb {
+		if counts[g] > 0 {
+			counts[g]--
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(ba)+len(bb))
+}
+
+func bigrams(s string) []string {
+	runes := []rune(s)
+	if len(runes) < 2 {
+		return []string{s}
+	}
+	grams := make([]string, 0, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		grams = append(grams, string(runes[i:i+2]))
+	}
+	return grams
+}
+
+// distanceMeters is the haversine distance between two WGS-84 points.
+func distanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371000.0
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/domain"
+)
+
+// stubProvider 返回固定结果或错误，delay 用于模拟慢速上游
+type stubProvider struct {
+	name    string
+	results []domain.NominatimResult
+	err     error
+	delay   time.Duration
+}
+
+func (p stubProvider) Name() string        { return p.name }
+func (p stubProvider) Label() string       { return p.name }
+func (p stubProvider) Enabled() bool       { return true }
+func (p stubProvider) LoginRequired() bool { return false }
+func (p stubProvider) Search(ctx context.Context, query string, opts Options) ([]domain.NominatimResult, error) {
+	select {
+	case <-time.After(p.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return p.results, p.err
+}
+
+func place(name, lat, lon string) domain.NominatimResult {
+	return domain.NominatimResult{DisplayName: name, Lat: lat, Lon: lon, Importance: 0.8}
+}
+
+func TestFederate_MergesAndAnnotates(t *testing.T) {
+	providers := []Provider{
+		stubProvider{name: "baidu", results: []domain.NominatimResult{
+			place("清华大学, 北京市海淀区双清路30号", "40.0000", "116.3260"),
+			place("清华科技园, 海淀区", "39.9900", "116.3300"),
+		}},
+		stubProvider{name: "gaode", results: []domain.NominatimResult{
+			// 与百度的第一条相距约 100 米，名称相同
+			place("清华大学, 双清路30号", "40.0009", "116.3260"),
+			// 同名但在另一个城市，不应合并
+			place("清华大学, 深圳研究生院", "22.5900", "113.9700"),
+		}},
+		stubProvider{name: "tianmap", results: []domain.NominatimResult{
+			place("清华大学 (010-62793001)", "40.0003", "116.3262"),
+		}},
+	}
+
+	results, failures := Federate(context.Background(), providers, "清华大学", Options{}, config.FederatedSearchConfig{})
+	if len(failures) != 0 {
+		t.Fatalf("不应有失败: %v", failures)
+	}
+	if len(results) != 3 {
+		t.Fatalf("应合并为 3 条结果, 得到 %d: %+v", len(results), results)
+	}
+	top := results[0]
+	if top.Source != "baidu" || len(top.Sources) != 3 {
+		t.Errorf("三个搜索源都返回的地点应排在最前并标注来源, 得到 %s %v", top.Source, top.Sources)
+	}
+	if results[2].DisplayName != "清华科技园, 海淀区" {
+		t.Errorf("名称不匹配查询且只有一个来源的结果应排在最后, 得到 %q", results[2].DisplayName)
+	}
+
+	limited, _ := Federate(context.Background(), providers, "清华大学", Options{Limit: 1}, config.FederatedSearchConfig{})
+	if len(limited) != 1 {
+		t.Errorf("limit 应作用于合并后的结果, 得到 %d 条", len(limited))
+	}
+}
+
+func TestFederate_PartialResults(t *testing.T) {
+	providers := []Provider{
+		stubProvider{name: "baidu", err: errors.New("baidu api returned html")},
+		stubProvider{name: "slow", delay: time.Second, results: []domain.NominatimResult{place("慢", "1", "1")}},
+		stubProvider{name: "tianmap", results: []domain.NominatimResult{place("北京南站", "39.865", "116.379")}},
+	}
+
+	start := time.Now()
+	results, failures := Federate(context.Background(), providers, "北京南站", Options{}, config.FederatedSearchConfig{TimeoutMillis: 50})
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("慢速搜索源应在超时后放弃, 耗时 %v", elapsed)
+	}
+	if len(results) != 1 || results[0].Source != "tianmap" {
+		t.Errorf("应返回其余搜索源的结果: %+v", results)
+	}
+	failed := map[string]bool{}
+	for _, f := range failures {
+		failed[f.Provider] = true
+	}
+	if len(failures) != 2 || !failed["baidu"] || !failed["slow"] {
+		t.Errorf("应报告失败与超时的搜索源: %v", failures)
+	}
+}
+
+func TestSimilarNames(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want bool
+	}{
+		{"北京南站", "北京 南站", true},
+		{"首都国际机场", "北京首都国际机场", true},
+		{"Tsinghua University", "tsinghua university", true},
+		{"北京南站", "北京西站", false},
+		{"天安门", "故宫博物院", false},
+	} {
+		if got := similarNames(normalizeName(tc.a), normalizeName(tc.b)); got != tc.want {
+			t.Errorf("similarNames(%q, %q) = %v, 期望 %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
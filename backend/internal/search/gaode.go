@@ -0,0 +1,31 @@
+package search
+
+import (
+	"context"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/domain"
+	"github.com/chenxuan520/roadmap/backend/internal/search/gaode"
+)
+
+func init() {
+	Register("gaode", func(cfg config.SearchProviderConfig) Provider { return gaodeProvider{cfg} })
+}
+
+// gaodeProvider calls the AMap Web Service API and is enabled once a key is configured.
+type gaodeProvider struct {
+	cfg config.SearchProviderConfig
+}
+
+func (gaodeProvider) Name() string          { return "gaode" }
+func (gaodeProvider) Label() string         { return "高德" }
+func (p gaodeProvider) Enabled() bool       { return p.cfg.Key != "" && !p.cfg.Disabled }
+func (p gaodeProvider) LoginRequired() bool { return p.cfg.LoginRequired }
+
+func (p gaodeProvider) Search(ctx context.Context, query string, opts Options) ([]domain.NominatimResult, error) {
+	results, err := gaode.Search(ctx, query, p.cfg.Key)
+	if err == nil && !opts.GCJ02 {
+		results = gaode.ToWGS84(results)
+	}
+	return limit(results, opts), err
+}
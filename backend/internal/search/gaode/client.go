@@ -1,6 +1,7 @@
 package gaode
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,9 +11,15 @@ import (
 	"strings"
 	"time"
 
+	"github.com/chenxuan520/roadmap/backend/internal/coord"
 	"github.com/chenxuan520/roadmap/backend/internal/domain"
+	"github.com/chenxuan520/roadmap/backend/internal/logging"
+	"github.com/chenxuan520/roadmap/backend/internal/tracing"
 )
 
+// client is shared so repeated searches reuse connections to restapi.amap.com.
+var client = &http.Client{Timeout: 10 * time.Second}
+
 // GaodePOI defines the structure for a single Point of Interest from Gaode API.
 type GaodePOI struct {
 	Name     string      `json:"name"`
@@ -29,8 +36,9 @@ type GaodeResponse struct {
 	POIs   []GaodePOI `json:"pois"`
 }
 
-// Search performs a keyword search using the Gaode Web API.
-func Search(query string, apiKey string) ([]domain.NominatimResult, error) {
+// Search performs a keyword search using the Gaode Web API. Coordinates are returned in
+// GCJ-02 as the API reports them; see ToWGS84.
+func Search(ctx context.Context, query string, apiKey string) (results []domain.NominatimResult, err error) {
 	if query == "" {
 		return []domain.NominatimResult{}, nil
 	}
@@ -38,6 +46,14 @@ func Search(query string, apiKey string) ([]domain.NominatimResult, error) {
 		return nil, fmt.Errorf("gaode API key is missing")
 	}
 
+	ctx, span := tracing.Start(ctx, "gaode.Search", tracing.SpanKindClient,
+		tracing.String("search.provider", "gaode"), tracing.String("server.address", "restapi.amap.com"))
+	defer func() {
+		span.RecordError(err)
+		span.SetAttributes(tracing.Int("search.results", len(results)))
+		span.End()
+	}()
+
 	apiURL := "https://restapi.amap.com/v3/place/text"
 	u, err := url.Parse(apiURL)
 	if err != nil {
@@ -49,18 +65,22 @@ func Search(query string, apiKey string) ([]domain.NominatimResult, error) {
 	q.Set("key", apiKey)
 	u.RawQuery = q.Encode()
 
-	req, err := http.NewRequest("GET", u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 	req.Header.Set("User-Agent", "roadbook-backend/1.0")
+	tracing.Inject(ctx, req.Header)
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
+		logging.FromContext(ctx).Warn("search upstream request failed", "provider", "gaode", "latency_ms", time.Since(start).Milliseconds(), "error", err)
 		return nil, fmt.Errorf("gaode api request failed: %w", err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(tracing.Int("http.response.status_code", resp.StatusCode))
+	logging.FromContext(ctx).Debug("search upstream responded", "provider", "gaode", "status", resp.StatusCode, "latency_ms", time.Since(start).Milliseconds())
 
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("gaode api returned non-200 status: %d", resp.StatusCode)
@@ -85,7 +105,7 @@ func Search(query string, apiKey string) ([]domain.NominatimResult, error) {
 		return nil, fmt.Errorf("gaode api returned error status: %s - %s", gaodeResp.Status, gaodeResp.Info)
 	}
 
-	results := []domain.NominatimResult{}
+	results = []domain.NominatimResult{}
 	for _, poi := range gaodeResp.POIs {
 		parts := strings.Split(poi.Location, ",")
 		if len(parts) != 2 {
@@ -152,3 +172,28 @@ func Search(query string, apiKey string) ([]domain.NominatimResult, error) {
 
 	return results, nil
 }
+
+// ToWGS84 returns a copy of results from Search with the coordinates and bounding boxes
+// converted from GCJ-02 to WGS-84, the datum the other providers return. Results whose
+// coordinates cannot be parsed are dropped.
+func ToWGS84(results []domain.NominatimResult) []domain.NominatimResult {
+	converted := make([]domain.NominatimResult, 0, len(results))
+	for _, res := range results {
+		lng, lngErr := strconv.ParseFloat(res.Lon, 64)
+		lat, latErr := strconv.ParseFloat(res.Lat, 64)
+		if lngErr != nil || latErr != nil {
+			continue
+		}
+		lng, lat = coord.ConvertGCJToGPS(lng, lat)
+		res.Lat = fmt.Sprintf("%.7f", lat)
+		res.Lon = fmt.Sprintf("%.7f", lng)
+		res.BoundingBox = []string{
+			fmt.Sprintf("%.7f", lat-0.001),
+			fmt.Sprintf("%.7f", lat+0.001),
+			fmt.Sprintf("%.7f", lng-0.001),
+			fmt.Sprintf("%.7f", lng+0.001),
+		}
+		converted = append(converted, res)
+	}
+	return converted
+}
@@ -0,0 +1,33 @@
+package gaode
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/chenxuan520/roadmap/backend/internal/domain"
+)
+
+func TestToWGS84(t *testing.T) {
+	// 天安门: 高德返回 GCJ-02 坐标，对应的 WGS-84 坐标为 116.397477,39.908692
+	results := []domain.NominatimResult{
+		{DisplayName: "天安门", Lat: "39.910092", Lon: "116.403719"},
+		{DisplayName: "坐标非法", Lat: "abc", Lon: "116.403719"},
+	}
+	converted := ToWGS84(results)
+	if len(converted) != 1 {
+		t.Fatalf("坐标非法的结果应被丢弃, 得到 %d 条", len(converted))
+	}
+	lat, _ := strconv.ParseFloat(converted[0].Lat, 64)
+	lng, _ := strconv.ParseFloat(converted[0].Lon, 64)
+	// 0.00005 度约 5 米
+	if math.Abs(lng-116.397477) > 0.00005 || math.Abs(lat-39.908692) > 0.00005 {
+		t.Errorf("应转换为 WGS-84 坐标, 得到 %s,%s", converted[0].Lon, converted[0].Lat)
+	}
+	if len(converted[0].BoundingBox) != 4 {
+		t.Errorf("应按转换后的坐标重新计算 BoundingBox, 得到 %v", converted[0].BoundingBox)
+	}
+	if results[0].Lat != "39.910092" || results[0].Lon != "116.403719" {
+		t.Error("不应修改传入的结果")
+	}
+}
@@ -0,0 +1,235 @@
+// Package search puts the place search backends behind a common interface. Each provider
+// lives in a single file of this package that registers itself from init, so adding a
+// provider does not touch the handlers, the routes or the provider list.
+package search
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/domain"
+	"github.com/chenxuan520/roadmap/backend/internal/metrics"
+)
+
+// Options tunes a single search call.
+type Options struct {
+	// Limit caps the number of results. 0 keeps every result the upstream returned.
+	Limit int
+	// GCJ02 keeps GCJ-02 coordinates from providers whose upstream reports them, instead of
+	// converting to WGS-84. Only the legacy per-provider routes set it, for clients that
+	// already convert Gaode results themselves.
+	GCJ02 bool
+}
+
+// Provider is a place search backend returning Nominatim-style results.
+type Provider interface {
+	// Name is the stable identifier used in ?provider= and in metrics, e.g. "gaode".
+	Name() string
+	// Label is the display name shown in the frontend, e.g. "高德".
+	Label() string
+	// Enabled reports whether the provider is configured and may be called.
+	Enabled() bool
+	// LoginRequired reports whether anonymous requests must be rejected.
+	LoginRequired() bool
+	Search(ctx context.Context, query string, opts Options) ([]domain.NominatimResult, error)
+}
+
+// Factory builds a provider from its entry under search.providers.
+type Factory func(cfg config.SearchProviderConfig) Provider
+
+type registration struct {
+	name    string
+	factory Factory
+}
+
+var (
+	registryMu    sync.Mutex
+	registrations []registration
+)
+
+// Register makes a provider available to every registry. It is meant to be called from
+// init; providers are listed in registration order. Registering a name twice, or the
+// reserved name "all", panics. The name also becomes a valid key under search.providers,
+// with matching environment variables and flags.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if name == AllProviders {
+		panic(fmt.Sprintf("search: provider name %q is reserved", name))
+	}
+	for _, r := range registrations {
+		if r.name == name {
+			panic(fmt.Sprintf("search: provider %q registered twice", name))
+		}
+	}
+	registrations = append(registrations, registration{name: name, factory: factory})
+	config.RegisterSearchProvider(name)
+}
+
+// Registry holds the providers built from the active config. Every provider sits behind
+// the shared result cache and its own circuit breaker, and its upstream calls are
+// recorded in metrics.
+type Registry struct {
+	registrations []registration
+	breakers      map[string]*breaker
+	cache         *Cache // nil when search.cache.disabled is set
+	state         atomic.Pointer[registryState]
+}
+
+type registryState struct {
+	providers []Provider
+	federated config.FederatedSearchConfig
+	fallback  []string
+}
+
+// NewRegistry builds every registered provider from cfg. It fails when search.fallback
+// names a provider that is not registered or the cache file cannot be read.
+func NewRegistry(cfg config.SearchConfig) (*Registry, error) {
+	registryMu.Lock()
+	regs := append([]registration(nil), registrations...)
+	registryMu.Unlock()
+	return newRegistry(cfg, regs)
+}
+
+func newRegistry(cfg config.SearchConfig, regs []registration) (*Registry, error) {
+	r := &Registry{registrations: regs, breakers: make(map[string]*breaker, len(regs))}
+	for _, reg := range regs {
+		r.breakers[reg.name] = newBreaker(cfg.CircuitBreaker)
+	}
+	if !cfg.Cache.Disabled {
+		cache, err := NewCache(cfg.Cache)
+		if err != nil {
+			return nil, err
+		}
+		r.cache = cache
+	}
+	state, err := r.build(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r.state.Store(state)
+	return r, nil
+}
+
+func (r *Registry) build(cfg config.SearchConfig) (*registryState, error) {
+	state := &registryState{federated: cfg.Federated, fallback: cfg.Fallback}
+	for _, reg := range r.registrations {
+		var p Provider = guarded{Provider: observed{reg.factory(cfg.Providers.Get(reg.name))}, breaker: r.breakers[reg.name]}
+		if r.cache != nil {
+			p = cached{Provider: p, cache: r.cache}
+		}
+		state.providers = append(state.providers, p)
+	}
+	for _, name := range cfg.Fallback {
+		if _, ok := r.breakers[name]; !ok {
+			return nil, fmt.Errorf("search.fallback: unknown provider %q", name)
+		}
+	}
+	for name := range cfg.Providers {
+		if _, ok := r.breakers[name]; !ok {
+			return nil, fmt.Errorf("search.providers: unknown provider %q", name)
+		}
+	}
+	return state, nil
+}
+
+// Providers returns every registered provider, enabled or not, in registration order.
+func (r *Registry) Providers() []Provider {
+	return r.state.Load().providers
+}
+
+// Federated returns the settings for provider=all.
+func (r *Registry) Federated() config.FederatedSearchConfig {
+	return r.state.Load().federated
+}
+
+// Chain returns the provider with the given name followed by the providers to fail over
+// to, in search.fallback order. When name is part of the chain only the providers after it
+// are fallbacks; otherwise the whole chain is.
+func (r *Registry) Chain(name string) []Provider {
+	first, ok := r.Get(name)
+	if !ok {
+		return nil
+	}
+	fallback := r.state.Load().fallback
+	for i, n := range fallback {
+		if n == name {
+			fallback = fallback[i+1:]
+			break
+		}
+	}
+	chain := []Provider{first}
+	for _, n := range fallback {
+		if p, ok := r.Get(n); ok {
+			chain = append(chain, p)
+		}
+	}
+	return chain
+}
+
+// Circuit reports the breaker state of the named provider.
+func (r *Registry) Circuit(name string) CircuitStatus {
+	if b, ok := r.breakers[name]; ok {
+		return b.status()
+	}
+	return CircuitStatus{State: CircuitClosed}
+}
+
+// Get returns the provider with the given name.
+func (r *Registry) Get(name string) (Provider, bool) {
+	for _, p := range r.Providers() {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// PrepareReload implements config.ReloadHook: providers are rebuilt from the new config,
+// so keys and flags follow config reloads. Breaker state carries over.
+func (r *Registry) PrepareReload(cfg *config.Config) (func(), error) {
+	state, err := r.build(cfg.Search)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		for _, b := range r.breakers {
+			b.configure(cfg.Search.CircuitBreaker)
+		}
+		r.state.Store(state)
+	}, nil
+}
+
+// Close saves the result cache when search.cache.file is set.
+func (r *Registry) Close() error {
+	if r.cache == nil {
+		return nil
+	}
+	return r.cache.Save()
+}
+
+// observed records the outcome and latency of every upstream call in metrics. It sits
+// behind the cache and the breaker, so only calls that reach the upstream are counted.
+type observed struct {
+	Provider
+}
+
+func (p observed) Search(ctx context.Context, query string, opts Options) ([]domain.NominatimResult, error) {
+	start := time.Now()
+	results, err := p.Provider.Search(ctx, query, opts)
+	metrics.SearchRequests.Inc(p.Name(), metrics.Result(err))
+	metrics.SearchDuration.Observe(time.Since(start).Seconds(), p.Name())
+	return results, err
+}
+
+// limit truncates results to opts.Limit.
+func limit(results []domain.NominatimResult, opts Options) []domain.NominatimResult {
+	if opts.Limit > 0 && len(results) > opts.Limit {
+		return results[:opts.Limit]
+	}
+	return results
+}
@@ -0,0 +1,104 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/domain"
+)
+
+func TestNewRegistry_BuiltinProviders(t *testing.T) {
+	r, err := NewRegistry(config.SearchConfig{Providers: config.SearchProviders{
+		"baidu": {Disabled: true},
+		"gaode": {LoginRequired: true},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enabled := make(map[string]bool)
+	for _, p := range r.Providers() {
+		enabled[p.Name()] = p.Enabled()
+		if p.Label() == "" {
+			t.Errorf("%s: 缺少显示名称", p.Name())
+		}
+	}
+	if len(enabled) != 3 || enabled["baidu"] || enabled["gaode"] || !enabled["tianmap"] {
+		t.Errorf("启用状态错误: %v", enabled)
+	}
+	if gaode, _ := r.Get("gaode"); !gaode.LoginRequired() {
+		t.Error("gaode 应要求登录")
+	}
+	if _, ok := r.Get("nope"); ok {
+		t.Error("未注册的搜索源不应存在")
+	}
+
+	// 重新加载后使用新配置
+	commit, err := r.PrepareReload(&config.Config{Search: config.SearchConfig{Providers: config.SearchProviders{
+		"gaode": {Key: "k"},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gaode, _ := r.Get("gaode"); gaode.Enabled() {
+		t.Error("提交前不应生效")
+	}
+	commit()
+	if gaode, _ := r.Get("gaode"); !gaode.Enabled() || gaode.LoginRequired() {
+		t.Error("提交后应使用新配置")
+	}
+}
+
+// fakeProvider 返回固定数量的结果
+type fakeProvider struct {
+	name string
+	cfg  config.SearchProviderConfig
+}
+
+func (p fakeProvider) Name() string        { return p.name }
+func (p fakeProvider) Label() string       { return p.name }
+func (p fakeProvider) Enabled() bool       { return p.cfg.Key != "" }
+func (p fakeProvider) LoginRequired() bool { return false }
+func (p fakeProvider) Search(ctx context.Context, query string, opts Options) ([]domain.NominatimResult, error) {
+	return limit(make([]domain.NominatimResult, 5), opts), nil
+}
+
+func TestRegistry_Order(t *testing.T) {
+	factory := func(name string) Factory {
+		return func(cfg config.SearchProviderConfig) Provider { return fakeProvider{name, cfg} }
+	}
+	r, err := newRegistry(config.SearchConfig{Providers: config.SearchProviders{"tianmap": {Key: "k"}}},
+		[]registration{{"tianmap", factory("tianmap")}, {"custom", factory("custom")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	providers := r.Providers()
+	if len(providers) != 2 || providers[0].Name() != "tianmap" || providers[1].Name() != "custom" {
+		t.Fatalf("应按注册顺序列出: %v", providers)
+	}
+	if !providers[0].Enabled() || providers[1].Enabled() {
+		t.Error("工厂应收到同名的配置，没有配置项的搜索源收到零值")
+	}
+	if results, _ := providers[0].Search(context.Background(), "q", Options{Limit: 2}); len(results) != 2 {
+		t.Errorf("limit 未生效, 得到 %d 条", len(results))
+	}
+
+	// 新的搜索源只需注册，配置项按名称查找；未注册的名称视为拼写错误
+	if _, err := r.PrepareReload(&config.Config{Search: config.SearchConfig{Providers: config.SearchProviders{"custom": {Key: "k"}}}}); err != nil {
+		t.Errorf("已注册的搜索源应能配置: %v", err)
+	}
+	if _, err := r.PrepareReload(&config.Config{Search: config.SearchConfig{Providers: config.SearchProviders{"gaode": {Key: "k"}}}}); err == nil {
+		t.Error("未注册的搜索源配置应拒绝重新加载")
+	}
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("重复注册应 panic")
+		}
+	}()
+	Register("baidu", nil)
+}
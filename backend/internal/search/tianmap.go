@@ -0,0 +1,29 @@
+package search
+
+import (
+	"context"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/chenxuan520/roadmap/backend/internal/domain"
+	"github.com/chenxuan520/roadmap/backend/internal/search/tianmap"
+)
+
+func init() {
+	Register("tianmap", func(cfg config.SearchProviderConfig) Provider { return tianmapProvider{cfg} })
+}
+
+// tianmapProvider calls the Tianditu search API, using the public browser key unless one
+// is configured.
+type tianmapProvider struct {
+	cfg config.SearchProviderConfig
+}
+
+func (tianmapProvider) Name() string          { return "tianmap" }
+func (tianmapProvider) Label() string         { return "天地图" }
+func (p tianmapProvider) Enabled() bool       { return !p.cfg.Disabled }
+func (p tianmapProvider) LoginRequired() bool { return p.cfg.LoginRequired }
+
+func (p tianmapProvider) Search(ctx context.Context, query string, opts Options) ([]domain.NominatimResult, error) {
+	results, err := tianmap.Search(ctx, query, p.cfg.Key)
+	return limit(results, opts), err
+}
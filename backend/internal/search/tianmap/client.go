@@ -1,6 +1,7 @@
 package tianmap
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,14 +12,35 @@ import (
 	"time"
 
 	"github.com/chenxuan520/roadmap/backend/internal/domain"
+	"github.com/chenxuan520/roadmap/backend/internal/logging"
+	"github.com/chenxuan520/roadmap/backend/internal/tracing"
 )
 
-func Search(query string) ([]domain.NominatimResult, error) {
+// client is reused across calls; keystroke-driven searches would otherwise open a connection each time.
+var client = &http.Client{Timeout: 10 * time.Second}
+
+// DefaultKey is the public browser key used by map.tianditu.gov.cn.
+const DefaultKey = "75f0434f240669f4a2df6359275146d2"
+
+// Search queries the Tianditu search API. ctx carries the request-scoped logger
+// and cancels the upstream call when the client goes away. An empty key uses DefaultKey.
+func Search(ctx context.Context, query string, key string) (results []domain.NominatimResult, err error) {
 	if query == "" {
 		return []domain.NominatimResult{}, nil
 	}
 
-	tk := "75f0434f240669f4a2df6359275146d2"
+	ctx, span := tracing.Start(ctx, "tianmap.Search", tracing.SpanKindClient,
+		tracing.String("search.provider", "tianmap"), tracing.String("server.address", "api.tianditu.gov.cn"))
+	defer func() {
+		span.RecordError(err)
+		span.SetAttributes(tracing.Int("search.results", len(results)))
+		span.End()
+	}()
+
+	tk := key
+	if tk == "" {
+		tk = DefaultKey
+	}
 	postData := map[string]interface{}{
 		"keyWord":       query,
 		"level":         "11",
@@ -40,17 +62,21 @@ func Search(query string) ([]domain.NominatimResult, error) {
 	q.Set("tk", tk)
 	u.RawQuery = q.Encode()
 
-	req, _ := http.NewRequest("GET", u.String(), nil)
+	req, _ := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
 	req.Header.Set("Referer", "https://map.tianditu.gov.cn/")
 	req.Header.Set("Origin", "https://map.tianditu.gov.cn")
+	tracing.Inject(ctx, req.Header)
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
+		logging.FromContext(ctx).Warn("search upstream request failed", "provider", "tianmap", "latency_ms", time.Since(start).Milliseconds(), "error", err)
 		return nil, fmt.Errorf("upstream error: %w", err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(tracing.Int("http.response.status_code", resp.StatusCode))
+	logging.FromContext(ctx).Debug("search upstream responded", "provider", "tianmap", "status", resp.StatusCode, "latency_ms", time.Since(start).Milliseconds())
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -67,7 +93,7 @@ func Search(query string) ([]domain.NominatimResult, error) {
 		return nil, fmt.Errorf("failed to decode tianmap response: %w. Raw data: %s", err, displayBody)
 	}
 
-	results := []domain.NominatimResult{}
+	results = []domain.NominatimResult{}
 
 	if pois, ok := tianResp["pois"].([]interface{}); ok {
 		for i, p := range pois {
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+)
+
+// config.ServerConfig 中未设置（为 0）的字段使用以下默认值
+const (
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultReadTimeout       = 60 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+	defaultShutdownTimeout   = 30 * time.Second
+)
+
+// Server 管理 HTTP 服务以及需要在进行中的请求结束后才停止的后台组件
+type Server struct {
+	httpServer      *http.Server
+	redirectServer  *http.Server // 可选的 HTTP 监听，把请求重定向到 HTTPS
+	shutdownTimeout time.Duration
+	onShutdown      []func(context.Context) error
+}
+
+// New 创建路由以及使用配置超时的 http.Server。监听相关的配置只在此时从 store 读取一次，修改后需要重启。
+func New(store *config.Store) *Server {
+	cfg := *store.Current()
+	s := &Server{shutdownTimeout: seconds(cfg.Server.ShutdownTimeoutSeconds, defaultShutdownTimeout)}
+	s.httpServer = &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           newRouter(store, s),
+		ReadHeaderTimeout: seconds(cfg.Server.ReadHeaderTimeoutSeconds, defaultReadHeaderTimeout),
+		ReadTimeout:       seconds(cfg.Server.ReadTimeoutSeconds, defaultReadTimeout),
+		WriteTimeout:      seconds(cfg.Server.WriteTimeoutSeconds, 0),
+		IdleTimeout:       seconds(cfg.Server.IdleTimeoutSeconds, defaultIdleTimeout),
+	}
+	if cfg.TLS.Enabled {
+		if err := s.configureTLS(cfg.TLS, cfg.Port); err != nil {
+			log.Fatalf("TLS 配置错误: %v", err)
+		}
+	}
+	return s
+}
+
+// seconds 把以秒为单位的配置转换为 time.Duration，未设置时返回 def
+func seconds(n int, def time.Duration) time.Duration {
+	if n <= 0 {
+		return def
+	}
+	return time.Duration(n) * time.Second
+}
+
+// Handler 返回路由，主要用于测试
+func (s *Server) Handler() http.Handler {
+	return s.httpServer.Handler
+}
+
+// OnShutdown 登记在 HTTP 服务停止接受请求、进行中的请求结束后执行的函数，按登记的相反顺序执行
+func (s *Server) OnShutdown(fn func(context.Context) error) {
+	s.onShutdown = append(s.onShutdown, fn)
+}
+
+// Run 持续提供服务直到 ctx 被取消（通常由 SIGINT/SIGTERM 触发），随后停止接受新连接，
+// 在退出超时内等待保存计划、AI 流式对话等进行中的请求完成，强制关闭剩余连接，最后停止后台组件。
+// 任一监听失败时其余监听也会关闭，并返回该错误。
+func (s *Server) Run(ctx context.Context) error {
+	servers := []*http.Server{s.httpServer}
+	if s.redirectServer != nil {
+		servers = append(servers, s.redirectServer)
+	}
+
+	errCh := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func(srv *http.Server) {
+			if srv.TLSConfig != nil {
+				slog.Info("HTTPS 服务已启动", "addr", srv.Addr)
+				// 证书由 TLSConfig.GetCertificate 提供
+				errCh <- srv.ListenAndServeTLS("", "")
+				return
+			}
+			slog.Info("HTTP 服务已启动", "addr", srv.Addr)
+			errCh <- srv.ListenAndServe()
+		}(srv)
+	}
+
+	var errs []error
+	pending := len(servers)
+	select {
+	case err := <-errCh:
+		// 收到退出信号之前就有监听失败
+		errs = append(errs, err)
+		pending--
+	case <-ctx.Done():
+		slog.Info("收到退出信号，等待进行中的请求完成", "timeout", s.shutdownTimeout.String())
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			// 超时后仍有请求未完成，直接断开
+			slog.Warn("等待超时，强制关闭剩余连接", "addr", srv.Addr, "error", err)
+			srv.Close()
+		}
+	}
+	for ; pending > 0; pending-- {
+		if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+			errs = append(errs, err)
+		}
+	}
+
+	// 后台组件单独计算超时，请求排空较慢时也不会跳过它们
+	componentCtx, cancelComponents := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancelComponents()
+	errs = append(errs, s.stopComponents(componentCtx))
+	slog.Info("服务已停止")
+	return errors.Join(errs...)
+}
+
+func (s *Server) stopComponents(ctx context.Context) error {
+	var errs []error
+	for i := len(s.onShutdown) - 1; i >= 0; i-- {
+		if err := s.onShutdown[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer 在空闲端口上创建一个使用 handler 的 Server
+func newTestServer(t *testing.T, handler http.Handler, shutdownTimeout time.Duration) (*Server, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return &Server{
+		httpServer:      &http.Server{Addr: addr, Handler: handler},
+		shutdownTimeout: shutdownTimeout,
+	}, "http://" + addr
+}
+
+// waitReady 等待服务开始监听
+func waitReady(t *testing.T, url string) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if resp, err := http.Get(url + "/ready"); err == nil {
+			resp.Body.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("服务未能启动")
+}
+
+func TestServer_DrainsInFlightRequests(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "saved")
+	})
+	s, url := newTestServer(t, mux, 5*time.Second)
+
+	var requestDone, stoppedAfterRequest atomic.Bool
+	s.OnShutdown(func(context.Context) error {
+		stoppedAfterRequest.Store(requestDone.Load())
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.Run(ctx) }()
+	waitReady(t, url)
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow")
+		if err != nil {
+			body <- "error: " + err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		requestDone.Store(true)
+		body <- string(b)
+	}()
+	<-started
+	cancel()
+
+	// 退出开始后不再接受新连接
+	time.Sleep(50 * time.Millisecond)
+	if _, err := http.Get(url + "/ready"); err == nil {
+		t.Error("退出过程中不应接受新请求")
+	}
+
+	close(release)
+	if got := <-body; got != "saved" {
+		t.Fatalf("进行中的请求应正常完成, 得到 %q", got)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("Run 返回错误: %v", err)
+	}
+	if !stoppedAfterRequest.Load() {
+		t.Error("后台组件应在进行中的请求完成后才停止")
+	}
+}
+
+func TestServer_ForceClosesAfterTimeout(t *testing.T) {
+	started := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/stuck", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done() // 模拟永不结束的流式响应
+	})
+	s, url := newTestServer(t, mux, 100*time.Millisecond)
+	var stopped atomic.Bool
+	s.OnShutdown(func(context.Context) error {
+		stopped.Store(true)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.Run(ctx) }()
+	waitReady(t, url)
+
+	go http.Get(url + "/stuck")
+	<-started
+	cancel()
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatalf("超时强制关闭不应视为错误: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("超过退出时限后应强制关闭连接")
+	}
+	if !stopped.Load() {
+		t.Error("强制关闭后仍应停止后台组件")
+	}
+}
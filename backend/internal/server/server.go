@@ -1,96 +1,162 @@
 package server
 
 import (
+	"context"
 	// 导入 log 包用于错误处理
 	"log"
+	"log/slog"
+	"time"
 
 	"github.com/chenxuan520/roadmap/backend/internal/auth"
 	"github.com/chenxuan520/roadmap/backend/internal/config"
 	"github.com/chenxuan520/roadmap/backend/internal/handler"
+	"github.com/chenxuan520/roadmap/backend/internal/metrics"
 	"github.com/chenxuan520/roadmap/backend/internal/middleware"
+	"github.com/chenxuan520/roadmap/backend/internal/oidc"
 	"github.com/chenxuan520/roadmap/backend/internal/plan"
+	"github.com/chenxuan520/roadmap/backend/internal/ratelimit"
+	"github.com/chenxuan520/roadmap/backend/internal/search"
+	"github.com/chenxuan520/roadmap/backend/internal/token"
+	"github.com/chenxuan520/roadmap/backend/internal/twofactor"
+	"github.com/chenxuan520/roadmap/backend/internal/web"
 	"github.com/gin-gonic/gin"
 )
 
-func NewRouter(cfg config.Config) *gin.Engine {
-	r := gin.Default()
+// defaultTrustedProxies 未配置 trusted_proxies 时只信任本机，对应镜像中内置的 nginx
+var defaultTrustedProxies = []string{"127.0.0.1", "::1"}
 
-	// Secure CORS Middleware
-	r.Use(func(c *gin.Context) {
-		origin := c.Request.Header.Get("Origin")
-		isAllowed := false
-
-		// Check standard allowed origins
-		for _, allowedOrigin := range cfg.AllowedOrigins {
-			if origin == allowedOrigin {
-				isAllowed = true
-				break
-			}
-		}
-
-		// If not in standard list, check for dev-mode null origin
-		if !isAllowed && cfg.AllowNullOriginForDev && origin == "null" {
-			isAllowed = true
-		}
-
-		if isAllowed {
-			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
-			c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		}
-
-		if c.Request.Method == "OPTIONS" {
-			if isAllowed {
-				c.AbortWithStatus(204)
-			} else {
-				c.AbortWithStatus(403)
-			}
-			return
-		}
+// newRouter 创建路由并注册全部中间件与处理器，需要在退出时释放的资源登记到 s。
+// 中间件链与后台组件按启动时的配置创建；CORS、搜索、AI 与用户相关的配置在每次请求时从 store 读取，
+// 支持热加载。
+func newRouter(store *config.Store, s *Server) *gin.Engine {
+	cfg := *store.Current()
+	r := gin.New()
+	if err := configureClientIP(r, cfg); err != nil {
+		log.Fatalf("trusted_proxies 配置错误: %v", err)
+	}
+	// 结构化访问日志放在最前，使 CORS 拒绝与 panic 也带有请求ID
+	r.Use(middleware.RequestLogger())
+	if cfg.Tracing.Enabled {
+		// 位于 Recovery 之前，panic 转成的 500 也会记录在 span 上
+		r.Use(middleware.Tracing())
+	}
+	// Metrics 在 Recovery 之外，panic 转成的 500 同样计入请求数与耗时
+	r.Use(middleware.Metrics(), middleware.Recovery())
 
-		c.Next()
-	})
+	// 来源白名单在每次请求时读取，支持热加载
+	r.Use(middleware.CORS(store.Current))
+	if cfg.Compression.Enabled {
+		r.Use(middleware.Compress(cfg.Compression))
+	}
 
 	// 初始化服务和处理器
-	authService := auth.NewService(cfg)
+	authService, err := auth.NewService(cfg)
+	if err != nil {
+		log.Fatalf("初始化认证服务失败: %v", err)
+	}
+	if reloader, ok := authService.(auth.Reloader); ok {
+		// 新配置中的密钥或用户无效时拒绝整个重新加载
+		store.OnReload(reloader.PrepareReload)
+	}
 	planRepo, err := plan.NewFileRepository()
 	if err != nil {
 		log.Fatalf("初始化计划仓库失败: %v", err) // 如果仓库初始化失败，则终止应用
 	}
+	planRepo = plan.WithMetrics(planRepo)
+	tokenRepo, err := token.NewFileRepository()
+	if err != nil {
+		log.Fatalf("初始化令牌仓库失败: %v", err)
+	}
+	tokenService := token.NewService(tokenRepo)
+	twoFactorRepo, err := twofactor.NewFileRepository()
+	if err != nil {
+		log.Fatalf("初始化两步验证仓库失败: %v", err)
+	}
+	twoFactorService := twofactor.NewService(twoFactorRepo)
 
-	authHandler := handler.NewAuthHandler(authService)
+	authHandler := handler.NewAuthHandler(authService, auth.NewLoginGuard(cfg.LoginProtection), twoFactorService)
 	planHandler := handler.NewPlanHandler(planRepo)
-	searchHandlers := handler.NewSearchHandlers(cfg) // Create search handlers instance
+	tokenHandler := handler.NewTokenHandler(tokenService)
+	twoFactorHandler := handler.NewTwoFactorHandler(twoFactorService)
+	adminHandler := handler.NewAdminHandler(store, authService, tokenService, twoFactorService)
+	searchRegistry, err := search.NewRegistry(cfg.Search)
+	if err != nil {
+		log.Fatalf("初始化搜索源失败: %v", err)
+	}
+	store.OnReload(searchRegistry.PrepareReload)
+	s.OnShutdown(func(context.Context) error { return searchRegistry.Close() })
+	searchHandlers := handler.NewSearchHandlers(searchRegistry)
+	healthHandler := handler.NewHealthHandler(store)
+	rateLimitStore := newRateLimitStore(cfg.RateLimit)
+	if closer, ok := rateLimitStore.(interface{ Close() }); ok {
+		s.OnShutdown(func(context.Context) error {
+			closer.Close()
+			return nil
+		})
+	}
+	rateLimits, err := middleware.NewRateLimits(cfg.RateLimit, rateLimitStore)
+	if err != nil {
+		log.Fatalf("初始化限流策略失败: %v", err)
+	}
 
 	// API v1 路由组
 	v1 := r.Group("/api/v1")
 	{
 		// 认证接口
-		v1.POST("/login", middleware.RateLimitMiddleware(), authHandler.LoginHandler) // 登录接口应用IP限流
+		v1.POST("/login", rateLimits.For("login"), authHandler.LoginHandler) // 登录接口应用IP限流
+		v1.POST("/login/2fa", rateLimits.For("login"), authHandler.LoginTwoFactorHandler)
+
+		// OpenID Connect 登录 (可选)
+		if cfg.OIDC.Enabled {
+			oidcHandler := handler.NewOIDCHandler(oidc.NewClient(cfg.OIDC), authService, store)
+			v1.GET("/oidc/login", rateLimits.For("login"), oidcHandler.LoginHandler)
+			v1.GET("/oidc/callback", oidcHandler.CallbackHandler)
+		}
 
 		// 计划分享接口 (无需认证)
 		share := v1.Group("/share")
 		{
-			share.GET("/plans/:id", planHandler.SharePlanHandler)
+			share.GET("/plans/:id", rateLimits.For("share"), middleware.ETag("no-cache"), planHandler.SharePlanHandler)
 		}
 
 		// 需要JWT认证的计划管理接口
+		// 角色要求：readonly 只能查看计划；修改计划与AI助手需要 member；/admin 需要 admin
 		authenticated := v1.Group("/")
-		authenticated.Use(middleware.JWTAuthMiddleware(authService))
+		authenticated.Use(middleware.JWTAuthMiddleware(authService, tokenService))
 		{
+			writer := middleware.RequireRole(auth.RoleMember)
 			authenticated.POST("/refresh", authHandler.RefreshHandler)
-			authenticated.POST("/plans", planHandler.CreatePlanHandler)
-			authenticated.GET("/plans", planHandler.ListPlansHandler)
-			authenticated.GET("/plans/:id", planHandler.GetPlanHandler)
-			authenticated.PUT("/plans/:id", planHandler.SavePlanHandler)
-			authenticated.DELETE("/plans/:id", planHandler.DeletePlanHandler)
-			
+			authenticated.POST("/plans", writer, middleware.RequireScope(token.ScopePlansWrite), planHandler.CreatePlanHandler)
+			authenticated.GET("/plans", middleware.RequireScope(token.ScopePlansRead), planHandler.ListPlansHandler)
+			authenticated.GET("/plans/:id", middleware.RequireScope(token.ScopePlansRead), middleware.ETag("private, no-cache"), planHandler.GetPlanHandler)
+			authenticated.PUT("/plans/:id", writer, middleware.RequireScope(token.ScopePlansWrite), planHandler.SavePlanHandler)
+			authenticated.DELETE("/plans/:id", writer, middleware.RequireScope(token.ScopePlansWrite), planHandler.DeletePlanHandler)
+
+			// 个人访问令牌管理（仅限登录会话）
+			authenticated.GET("/tokens", tokenHandler.ListTokensHandler)
+			authenticated.POST("/tokens", tokenHandler.CreateTokenHandler)
+			authenticated.DELETE("/tokens/:id", tokenHandler.RevokeTokenHandler)
+
+			// 两步验证管理（仅限登录会话）
+			authenticated.GET("/2fa", twoFactorHandler.StatusHandler)
+			authenticated.POST("/2fa/enroll", twoFactorHandler.EnrollHandler)
+			authenticated.POST("/2fa/activate", twoFactorHandler.ActivateHandler)
+			authenticated.POST("/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodesHandler)
+			authenticated.POST("/2fa/disable", twoFactorHandler.DisableHandler)
+
 			// AI routes
-			authenticated.GET("/ai/config", handler.GetAIConfig(&cfg))
-			authenticated.GET("/ai/session", handler.GetAISession)
-			authenticated.POST("/ai/session", handler.SaveAISession)
-			authenticated.POST("/ai/chat", handler.AIChat(&cfg))
+			ai := authenticated.Group("/ai", writer, middleware.RequireScope(token.ScopeAI))
+			ai.GET("/config", handler.GetAIConfig(store))
+			ai.GET("/session", handler.GetAISession)
+			ai.POST("/session", handler.SaveAISession)
+			ai.POST("/chat", rateLimits.For("ai"), handler.AIChat(store)) // AI对话按用户限流
+
+			// 管理员接口
+			admin := authenticated.Group("/admin", middleware.RequireRole(auth.RoleAdmin))
+			admin.GET("/users", adminHandler.ListUsersHandler)
+			admin.DELETE("/users/:username/tokens", adminHandler.RevokeUserTokensHandler)
+			admin.POST("/users/:username/2fa/reset", adminHandler.ResetTwoFactorHandler)
+			admin.GET("/diagnostics", adminHandler.DiagnosticsHandler)
 		}
 
 		// 现有cnmap/tianmap搜索接口
@@ -107,23 +173,87 @@ func NewRouter(cfg config.Config) *gin.Engine {
 	{
 		api.GET("/ping", handler.Ping)
 		api.HEAD("/ping", handler.Ping)
-		api.GET("/cnmap/search", searchHandlers.BaiduSearchHandler)
-		api.GET("/tianmap/search", searchHandlers.TianmapSearchHandler)
-		api.GET("/gaode/search", applyAuthMiddleware(cfg.Search.Providers.Gaode.LoginRequired, authService, searchHandlers.GaodeSearchHandler)...)
+		// 存活与就绪检查，供容器编排与负载均衡使用
+		api.GET("/healthz", healthHandler.LivenessHandler)
+		api.HEAD("/healthz", healthHandler.LivenessHandler)
+		api.GET("/readyz", healthHandler.ReadinessHandler)
+		searchLimit := rateLimits.For("search")
+		// 统一搜索接口，搜索源由 provider 参数指定
+		api.GET("/search", applyAuthMiddleware(searchHandlers.SearchLoginRequired(""), authService, tokenService, searchLimit, searchHandlers.SearchHandler)...)
+		// 兼容原有的按搜索源划分的路径
+		for path, provider := range map[string]string{"/cnmap/search": "baidu", "/tianmap/search": "tianmap", "/gaode/search": "gaode"} {
+			api.GET(path, applyAuthMiddleware(searchHandlers.SearchLoginRequired(provider), authService, tokenService, searchLimit, searchHandlers.ProviderSearchHandler(provider))...)
+		}
 		api.GET("/search/providers", searchHandlers.GetSearchProvidersHandler)
-		// 新增trafficpos接口
-		api.GET("/trafficpos", handler.GetTrafficPos)
+		// 公开验签公钥，供 Cloudflare Worker 等服务校验 roadbook 令牌
+		api.GET("/.well-known/jwks.json", authHandler.JWKSHandler)
+		// 新增trafficpos接口，机场与车站数据只在启动时加载，结果可以缓存
+		api.GET("/trafficpos", middleware.ETag("public, max-age=3600"), handler.GetTrafficPos)
+	}
+
+	// Prometheus 指标。不在 /api 下，默认不经由 nginx 对外暴露，供监控系统直接抓取后端端口
+	if cfg.Metrics.Enabled {
+		r.GET("/metrics", handler.MetricsHandler(metrics.Default, cfg.Metrics.Token))
+	}
+
+	// 前端页面。注册为 NoRoute，API 路由始终优先
+	if cfg.Static.Enabled {
+		static, err := web.Handler(cfg.Static)
+		if err != nil {
+			log.Fatalf("初始化前端静态文件失败: %v", err)
+		}
+		r.NoRoute(static)
 	}
 
 	return r
 }
 
-// applyAuthMiddleware conditionally applies JWTAuthMiddleware if loginRequired is true,
-// returning a HandlersChain suitable for gin.
-func applyAuthMiddleware(loginRequired bool, authService auth.Authenticator, handler gin.HandlerFunc) gin.HandlersChain {
-	if loginRequired {
-		return gin.HandlersChain{middleware.JWTAuthMiddleware(authService), handler}
+// applyAuthMiddleware applies JWTAuthMiddleware to requests made while loginRequired returns
+// true, returning a HandlersChain suitable for gin. loginRequired is checked per request so the
+// setting follows config reloads. Personal access tokens need the search scope; anonymous
+// requests pass RequireScope untouched. The rate limiter runs after authentication so that
+// user- and token-keyed policies work.
+func applyAuthMiddleware(loginRequired func(c *gin.Context) bool, authService auth.Authenticator, tokenService token.Service, limiter, handler gin.HandlerFunc) gin.HandlersChain {
+	authenticate := middleware.JWTAuthMiddleware(authService, tokenService)
+	maybeAuthenticate := func(c *gin.Context) {
+		if loginRequired(c) {
+			authenticate(c)
+			return
+		}
+		c.Next()
 	}
-	return gin.HandlersChain{handler}
+	return gin.HandlersChain{maybeAuthenticate, middleware.RequireScope(token.ScopeSearch), limiter, handler}
 }
 
+// configureClientIP makes c.ClientIP() honour forwarding headers only when the TCP peer is
+// a trusted proxy, so clients cannot spoof X-Forwarded-For to dodge rate limits.
+func configureClientIP(r *gin.Engine, cfg config.Config) error {
+	proxies := cfg.TrustedProxies
+	if proxies == nil {
+		proxies = defaultTrustedProxies
+	}
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		return err
+	}
+	if cfg.RealIPHeader != "" {
+		r.RemoteIPHeaders = []string{cfg.RealIPHeader}
+	}
+	return nil
+}
+
+// newRateLimitStore selects where rate-limit counters live. With the Redis store every
+// replica behind the load balancer shares the same limits.
+func newRateLimitStore(cfg config.RateLimitConfig) ratelimit.Store {
+	if cfg.Store != "redis" {
+		return ratelimit.NewMemoryStore()
+	}
+	slog.Info("限流计数使用 Redis", "addr", cfg.Redis.Addr)
+	return ratelimit.NewRedisStore(ratelimit.RedisOptions{
+		Addr:     cfg.Redis.Addr,
+		Username: cfg.Redis.Username,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+		PoolSize: cfg.Redis.PoolSize,
+		Timeout:  time.Duration(cfg.Redis.TimeoutMillis) * time.Millisecond,
+	}, cfg.Redis.KeyPrefix)
+}
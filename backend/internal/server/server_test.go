@@ -0,0 +1,128 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chenxuan520/roadmap/backend/internal/auth"
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// clientIP 通过配置好的引擎解析一次请求的客户端IP
+func clientIP(t *testing.T, cfg config.Config, remoteAddr string, headers map[string]string) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := configureClientIP(r, cfg); err != nil {
+		t.Fatalf("配置失败: %v", err)
+	}
+	r.GET("/", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Body.String()
+}
+
+func TestClientIP_TrustedProxies(t *testing.T) {
+	cases := []struct {
+		name       string
+		cfg        config.Config
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{
+			name:       "默认信任本机nginx",
+			remoteAddr: "127.0.0.1:5000",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.7"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "默认配置下外部客户端伪造的头被忽略",
+			remoteAddr: "198.51.100.9:5000",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4", "X-Real-IP": "1.2.3.4"},
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "不在CIDR内的对端伪造的头被忽略",
+			cfg:        config.Config{TrustedProxies: []string{"10.0.0.0/8"}},
+			remoteAddr: "192.168.1.10:5000",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4"},
+			want:       "192.168.1.10",
+		},
+		{
+			name:       "可信代理之前的伪造条目被跳过",
+			cfg:        config.Config{TrustedProxies: []string{"10.0.0.0/8"}},
+			remoteAddr: "10.1.2.3:5000",
+			// 客户端自带 X-Forwarded-For: 1.2.3.4，代理追加了真实地址
+			headers: map[string]string{"X-Forwarded-For": "1.2.3.4, 203.0.113.7"},
+			want:    "203.0.113.7",
+		},
+		{
+			name:       "空列表不信任任何代理",
+			cfg:        config.Config{TrustedProxies: []string{}},
+			remoteAddr: "127.0.0.1:5000",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.7"},
+			want:       "127.0.0.1",
+		},
+		{
+			name:       "real_ip_header只读取指定的头",
+			cfg:        config.Config{TrustedProxies: []string{"10.0.0.1"}, RealIPHeader: "CF-Connecting-IP"},
+			remoteAddr: "10.0.0.1:5000",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4", "CF-Connecting-IP": "203.0.113.7"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "real_ip_header来自不可信对端时被忽略",
+			cfg:        config.Config{TrustedProxies: []string{"10.0.0.1"}, RealIPHeader: "CF-Connecting-IP"},
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string]string{"CF-Connecting-IP": "1.2.3.4"},
+			want:       "10.0.0.2",
+		},
+	}
+	for _, tc := range cases {
+		if got := clientIP(t, tc.cfg, tc.remoteAddr, tc.headers); got != tc.want {
+			t.Errorf("%s: 得到 %s, 期望 %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestConfigureClientIP_InvalidCIDR(t *testing.T) {
+	if err := configureClientIP(gin.New(), config.Config{TrustedProxies: []string{"10.0.0.0/33"}}); err == nil {
+		t.Fatal("期望无效的CIDR返回错误")
+	}
+}
+
+func TestApplyAuthMiddleware_FollowsReload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authService, err := auth.NewService(config.Config{JwtSecret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loginRequired atomic.Bool
+	r := gin.New()
+	r.GET("/search", applyAuthMiddleware(func(*gin.Context) bool { return loginRequired.Load() }, authService, nil,
+		func(c *gin.Context) { c.Next() },
+		func(c *gin.Context) { c.String(http.StatusOK, "ok") })...)
+
+	search := func() int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search", nil))
+		return w.Code
+	}
+	if code := search(); code != http.StatusOK {
+		t.Fatalf("无需登录时应放行匿名请求, 得到 %d", code)
+	}
+	loginRequired.Store(true) // 模拟重新加载后开启 login_required
+	if code := search(); code != http.StatusUnauthorized {
+		t.Fatalf("开启 login_required 后应要求登录, 得到 %d", code)
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+)
+
+// certCheckInterval 限制握手时检查证书文件修改时间的频率
+const certCheckInterval = 10 * time.Second
+
+// certReloader 提供从磁盘加载的证书，证书或私钥文件的修改时间变化时重新加载。
+// 检查在握手时按需进行，不需要单独的监视协程。重新加载失败（如私钥已写入而证书尚未写入）时
+// 继续使用旧证书，下次检查时重试。
+type certReloader struct {
+	certFile, keyFile string
+	now               func() time.Time
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	lastCheck time.Time
+}
+
+// newCertReloader 加载初始证书，文件缺失或无效时启动失败
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, now: time.Now}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) reload() error {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("read certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("read key: %w", err)
+	}
+	if r.cert != nil && certInfo.ModTime().Equal(r.certMod) && keyInfo.ModTime().Equal(r.keyMod) {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load key pair: %w", err)
+	}
+	if r.cert != nil {
+		slog.Info("TLS 证书已重新加载", "cert_file", r.certFile)
+	}
+	r.cert = &cert
+	r.certMod, r.keyMod = certInfo.ModTime(), keyInfo.ModTime()
+	return nil
+}
+
+// GetCertificate 实现 tls.Config.GetCertificate
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now := r.now(); now.Sub(r.lastCheck) >= certCheckInterval {
+		r.lastCheck = now
+		if err := r.reload(); err != nil {
+			slog.Warn("TLS 证书重新加载失败，继续使用旧证书", "error", err)
+		}
+	}
+	return r.cert, nil
+}
+
+// configureTLS 把主服务切换为 HTTPS，并按配置添加 HTTP 到 HTTPS 的重定向监听
+func (s *Server) configureTLS(cfg config.TLSConfig, httpsPort int) error {
+	reloader, err := newCertReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return err
+	}
+	s.httpServer.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if cfg.DisableHTTP2 {
+		// 非 nil 的空 map 阻止 net/http 自动启用 HTTP/2
+		s.httpServer.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	}
+	if cfg.HTTPRedirectPort > 0 {
+		s.redirectServer = &http.Server{
+			Addr:              fmt.Sprintf(":%d", cfg.HTTPRedirectPort),
+			Handler:           redirectToHTTPS(httpsPort),
+			ReadHeaderTimeout: s.httpServer.ReadHeaderTimeout,
+			IdleTimeout:       s.httpServer.IdleTimeout,
+		}
+	}
+	return nil
+}
+
+// redirectToHTTPS 把所有请求永久重定向到相同主机与路径的 HTTPS 地址。
+// 308 保留请求方法与请求体，发到 HTTP 端口的 POST 不会被悄悄变成 GET。
+func redirectToHTTPS(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		} else {
+			host = strings.Trim(host, "[]") // 不带端口的 IPv6 地址
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+)
+
+// writeCert 生成自签名证书写入 dir，返回证书与私钥路径
+func writeCert(t *testing.T, dir, commonName string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func commonName(t *testing.T, cert *tls.Certificate) string {
+	t.Helper()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader_ReloadsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "old.example.com")
+	r, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	r.GetCertificate(nil)
+
+	// 续期后写入新证书，并把修改时间推后以免文件系统时间精度不足
+	writeCert(t, dir, "new.example.com")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	os.Chtimes(keyFile, later, later)
+
+	cert, _ := r.GetCertificate(nil)
+	if got := commonName(t, cert); got != "old.example.com" {
+		t.Fatalf("检查间隔内不应重新读取文件, 得到 %s", got)
+	}
+	now = now.Add(certCheckInterval)
+	cert, _ = r.GetCertificate(nil)
+	if got := commonName(t, cert); got != "new.example.com" {
+		t.Fatalf("超过检查间隔后应加载新证书, 得到 %s", got)
+	}
+
+	// 写坏的证书不影响正在使用的证书
+	os.WriteFile(certFile, []byte("broken"), 0o600)
+	latest := later.Add(time.Minute)
+	os.Chtimes(certFile, latest, latest)
+	now = now.Add(certCheckInterval)
+	cert, err = r.GetCertificate(nil)
+	if err != nil || commonName(t, cert) != "new.example.com" {
+		t.Fatalf("重新加载失败时应保留旧证书, err=%v", err)
+	}
+}
+
+func TestNewCertReloader_MissingFiles(t *testing.T) {
+	if _, err := newCertReloader("missing-cert.pem", "missing-key.pem"); err == nil {
+		t.Fatal("证书不存在时应返回错误")
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	cases := []struct {
+		port       int
+		host, path string
+		want       string
+	}{
+		{443, "example.com", "/api/plans?id=1", "https://example.com/api/plans?id=1"},
+		{443, "example.com:80", "/", "https://example.com/"},
+		{8443, "example.com:8080", "/share/abc", "https://example.com:8443/share/abc"},
+		{8443, "[::1]:8080", "/", "https://[::1]:8443/"},
+		{443, "[::1]", "/", "https://[::1]/"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+		req.Host = tc.host
+		w := httptest.NewRecorder()
+		redirectToHTTPS(tc.port).ServeHTTP(w, req)
+		if w.Code != http.StatusPermanentRedirect {
+			t.Errorf("%s%s: 期望 308, 得到 %d", tc.host, tc.path, w.Code)
+		}
+		if got := w.Header().Get("Location"); got != tc.want {
+			t.Errorf("%s%s: 期望跳转到 %s, 得到 %s", tc.host, tc.path, tc.want, got)
+		}
+	}
+}
+
+func TestServer_ServesTLS(t *testing.T) {
+	certFile, keyFile := writeCert(t, t.TempDir(), "localhost")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, r.Proto) })
+	for _, disableHTTP2 := range []bool{false, true} {
+		s, url := newTestServer(t, mux, time.Second)
+		url = "https" + strings.TrimPrefix(url, "http")
+		if err := s.configureTLS(config.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, DisableHTTP2: disableHTTP2}, 443); err != nil {
+			t.Fatal(err)
+		}
+		if s.redirectServer != nil {
+			t.Fatal("未配置 http_redirect_port 时不应启动跳转监听")
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		runErr := make(chan error, 1)
+		go func() { runErr <- s.Run(ctx) }()
+
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			ForceAttemptHTTP2: true,
+		}}
+		var proto string
+		for i := 0; i < 100; i++ {
+			resp, err := client.Get(url + "/ready")
+			if err == nil {
+				b, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				proto = string(b)
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		want := "HTTP/2.0"
+		if disableHTTP2 {
+			want = "HTTP/1.1"
+		}
+		if proto != want {
+			t.Errorf("disable_http2=%v: 期望 %s, 得到 %q", disableHTTP2, want, proto)
+		}
+		client.CloseIdleConnections()
+		cancel()
+		if err := <-runErr; err != nil {
+			t.Fatalf("Run 返回错误: %v", err)
+		}
+	}
+}
+
+func TestServer_ConfigureTLSRedirect(t *testing.T) {
+	certFile, keyFile := writeCert(t, t.TempDir(), "localhost")
+	s, _ := newTestServer(t, http.NotFoundHandler(), time.Second)
+	if err := s.configureTLS(config.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, HTTPRedirectPort: 8080}, 8443); err != nil {
+		t.Fatal(err)
+	}
+	if s.redirectServer == nil || s.redirectServer.Addr != ":8080" {
+		t.Fatal("应在 http_redirect_port 上启动跳转监听")
+	}
+	if s.httpServer.TLSConfig.MinVersion != tls.VersionTLS12 {
+		t.Error("最低 TLS 版本应为 1.2")
+	}
+}
@@ -0,0 +1,205 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	dataDir   = "data"        // 令牌文件存储目录
+	tokenFile = "tokens.json" // 所有令牌保存在同一个文件中
+)
+
+// Repository 定义了个人访问令牌存储的接口
+type Repository interface {
+	Save(t *Token) error
+	FindByHash(hash string) (*Token, error)
+	FindByUser(username string) ([]Token, error)
+	Delete(username, id string) error
+	// Touch 更新令牌的最后使用时间；令牌已被删除时什么也不做，不会把吊销的令牌写回
+	Touch(id string, at time.Time) error
+}
+
+// fileRepository 是 Repository 接口的文件系统实现。
+// 令牌在创建时从文件加载到内存并按哈希建立索引，校验令牌不读盘；修改先写盘成功后再更新内存
+type fileRepository struct {
+	mu     sync.RWMutex
+	tokens []Token        // 与文件中的顺序一致
+	byHash map[string]int // 令牌哈希 -> tokens 下标
+}
+
+// NewFileRepository 创建一个新的 fileRepository 实例并加载已有令牌
+func NewFileRepository() (Repository, error) {
+	if _, err := os.Stat(dataDir); os.IsNotExist(err) {
+		if err := os.Mkdir(dataDir, 0755); err != nil {
+			return nil, fmt.Errorf("创建数据目录失败: %w", err)
+		}
+	}
+	r := &fileRepository{}
+	tokens, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	r.setTokens(tokens)
+	return r, nil
+}
+
+func (r *fileRepository) path() string {
+	return filepath.Join(dataDir, tokenFile)
+}
+
+// load 从文件读取全部令牌
+func (r *fileRepository) load() ([]Token, error) {
+	data, err := os.ReadFile(r.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Token{}, nil
+		}
+		return nil, fmt.Errorf("读取令牌文件失败: %w", err)
+	}
+	var tokens []Token
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("反序列化令牌文件失败: %w", err)
+	}
+	return tokens, nil
+}
+
+// setTokens 替换内存中的令牌并重建索引，调用方需持有写锁
+func (r *fileRepository) setTokens(tokens []Token) {
+	r.tokens = tokens
+	r.byHash = make(map[string]int, len(tokens))
+	for i, t := range tokens {
+		r.byHash[t.Hash] = i
+	}
+}
+
+// update 把修改后的令牌列表写盘，成功后才替换内存中的数据，调用方需持有写锁
+func (r *fileRepository) update(tokens []Token) error {
+	if err := r.store(tokens); err != nil {
+		return err
+	}
+	r.setTokens(tokens)
+	return nil
+}
+
+// withoutIndex 返回 tokens 去掉第 i 个元素后的副本
+func withoutIndex(tokens []Token, i int) []Token {
+	updated := make([]Token, 0, len(tokens)-1)
+	updated = append(updated, tokens[:i]...)
+	return append(updated, tokens[i+1:]...)
+}
+
+// store 写入全部令牌，调用方需持有写锁。
+// 先写入同目录下的临时文件并 fsync，再重命名替换，崩溃或并发读取时不会看到写了一半的文件
+func (r *fileRepository) store(tokens []Token) error {
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化令牌失败: %w", err)
+	}
+	// 令牌哈希属于敏感信息，仅允许属主读写（CreateTemp 创建的文件权限为 0600）
+	tmp, err := os.CreateTemp(dataDir, tokenFile+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("写入令牌文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // 重命名成功后不再存在
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入令牌文件失败: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入令牌文件失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入令牌文件失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.path()); err != nil {
+		return fmt.Errorf("写入令牌文件失败: %w", err)
+	}
+	return nil
+}
+
+// Save 新增或更新一个令牌（按ID匹配）
+func (r *fileRepository) Save(t *Token) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tokens := append([]Token(nil), r.tokens...)
+	replaced := false
+	for i := range tokens {
+		if tokens[i].ID == t.ID {
+			tokens[i] = *t
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		tokens = append(tokens, *t)
+	}
+	return r.update(tokens)
+}
+
+// FindByHash 根据令牌哈希查找令牌
+func (r *fileRepository) FindByHash(hash string) (*Token, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i, ok := r.byHash[hash]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	t := r.tokens[i]
+	return &t, nil
+}
+
+// FindByUser 返回指定用户的全部令牌
+func (r *fileRepository) FindByUser(username string) ([]Token, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := []Token{}
+	for _, t := range r.tokens {
+		if t.Username == username {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+// Touch 只更新已存在令牌的 LastUsedAt。与 Save 不同，找不到ID时不会新增，
+// 避免校验与吊销并发时把刚吊销的令牌重新写入。同一令牌每 lastUsedInterval 最多写盘一次，
+// 并发的校验请求不会各自重写文件
+func (r *fileRepository) Touch(id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.tokens {
+		if r.tokens[i].ID != id {
+			continue
+		}
+		if last := r.tokens[i].LastUsedAt; last != nil && at.Sub(*last) < lastUsedInterval {
+			return nil
+		}
+		tokens := append([]Token(nil), r.tokens...)
+		tokens[i].LastUsedAt = &at
+		return r.update(tokens)
+	}
+	return nil
+}
+
+// Delete 删除指定用户的一个令牌
+func (r *fileRepository) Delete(username, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.tokens {
+		if r.tokens[i].ID == id && r.tokens[i].Username == username {
+			return r.update(withoutIndex(r.tokens, i))
+		}
+	}
+	return ErrNotFound
+}
@@ -0,0 +1,158 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound     = errors.New("令牌不存在")
+	ErrInvalidToken = errors.New("个人访问令牌无效")
+	ErrExpiredToken = errors.New("个人访问令牌已过期")
+)
+
+// lastUsedInterval 限制 LastUsedAt 的写盘频率，避免每个请求都重写令牌文件
+const lastUsedInterval = time.Minute
+
+// Service 定义了个人访问令牌的业务接口
+type Service interface {
+	// Create 为用户签发一个新令牌，返回只展示一次的明文令牌
+	Create(username, name string, scopes []string, expiresAt *time.Time) (string, *Token, error)
+	List(username string) ([]Token, error)
+	Revoke(username, id string) error
+	// RevokeAll 吊销用户的全部令牌，返回吊销的数量
+	RevokeAll(username string) (int, error)
+	// Verify 校验明文令牌，成功时返回对应的令牌记录
+	Verify(plaintext string) (*Token, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+// NewService 创建并返回一个个人访问令牌服务实例
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+// IsToken 判断认证头中的凭证是否为个人访问令牌（而非JWT）
+func IsToken(credential string) bool {
+	return strings.HasPrefix(credential, Prefix)
+}
+
+// hashToken 计算明文令牌的SHA256哈希。令牌本身是高熵随机串，无需加盐。
+func hashToken(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+func generatePlaintext() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成随机令牌失败: %w", err)
+	}
+	return Prefix + hex.EncodeToString(buf), nil
+}
+
+// Create 为用户签发一个新令牌
+func (s *service) Create(username, name string, scopes []string, expiresAt *time.Time) (string, *Token, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, errors.New("令牌名称不能为空")
+	}
+	if len(scopes) == 0 {
+		return "", nil, errors.New("至少需要一个权限范围")
+	}
+	seen := make(map[string]bool, len(scopes))
+	uniqueScopes := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !IsValidScope(scope) {
+			return "", nil, fmt.Errorf("无效的权限范围: %s", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			uniqueScopes = append(uniqueScopes, scope)
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return "", nil, errors.New("过期时间必须晚于当前时间")
+	}
+
+	plaintext, err := generatePlaintext()
+	if err != nil {
+		return "", nil, err
+	}
+
+	t := &Token{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Username:  username,
+		Scopes:    uniqueScopes,
+		Hash:      hashToken(plaintext),
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repo.Save(t); err != nil {
+		return "", nil, err
+	}
+	return plaintext, t, nil
+}
+
+// List 返回用户的全部令牌
+func (s *service) List(username string) ([]Token, error) {
+	return s.repo.FindByUser(username)
+}
+
+// Revoke 吊销用户的一个令牌
+func (s *service) Revoke(username, id string) error {
+	return s.repo.Delete(username, id)
+}
+
+// RevokeAll 吊销用户的全部令牌
+func (s *service) RevokeAll(username string) (int, error) {
+	tokens, err := s.repo.FindByUser(username)
+	if err != nil {
+		return 0, err
+	}
+	revoked := 0
+	for _, t := range tokens {
+		if err := s.repo.Delete(username, t.ID); err != nil && !errors.Is(err, ErrNotFound) {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// Verify 校验明文令牌
+func (s *service) Verify(plaintext string) (*Token, error) {
+	if !IsToken(plaintext) {
+		return nil, ErrInvalidToken
+	}
+	t, err := s.repo.FindByHash(hashToken(plaintext))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if t.Expired(now) {
+		return nil, ErrExpiredToken
+	}
+
+	if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) >= lastUsedInterval {
+		t.LastUsedAt = &now
+		// 记录使用时间失败不影响认证结果；Touch 不会写回在此期间被吊销的令牌
+		_ = s.repo.Touch(t.ID, now)
+	}
+	return t, nil
+}
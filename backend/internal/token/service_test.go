@@ -0,0 +1,279 @@
+package token
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupTestEnv 创建临时数据目录，并返回服务实例与清理函数
+func setupTestEnv(t *testing.T) (Service, Repository, func()) {
+	tempDir, err := os.MkdirTemp("", "roadbook_token_test_")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+
+	originalDataDir := dataDir
+	dataDir = tempDir
+
+	repo, err := NewFileRepository()
+	if err != nil {
+		os.RemoveAll(tempDir)
+		t.Fatalf("创建仓库失败: %v", err)
+	}
+
+	return NewService(repo), repo, func() {
+		dataDir = originalDataDir
+		os.RemoveAll(tempDir)
+	}
+}
+
+func TestService_CreateAndVerify(t *testing.T) {
+	svc, repo, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	plaintext, created, err := svc.Create("alice", "backup", []string{ScopePlansRead, ScopePlansRead}, nil)
+	if err != nil {
+		t.Fatalf("创建令牌失败: %v", err)
+	}
+	if !strings.HasPrefix(plaintext, Prefix) {
+		t.Errorf("期望令牌以 %s 开头, 得到 %s", Prefix, plaintext)
+	}
+	if len(created.Scopes) != 1 {
+		t.Errorf("期望重复的权限范围被去重, 得到 %v", created.Scopes)
+	}
+
+	// 明文令牌不应被持久化
+	data, err := os.ReadFile(repo.(*fileRepository).path())
+	if err != nil {
+		t.Fatalf("读取令牌文件失败: %v", err)
+	}
+	if strings.Contains(string(data), plaintext) {
+		t.Fatal("令牌文件中不应包含明文令牌")
+	}
+
+	verified, err := svc.Verify(plaintext)
+	if err != nil {
+		t.Fatalf("校验令牌失败: %v", err)
+	}
+	if verified.Username != "alice" || verified.ID != created.ID {
+		t.Errorf("校验返回的令牌不匹配: %+v", verified)
+	}
+	if verified.LastUsedAt == nil {
+		t.Error("期望校验后记录最后使用时间")
+	}
+
+	if _, err := svc.Verify(plaintext + "x"); err != ErrInvalidToken {
+		t.Errorf("期望错误 %v, 得到 %v", ErrInvalidToken, err)
+	}
+}
+
+func TestService_CreateValidation(t *testing.T) {
+	svc, _, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	if _, _, err := svc.Create("alice", " ", []string{ScopeAI}, nil); err == nil {
+		t.Error("期望空名称返回错误")
+	}
+	if _, _, err := svc.Create("alice", "ci", nil, nil); err == nil {
+		t.Error("期望缺少权限范围返回错误")
+	}
+	if _, _, err := svc.Create("alice", "ci", []string{"admin"}, nil); err == nil {
+		t.Error("期望未知权限范围返回错误")
+	}
+	past := time.Now().Add(-time.Hour)
+	if _, _, err := svc.Create("alice", "ci", []string{ScopeAI}, &past); err == nil {
+		t.Error("期望过去的过期时间返回错误")
+	}
+}
+
+func TestService_Expired(t *testing.T) {
+	svc, repo, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	future := time.Now().Add(time.Hour)
+	plaintext, created, err := svc.Create("alice", "short", []string{ScopeSearch}, &future)
+	if err != nil {
+		t.Fatalf("创建令牌失败: %v", err)
+	}
+
+	// 直接修改存储中的过期时间，模拟令牌过期
+	past := time.Now().Add(-time.Minute)
+	created.ExpiresAt = &past
+	if err := repo.Save(created); err != nil {
+		t.Fatalf("更新令牌失败: %v", err)
+	}
+
+	if _, err := svc.Verify(plaintext); err != ErrExpiredToken {
+		t.Errorf("期望错误 %v, 得到 %v", ErrExpiredToken, err)
+	}
+}
+
+func TestService_ListAndRevoke(t *testing.T) {
+	svc, _, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	plaintext, created, err := svc.Create("alice", "a", []string{ScopeAI}, nil)
+	if err != nil {
+		t.Fatalf("创建令牌失败: %v", err)
+	}
+	if _, _, err := svc.Create("bob", "b", []string{ScopeAI}, nil); err != nil {
+		t.Fatalf("创建令牌失败: %v", err)
+	}
+
+	tokens, err := svc.List("alice")
+	if err != nil {
+		t.Fatalf("列出令牌失败: %v", err)
+	}
+	if len(tokens) != 1 || tokens[0].ID != created.ID {
+		t.Fatalf("期望 alice 只有一个令牌, 得到 %+v", tokens)
+	}
+
+	// 其他用户不能吊销 alice 的令牌
+	if err := svc.Revoke("bob", created.ID); err != ErrNotFound {
+		t.Errorf("期望错误 %v, 得到 %v", ErrNotFound, err)
+	}
+	if err := svc.Revoke("alice", created.ID); err != nil {
+		t.Fatalf("吊销令牌失败: %v", err)
+	}
+	if _, err := svc.Verify(plaintext); err != ErrInvalidToken {
+		t.Errorf("期望吊销后的令牌无效, 得到 %v", err)
+	}
+}
+
+func TestService_RevokeAll(t *testing.T) {
+	svc, _, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	a1, _, _ := svc.Create("alice", "a1", []string{ScopeAI}, nil)
+	a2, _, _ := svc.Create("alice", "a2", []string{ScopeSearch}, nil)
+	b, _, _ := svc.Create("bob", "b", []string{ScopeAI}, nil)
+
+	revoked, err := svc.RevokeAll("alice")
+	if err != nil || revoked != 2 {
+		t.Fatalf("期望吊销 2 个令牌, 得到 %d, %v", revoked, err)
+	}
+	for _, plaintext := range []string{a1, a2} {
+		if _, err := svc.Verify(plaintext); err != ErrInvalidToken {
+			t.Errorf("期望吊销后的令牌无效, 得到 %v", err)
+		}
+	}
+	if _, err := svc.Verify(b); err != nil {
+		t.Errorf("不应影响其他用户的令牌: %v", err)
+	}
+}
+
+func TestRepository_TouchDoesNotResurrect(t *testing.T) {
+	svc, repo, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	plaintext, created, err := svc.Create("alice", "a", []string{ScopeAI}, nil)
+	if err != nil {
+		t.Fatalf("创建令牌失败: %v", err)
+	}
+	// 模拟 Verify 读到令牌之后、记录使用时间之前，令牌被吊销
+	if err := svc.Revoke("alice", created.ID); err != nil {
+		t.Fatalf("吊销令牌失败: %v", err)
+	}
+	if err := repo.Touch(created.ID, time.Now().UTC()); err != nil {
+		t.Fatalf("更新使用时间失败: %v", err)
+	}
+	if _, err := svc.Verify(plaintext); err != ErrInvalidToken {
+		t.Errorf("吊销的令牌不应被写回, 得到 %v", err)
+	}
+
+	// 令牌存在时正常更新使用时间
+	plaintext, created, _ = svc.Create("alice", "b", []string{ScopeAI}, nil)
+	at := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	if err := repo.Touch(created.ID, at); err != nil {
+		t.Fatalf("更新使用时间失败: %v", err)
+	}
+	stored, err := repo.FindByHash(hashToken(plaintext))
+	if err != nil || stored.LastUsedAt == nil || !stored.LastUsedAt.Equal(at) {
+		t.Errorf("期望记录使用时间, 得到 %+v, %v", stored, err)
+	}
+}
+
+func TestRepository_StoreReplacesFileAtomically(t *testing.T) {
+	svc, _, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	for _, name := range []string{"a", "b"} {
+		if _, _, err := svc.Create("alice", name, []string{ScopeAI}, nil); err != nil {
+			t.Fatalf("创建令牌失败: %v", err)
+		}
+	}
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != tokenFile {
+		t.Errorf("写入后只应留下令牌文件, 得到 %v", entries)
+	}
+	info, err := os.Stat(filepath.Join(dataDir, tokenFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("令牌文件应仅属主可读写, 得到 %o", perm)
+	}
+}
+
+func TestRepository_ServesLookupsFromMemory(t *testing.T) {
+	svc, repo, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	plaintext, created, err := svc.Create("alice", "a", []string{ScopeAI}, nil)
+	if err != nil {
+		t.Fatalf("创建令牌失败: %v", err)
+	}
+	// 校验令牌不应再读取文件
+	path := filepath.Join(dataDir, tokenFile)
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Verify(plaintext); err != nil {
+		t.Fatalf("应从内存索引找到令牌: %v", err)
+	}
+
+	// 重启后从文件加载
+	if err := repo.Save(created); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewFileRepository()
+	if err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	if stored, err := reloaded.FindByHash(hashToken(plaintext)); err != nil || stored.ID != created.ID {
+		t.Errorf("重新加载后应能找到令牌: %+v, %v", stored, err)
+	}
+}
+
+func TestRepository_TouchThrottlesWrites(t *testing.T) {
+	svc, repo, cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	plaintext, created, err := svc.Create("alice", "a", []string{ScopeAI}, nil)
+	if err != nil {
+		t.Fatalf("创建令牌失败: %v", err)
+	}
+	first := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{first, first.Add(10 * time.Second), first.Add(59 * time.Second)} {
+		if err := repo.Touch(created.ID, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stored, _ := repo.FindByHash(hashToken(plaintext))
+	if stored.LastUsedAt == nil || !stored.LastUsedAt.Equal(first) {
+		t.Errorf("一分钟内应只记录一次使用时间, 得到 %v", stored.LastUsedAt)
+	}
+
+	later := first.Add(time.Minute)
+	repo.Touch(created.ID, later)
+	stored, _ = repo.FindByHash(hashToken(plaintext))
+	if !stored.LastUsedAt.Equal(later) {
+		t.Errorf("超过一分钟后应更新使用时间, 得到 %v", stored.LastUsedAt)
+	}
+}
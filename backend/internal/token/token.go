@@ -0,0 +1,56 @@
+package token
+
+import (
+	"time"
+)
+
+// 个人访问令牌可授予的权限范围
+const (
+	ScopePlansRead  = "plans:read"  // 读取计划
+	ScopePlansWrite = "plans:write" // 创建、修改、删除计划
+	ScopeAI         = "ai"          // 使用AI助手
+	ScopeSearch     = "search"      // 使用需要登录的搜索接口
+)
+
+// AllScopes 列出所有合法的权限范围
+var AllScopes = []string{ScopePlansRead, ScopePlansWrite, ScopeAI, ScopeSearch}
+
+// Prefix 是个人访问令牌明文的固定前缀，用于与JWT区分
+const Prefix = "rbk_"
+
+// Token 定义了个人访问令牌的存储结构。明文令牌不落盘，只保存其哈希值。
+type Token struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Username   string     `json:"username"`
+	Scopes     []string   `json:"scopes"`
+	Hash       string     `json:"hash"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+// HasScope 判断令牌是否包含指定权限范围
+func (t *Token) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired 判断令牌在给定时间点是否已过期
+func (t *Token) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// IsValidScope 判断权限范围是否合法
+func IsValidScope(scope string) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,308 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+)
+
+// 导出器参数
+const (
+	defaultServiceName = "roadbook-backend"
+	queueSize          = 2048
+	maxBatchSize       = 512
+	flushInterval      = 5 * time.Second
+	exportTimeout      = 10 * time.Second
+)
+
+// exporter 在后台批量地把 span 以 OTLP/HTTP JSON 发送到 {endpoint}/v1/traces。
+// 队列满时丢弃新的 span，不阻塞请求处理。
+type exporter struct {
+	url         string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+
+	queue   chan *Span
+	flushCh chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+
+	mu      sync.Mutex
+	dropped int
+}
+
+// Setup 根据配置设置全局 Provider，返回的函数在服务退出时调用以导出剩余的 span。
+// 未启用时返回空操作。
+func Setup(cfg config.TracingConfig) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		SetProvider(nil)
+		return func(context.Context) error { return nil }, nil
+	}
+	p, err := NewProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	SetProvider(p)
+	return p.Shutdown, nil
+}
+
+// NewProvider 创建 Provider 并启动后台导出
+func NewProvider(cfg config.TracingConfig) (*Provider, error) {
+	endpoint := strings.TrimRight(cfg.Endpoint, "/")
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		return nil, fmt.Errorf("tracing.endpoint 必须是 http(s) 地址: %q", cfg.Endpoint)
+	}
+	ratio := cfg.SampleRatio
+	if ratio == 0 {
+		ratio = 1
+	}
+	if ratio < 0 || ratio > 1 {
+		return nil, fmt.Errorf("tracing.sample_ratio 必须在 0 到 1 之间: %v", cfg.SampleRatio)
+	}
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+
+	e := &exporter{
+		url:         endpoint + "/v1/traces",
+		headers:     cfg.Headers,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: exportTimeout},
+		queue:       make(chan *Span, queueSize),
+		flushCh:     make(chan chan struct{}),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go e.run()
+	return &Provider{sampleRatio: ratio, exporter: e}, nil
+}
+
+// Shutdown 停止接收新的 span，并在 ctx 结束前导出队列中剩余的 span
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if global.Load() == p {
+		SetProvider(nil)
+	}
+	p.exporter.once.Do(func() { close(p.exporter.stop) })
+	select {
+	case <-p.exporter.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ForceFlush 立即导出队列中的 span，主要用于测试
+func (p *Provider) ForceFlush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case p.exporter.flushCh <- ack:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *exporter) enqueue(s *Span) {
+	select {
+	case e.queue <- s:
+	default:
+		e.mu.Lock()
+		e.dropped++
+		e.mu.Unlock()
+	}
+}
+
+func (e *exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, maxBatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			e.export(batch)
+			batch = batch[:0]
+		}
+	}
+	// drain 取出队列中已有的全部 span
+	drain := func() {
+		for {
+			select {
+			case s := <-e.queue:
+				batch = append(batch, s)
+				if len(batch) >= maxBatchSize {
+					flush()
+				}
+			default:
+				flush()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case s := <-e.queue:
+			batch = append(batch, s)
+			if len(batch) >= maxBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case ack := <-e.flushCh:
+			drain()
+			close(ack)
+		case <-e.stop:
+			drain()
+			return
+		}
+	}
+}
+
+func (e *exporter) export(spans []*Span) {
+	e.mu.Lock()
+	dropped := e.dropped
+	e.dropped = 0
+	e.mu.Unlock()
+	if dropped > 0 {
+		slog.Warn("追踪队列已满，部分 span 被丢弃", "dropped", dropped)
+	}
+
+	body, err := json.Marshal(e.encode(spans))
+	if err != nil {
+		slog.Warn("序列化 span 失败", "error", err)
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		slog.Warn("创建 OTLP 请求失败", "error", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		slog.Warn("导出 span 失败", "endpoint", e.url, "spans", len(spans), "error", err)
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		slog.Warn("导出 span 失败", "endpoint", e.url, "spans", len(spans), "status", resp.StatusCode)
+	}
+}
+
+// 以下类型对应 OTLP/JSON 的 ExportTraceServiceRequest。
+// 按规范 traceId/spanId 使用十六进制字符串，64 位整数使用十进制字符串。
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		TraceState        string         `json:"traceState,omitempty"`
+		Name              string         `json:"name"`
+		Kind              SpanKind       `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"` // 0 未设置，2 错误
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string                 `json:"key"`
+		Value map[string]interface{} `json:"value"`
+	}
+)
+
+func (e *exporter) encode(spans []*Span) otlpRequest {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		encoded := otlpSpan{
+			TraceID:           s.sc.TraceID.String(),
+			SpanID:            s.sc.SpanID.String(),
+			TraceState:        s.sc.TraceState,
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        encodeAttributes(s.attrs),
+		}
+		if s.parent.IsValid() {
+			encoded.ParentSpanID = s.parent.String()
+		}
+		if s.errored {
+			encoded.Status = otlpStatus{Code: 2, Message: s.statusMsg}
+		}
+		s.mu.Unlock()
+		out = append(out, encoded)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: encodeAttributes([]Attribute{String("service.name", e.serviceName)})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "roadbook"}, Spans: out}},
+	}}}
+}
+
+func encodeAttributes(attrs []Attribute) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	out := make([]otlpKeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		var v map[string]interface{}
+		switch val := a.Value.(type) {
+		case string:
+			v = map[string]interface{}{"stringValue": val}
+		case bool:
+			v = map[string]interface{}{"boolValue": val}
+		case int64:
+			v = map[string]interface{}{"intValue": strconv.FormatInt(val, 10)}
+		case float64:
+			v = map[string]interface{}{"doubleValue": val}
+		default:
+			v = map[string]interface{}{"stringValue": fmt.Sprint(val)}
+		}
+		out = append(out, otlpKeyValue{Key: a.Key, Value: v})
+	}
+	return out
+}
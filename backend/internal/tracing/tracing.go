@@ -0,0 +1,215 @@
+// Package tracing 实现了一个精简的分布式追踪：W3C Trace Context 传播，
+// 以及通过 OTLP/HTTP（JSON 编码）把 span 批量导出到 OpenTelemetry Collector。
+// 未启用时所有 API 都是空操作，调用方无需判断。
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpanKind 对应 OTLP 的 span 类型
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// TraceID 与 SpanID 为 W3C Trace Context 中的标识
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+
+// IsValid 全零的标识无效
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+func (s SpanID) IsValid() bool  { return s != SpanID{} }
+
+// SpanContext 是需要跨进程传播的 span 信息
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string // 原样透传的 tracestate
+}
+
+// IsValid 判断是否携带了合法的追踪标识
+func (sc SpanContext) IsValid() bool { return sc.TraceID.IsValid() && sc.SpanID.IsValid() }
+
+// Attribute 是 span 上的一个属性，值支持 string、bool、int、int64 与 float64
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String、Int、Int64、Bool、Float64 构造属性
+func String(key, value string) Attribute      { return Attribute{key, value} }
+func Int(key string, value int) Attribute     { return Attribute{key, int64(value)} }
+func Int64(key string, value int64) Attribute { return Attribute{key, value} }
+func Bool(key string, value bool) Attribute   { return Attribute{key, value} }
+func Float64(key string, v float64) Attribute { return Attribute{key, v} }
+
+// Span 表示一次操作。nil 的 *Span 可以安全调用所有方法，用于未启用追踪的情况。
+type Span struct {
+	provider *Provider
+	sc       SpanContext
+	parent   SpanID
+	name     string
+	kind     SpanKind
+	start    time.Time
+
+	mu        sync.Mutex
+	end       time.Time
+	attrs     []Attribute
+	errored   bool
+	statusMsg string
+	ended     bool
+}
+
+// SpanContext 返回 span 的传播信息
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttributes 添加属性
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attrs...)
+	s.mu.Unlock()
+}
+
+// RecordError 记录错误并将 span 状态设为失败，err 为 nil 时不做任何事
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.SetError(err.Error())
+}
+
+// SetError 将 span 状态设为失败
+func (s *Span) SetError(msg string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.errored = true
+	s.statusMsg = msg
+	s.mu.Unlock()
+}
+
+// End 结束 span 并交给导出器。重复调用无效。
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+	if s.sc.Sampled {
+		s.provider.exporter.enqueue(s)
+	}
+}
+
+type ctxKey int
+
+const (
+	spanKey ctxKey = iota
+	remoteKey
+)
+
+// ContextWithSpan 返回携带 span 的 context
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey, span)
+}
+
+// SpanFromContext 返回 context 中当前的 span，没有时返回 nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey).(*Span)
+	return span
+}
+
+// SpanContextFromContext 返回当前 span 的传播信息；没有本地 span 时返回从上游提取的信息
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.sc
+	}
+	sc, _ := ctx.Value(remoteKey).(SpanContext)
+	return sc
+}
+
+// Provider 负责采样与导出
+type Provider struct {
+	sampleRatio float64
+	exporter    *exporter
+}
+
+var global atomic.Pointer[Provider]
+
+// SetProvider 设置全局 Provider，nil 表示关闭追踪
+func SetProvider(p *Provider) {
+	global.Store(p)
+}
+
+// Start 以 ctx 中的 span（或上游传入的 span）为父节点创建新 span。
+// 未启用追踪时返回原 ctx 与 nil span。
+func Start(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (context.Context, *Span) {
+	p := global.Load()
+	if p == nil {
+		return ctx, nil
+	}
+	return p.start(ctx, name, kind, attrs)
+}
+
+func (p *Provider) start(ctx context.Context, name string, kind SpanKind, attrs []Attribute) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+	span := &Span{
+		provider: p,
+		name:     name,
+		kind:     kind,
+		start:    time.Now(),
+		attrs:    attrs,
+	}
+	if parent.IsValid() {
+		// 跟随父节点的采样决定，保证整条链路要么完整要么不记录
+		span.sc = SpanContext{TraceID: parent.TraceID, Sampled: parent.Sampled, TraceState: parent.TraceState}
+		span.parent = parent.SpanID
+	} else {
+		rand.Read(span.sc.TraceID[:])
+		span.sc.Sampled = p.sample(span.sc.TraceID)
+	}
+	rand.Read(span.sc.SpanID[:])
+	return ContextWithSpan(ctx, span), span
+}
+
+// sample 根据 trace id 的低 8 字节做确定性的比例采样
+func (p *Provider) sample(id TraceID) bool {
+	if p.sampleRatio >= 1 {
+		return true
+	}
+	if p.sampleRatio <= 0 {
+		return false
+	}
+	v := binary.BigEndian.Uint64(id[8:]) >> 1
+	return float64(v) < p.sampleRatio*float64(uint64(1)<<63)
+}
@@ -0,0 +1,193 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+)
+
+// collector 是一个接收 OTLP/JSON 的测试服务
+type collector struct {
+	mu      sync.Mutex
+	spans   []otlpSpan
+	service string
+	header  string
+}
+
+func newCollector(t *testing.T) (*collector, *httptest.Server) {
+	t.Helper()
+	col := &collector{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		col.mu.Lock()
+		defer col.mu.Unlock()
+		col.header = r.Header.Get("X-Api-Key")
+		for _, rs := range req.ResourceSpans {
+			col.service = rs.Resource.Attributes[0].Value["stringValue"].(string)
+			for _, ss := range rs.ScopeSpans {
+				col.spans = append(col.spans, ss.Spans...)
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return col, srv
+}
+
+func newTestProvider(t *testing.T, cfg config.TracingConfig) *Provider {
+	t.Helper()
+	p, err := NewProvider(cfg)
+	if err != nil {
+		t.Fatalf("创建 Provider 失败: %v", err)
+	}
+	SetProvider(p)
+	t.Cleanup(func() { p.Shutdown(context.Background()) })
+	return p
+}
+
+func TestPropagation(t *testing.T) {
+	h := http.Header{}
+	h.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.Set(TracestateHeader, "vendor=abc")
+	ctx := Extract(context.Background(), h)
+
+	sc := SpanContextFromContext(ctx)
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" || !sc.Sampled {
+		t.Fatalf("traceparent 解析错误: %+v", sc)
+	}
+
+	out := http.Header{}
+	Inject(ctx, out)
+	if got := out.Get(TraceparentHeader); got != h.Get(TraceparentHeader) {
+		t.Errorf("Inject 应写回上游的 traceparent, 得到 %q", got)
+	}
+	if out.Get(TracestateHeader) != "vendor=abc" {
+		t.Error("tracestate 应原样透传")
+	}
+
+	for _, bad := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01", // 全零 trace id
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if _, ok := parseTraceparent(bad); ok {
+			t.Errorf("应拒绝不合法的 traceparent %q", bad)
+		}
+	}
+	if _, ok := parseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future"); !ok {
+		t.Error("未来版本的 traceparent 允许追加字段")
+	}
+}
+
+func TestStart_DisabledIsNoop(t *testing.T) {
+	SetProvider(nil)
+	ctx := context.Background()
+	got, span := Start(ctx, "noop", SpanKindInternal)
+	if got != ctx || span != nil {
+		t.Fatal("未启用追踪时 Start 应返回原 context 与 nil span")
+	}
+	// nil span 的方法都可以安全调用
+	span.SetAttributes(String("k", "v"))
+	span.RecordError(errors.New("x"))
+	span.End()
+}
+
+func TestExport_ParentChildAndRemoteParent(t *testing.T) {
+	col, srv := newCollector(t)
+	p := newTestProvider(t, config.TracingConfig{
+		Enabled:     true,
+		Endpoint:    srv.URL + "/",
+		ServiceName: "roadbook-test",
+		Headers:     map[string]string{"X-Api-Key": "k"},
+	})
+
+	h := http.Header{}
+	h.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := Extract(context.Background(), h)
+
+	ctx, server := Start(ctx, "GET /api/cnmap/search", SpanKindServer)
+	_, client := Start(ctx, "baidu.Search", SpanKindClient, String("search.provider", "baidu"))
+	client.SetAttributes(Int("search.results", 3))
+	client.RecordError(errors.New("upstream error"))
+	client.End()
+	server.End()
+	server.End() // 重复结束不应重复导出
+
+	ctx2, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.ForceFlush(ctx2); err != nil {
+		t.Fatal(err)
+	}
+
+	col.mu.Lock()
+	defer col.mu.Unlock()
+	if col.service != "roadbook-test" || col.header != "k" {
+		t.Errorf("resource 或请求头错误: service=%q header=%q", col.service, col.header)
+	}
+	if len(col.spans) != 2 {
+		t.Fatalf("期望导出 2 个 span, 得到 %d", len(col.spans))
+	}
+	c, s := col.spans[0], col.spans[1]
+	if s.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || s.ParentSpanID != "00f067aa0ba902b7" || s.Kind != SpanKindServer {
+		t.Errorf("服务端 span 应挂在上游 span 之下: %+v", s)
+	}
+	if c.TraceID != s.TraceID || c.ParentSpanID != s.SpanID || c.Kind != SpanKindClient {
+		t.Errorf("客户端 span 应是服务端 span 的子节点: %+v", c)
+	}
+	if c.Status.Code != 2 || c.Status.Message != "upstream error" {
+		t.Errorf("错误状态未导出: %+v", c.Status)
+	}
+	if len(c.Attributes) != 2 || c.Attributes[1].Value["intValue"] != "3" {
+		t.Errorf("属性编码错误: %+v", c.Attributes)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	p := &Provider{sampleRatio: 0.5}
+	var low, high TraceID
+	low[8], high[8] = 0x7f, 0x80 // 低 8 字节作为 [0, 1) 上的均匀值，与采样率比较
+	if !p.sample(low) || p.sample(high) {
+		t.Error("0.5 的采样率应只采样低 8 字节小于一半取值范围的 trace")
+	}
+
+	// 未被采样的上游链路，子 span 同样不采样
+	h := http.Header{}
+	h.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	full := &Provider{sampleRatio: 1, exporter: &exporter{queue: make(chan *Span, 1)}}
+	_, span := full.start(Extract(context.Background(), h), "child", SpanKindInternal, nil)
+	if span.SpanContext().Sampled {
+		t.Error("应跟随上游的不采样决定")
+	}
+	span.End()
+	if len(full.exporter.queue) != 0 {
+		t.Error("未采样的 span 不应导出")
+	}
+}
+
+func TestNewProvider_ConfigErrors(t *testing.T) {
+	for _, cfg := range []config.TracingConfig{
+		{Enabled: true, Endpoint: "otel:4318"},
+		{Enabled: true, Endpoint: "http://otel:4318", SampleRatio: 1.5},
+	} {
+		if _, err := NewProvider(cfg); err == nil {
+			t.Errorf("配置 %+v 应返回错误", cfg)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package twofactor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	dataDir       = "data"           // 两步验证数据存储目录
+	twoFactorFile = "twofactor.json" // 所有用户的两步验证信息保存在同一个文件中
+)
+
+var ErrNotFound = errors.New("未配置两步验证")
+
+// Enrollment 记录一个用户的两步验证状态
+type Enrollment struct {
+	Username string `json:"username"`
+	// Secret 为已启用的TOTP密钥；PendingSecret 为已申请但尚未用验证码确认的密钥
+	Secret        string     `json:"secret,omitempty"`
+	PendingSecret string     `json:"pendingSecret,omitempty"`
+	Enabled       bool       `json:"enabled"`
+	EnabledAt     *time.Time `json:"enabledAt,omitempty"`
+	// RecoveryCodes 只保存恢复码的哈希，使用后即删除
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+	// LastUsedStep 记录最近一次成功使用的时间步，防止验证码在有效期内被重放
+	LastUsedStep int64 `json:"lastUsedStep,omitempty"`
+}
+
+// Repository 定义了两步验证信息存储的接口
+type Repository interface {
+	Find(username string) (*Enrollment, error)
+	Save(e *Enrollment) error
+	Delete(username string) error
+}
+
+// fileRepository 是 Repository 接口的文件系统实现
+type fileRepository struct {
+	mu sync.RWMutex
+}
+
+// NewFileRepository 创建一个新的 fileRepository 实例
+func NewFileRepository() (Repository, error) {
+	if _, err := os.Stat(dataDir); os.IsNotExist(err) {
+		if err := os.Mkdir(dataDir, 0755); err != nil {
+			return nil, fmt.Errorf("创建数据目录失败: %w", err)
+		}
+	}
+	return &fileRepository{}, nil
+}
+
+func (r *fileRepository) path() string {
+	return filepath.Join(dataDir, twoFactorFile)
+}
+
+// load 读取全部记录，调用方需持有锁
+func (r *fileRepository) load() (map[string]Enrollment, error) {
+	data, err := os.ReadFile(r.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]Enrollment{}, nil
+		}
+		return nil, fmt.Errorf("读取两步验证文件失败: %w", err)
+	}
+	enrollments := map[string]Enrollment{}
+	if err := json.Unmarshal(data, &enrollments); err != nil {
+		return nil, fmt.Errorf("反序列化两步验证文件失败: %w", err)
+	}
+	return enrollments, nil
+}
+
+// store 覆盖写入全部记录，调用方需持有写锁
+func (r *fileRepository) store(enrollments map[string]Enrollment) error {
+	data, err := json.MarshalIndent(enrollments, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化两步验证信息失败: %w", err)
+	}
+	// 文件中包含TOTP密钥，仅允许属主读写
+	if err := os.WriteFile(r.path(), data, 0600); err != nil {
+		return fmt.Errorf("写入两步验证文件失败: %w", err)
+	}
+	return nil
+}
+
+// Find 查找用户的两步验证信息
+func (r *fileRepository) Find(username string) (*Enrollment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	enrollments, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	e, ok := enrollments[username]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &e, nil
+}
+
+// Save 新增或覆盖用户的两步验证信息
+func (r *fileRepository) Save(e *Enrollment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	enrollments, err := r.load()
+	if err != nil {
+		return err
+	}
+	enrollments[e.Username] = *e
+	return r.store(enrollments)
+}
+
+// Delete 删除用户的两步验证信息
+func (r *fileRepository) Delete(username string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	enrollments, err := r.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := enrollments[username]; !ok {
+		return ErrNotFound
+	}
+	delete(enrollments, username)
+	return r.store(enrollments)
+}
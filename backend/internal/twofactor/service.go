@@ -0,0 +1,262 @@
+package twofactor
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Issuer 显示在验证器 App 中的服务名称
+	Issuer = "Roadbook"
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrInvalidCode     = errors.New("验证码无效")
+	ErrNotEnabled      = errors.New("未启用两步验证")
+	ErrAlreadyEnabled  = errors.New("已启用两步验证，如需更换请先停用")
+	ErrNoPendingSecret = errors.New("请先申请绑定两步验证")
+)
+
+// Status 描述用户的两步验证状态
+type Status struct {
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// Service 定义了两步验证的业务接口
+type Service interface {
+	Status(username string) (Status, error)
+	// Enroll 生成新的待确认密钥，返回密钥与 otpauth:// 地址
+	Enroll(username string) (string, string, error)
+	// Activate 用验证码确认待确认密钥并启用两步验证，返回只展示一次的恢复码
+	Activate(username, code string) ([]string, error)
+	// RegenerateRecoveryCodes 作废旧恢复码并生成新的一组
+	RegenerateRecoveryCodes(username, code string) ([]string, error)
+	Disable(username, code string) error
+	// Reset 由管理员为丢失验证器和恢复码的用户清除两步验证，无需验证码
+	Reset(username string) error
+	Enabled(username string) (bool, error)
+	// Verify 校验TOTP验证码或恢复码，恢复码使用后立即失效
+	Verify(username, code string) error
+}
+
+type service struct {
+	repo Repository
+	// mu 保证"读取-校验-写回"过程的原子性，避免同一验证码被并发使用两次
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// NewService 创建并返回一个两步验证服务实例
+func NewService(repo Repository) Service {
+	return &service{repo: repo, now: time.Now}
+}
+
+func (s *service) find(username string) (*Enrollment, error) {
+	e, err := s.repo.Find(username)
+	if errors.Is(err, ErrNotFound) {
+		return &Enrollment{Username: username}, nil
+	}
+	return e, err
+}
+
+// Status 返回用户的两步验证状态
+func (s *service) Status(username string) (Status, error) {
+	e, err := s.find(username)
+	if err != nil {
+		return Status{}, err
+	}
+	return Status{Enabled: e.Enabled, RecoveryCodesRemaining: len(e.RecoveryCodes)}, nil
+}
+
+// Enabled 判断用户是否启用了两步验证
+func (s *service) Enabled(username string) (bool, error) {
+	e, err := s.find(username)
+	if err != nil {
+		return false, err
+	}
+	return e.Enabled, nil
+}
+
+// Enroll 生成新的待确认密钥
+func (s *service) Enroll(username string) (string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.find(username)
+	if err != nil {
+		return "", "", err
+	}
+	if e.Enabled {
+		return "", "", ErrAlreadyEnabled
+	}
+	secret, err := GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	e.PendingSecret = secret
+	if err := s.repo.Save(e); err != nil {
+		return "", "", err
+	}
+	return secret, ProvisioningURI(Issuer, username, secret), nil
+}
+
+// Activate 确认待确认密钥并启用两步验证
+func (s *service) Activate(username, code string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.find(username)
+	if err != nil {
+		return nil, err
+	}
+	if e.Enabled {
+		return nil, ErrAlreadyEnabled
+	}
+	if e.PendingSecret == "" {
+		return nil, ErrNoPendingSecret
+	}
+	step, ok := ValidateCode(e.PendingSecret, code, s.now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	e.Secret = e.PendingSecret
+	e.PendingSecret = ""
+	e.Enabled = true
+	e.EnabledAt = &now
+	e.RecoveryCodes = hashes
+	e.LastUsedStep = step
+	if err := s.repo.Save(e); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes 作废旧恢复码并生成新的一组
+func (s *service) RegenerateRecoveryCodes(username, code string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.find(username)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyLocked(e, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	e.RecoveryCodes = hashes
+	if err := s.repo.Save(e); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable 校验验证码后停用两步验证
+func (s *service) Disable(username, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.find(username)
+	if err != nil {
+		return err
+	}
+	if err := s.verifyLocked(e, code); err != nil {
+		return err
+	}
+	return s.repo.Delete(username)
+}
+
+// Reset 清除用户的两步验证信息
+func (s *service) Reset(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.repo.Delete(username)
+	if errors.Is(err, ErrNotFound) {
+		return ErrNotEnabled
+	}
+	return err
+}
+
+// Verify 校验TOTP验证码或恢复码
+func (s *service) Verify(username, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.find(username)
+	if err != nil {
+		return err
+	}
+	return s.verifyLocked(e, code)
+}
+
+// verifyLocked 校验并持久化验证码的使用状态，调用方需持有 s.mu
+func (s *service) verifyLocked(e *Enrollment, code string) error {
+	if !e.Enabled {
+		return ErrNotEnabled
+	}
+
+	if step, ok := ValidateCode(e.Secret, code, s.now()); ok {
+		if step <= e.LastUsedStep {
+			return ErrInvalidCode // 验证码已被使用过
+		}
+		e.LastUsedStep = step
+		return s.repo.Save(e)
+	}
+
+	hash := hashRecoveryCode(code)
+	for i, stored := range e.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(hash)) == 1 {
+			e.RecoveryCodes = append(e.RecoveryCodes[:i], e.RecoveryCodes[i+1:]...)
+			return s.repo.Save(e)
+		}
+	}
+	return ErrInvalidCode
+}
+
+// normalizeRecoveryCode 忽略大小写、空格与连字符，方便用户输入
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateRecoveryCodes 生成恢复码明文（形如 "a1b2c-3d4e5"）及其哈希
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("生成恢复码失败: %w", err)
+		}
+		raw := hex.EncodeToString(buf)
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashRecoveryCode(raw)
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,160 @@
+package twofactor
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录B的 SHA1 测试向量（取后6位）
+func TestGenerateCode_RFC6238Vectors(t *testing.T) {
+	secret := base32NoPad.EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for ts, want := range vectors {
+		got, err := GenerateCode(secret, time.Unix(ts, 0))
+		if err != nil {
+			t.Fatalf("计算验证码失败: %v", err)
+		}
+		if got != want {
+			t.Errorf("时间 %d: 期望 %s, 得到 %s", ts, want, got)
+		}
+	}
+}
+
+func TestValidateCode_Skew(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	prev, _ := GenerateCode(secret, now.Add(-30*time.Second))
+	old, _ := GenerateCode(secret, now.Add(-90*time.Second))
+
+	if _, ok := ValidateCode(secret, prev, now); !ok {
+		t.Error("期望接受上一个时间步的验证码")
+	}
+	if _, ok := ValidateCode(secret, old, now); ok {
+		t.Error("期望拒绝超出容忍范围的验证码")
+	}
+	if _, ok := ValidateCode(secret, "12345", now); ok {
+		t.Error("期望拒绝长度错误的验证码")
+	}
+}
+
+func setupTestService(t *testing.T) (*service, func()) {
+	tempDir, err := os.MkdirTemp("", "roadbook_2fa_test_")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	originalDataDir := dataDir
+	dataDir = tempDir
+
+	repo, err := NewFileRepository()
+	if err != nil {
+		os.RemoveAll(tempDir)
+		t.Fatalf("创建仓库失败: %v", err)
+	}
+	svc := NewService(repo).(*service)
+	return svc, func() {
+		dataDir = originalDataDir
+		os.RemoveAll(tempDir)
+	}
+}
+
+func TestService_EnrollActivateVerify(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	clock := time.Unix(1700000000, 0)
+	svc.now = func() time.Time { return clock }
+
+	if _, err := svc.Activate("alice", "000000"); err != ErrNoPendingSecret {
+		t.Fatalf("期望未申请时返回 %v, 得到 %v", ErrNoPendingSecret, err)
+	}
+
+	secret, uri, err := svc.Enroll("alice")
+	if err != nil {
+		t.Fatalf("申请绑定失败: %v", err)
+	}
+	if uri == "" || secret == "" {
+		t.Fatal("期望返回密钥与 otpauth 地址")
+	}
+	if enabled, _ := svc.Enabled("alice"); enabled {
+		t.Fatal("确认前不应启用两步验证")
+	}
+
+	code, _ := GenerateCode(secret, clock)
+	recovery, err := svc.Activate("alice", code)
+	if err != nil {
+		t.Fatalf("启用失败: %v", err)
+	}
+	if len(recovery) != recoveryCodeCount {
+		t.Fatalf("期望 %d 个恢复码, 得到 %d", recoveryCodeCount, len(recovery))
+	}
+
+	// 激活时用过的验证码不能再次使用
+	if err := svc.Verify("alice", code); err != ErrInvalidCode {
+		t.Errorf("期望重放的验证码被拒绝, 得到 %v", err)
+	}
+
+	clock = clock.Add(30 * time.Second)
+	next, _ := GenerateCode(secret, clock)
+	if err := svc.Verify("alice", next); err != nil {
+		t.Errorf("期望新验证码通过, 得到 %v", err)
+	}
+
+	// 恢复码只能使用一次，且输入不区分大小写
+	if err := svc.Verify("alice", " "+recovery[0]+" "); err != nil {
+		t.Errorf("期望恢复码通过, 得到 %v", err)
+	}
+	if err := svc.Verify("alice", recovery[0]); err != ErrInvalidCode {
+		t.Errorf("期望已使用的恢复码被拒绝, 得到 %v", err)
+	}
+	status, _ := svc.Status("alice")
+	if status.RecoveryCodesRemaining != recoveryCodeCount-1 {
+		t.Errorf("期望剩余 %d 个恢复码, 得到 %d", recoveryCodeCount-1, status.RecoveryCodesRemaining)
+	}
+
+	if _, _, err := svc.Enroll("alice"); err != ErrAlreadyEnabled {
+		t.Errorf("期望已启用时拒绝重新绑定, 得到 %v", err)
+	}
+
+	if err := svc.Disable("alice", recovery[1]); err != nil {
+		t.Fatalf("停用失败: %v", err)
+	}
+	if enabled, _ := svc.Enabled("alice"); enabled {
+		t.Error("停用后不应再要求两步验证")
+	}
+}
+
+func TestService_Reset(t *testing.T) {
+	svc, cleanup := setupTestService(t)
+	defer cleanup()
+
+	if err := svc.Reset("alice"); err != ErrNotEnabled {
+		t.Fatalf("期望未启用时返回 %v, 得到 %v", ErrNotEnabled, err)
+	}
+
+	secret, _, err := svc.Enroll("alice")
+	if err != nil {
+		t.Fatalf("申请绑定失败: %v", err)
+	}
+	code, _ := GenerateCode(secret, time.Now())
+	if _, err := svc.Activate("alice", code); err != nil {
+		t.Fatalf("启用失败: %v", err)
+	}
+
+	if err := svc.Reset("alice"); err != nil {
+		t.Fatalf("重置失败: %v", err)
+	}
+	if enabled, _ := svc.Enabled("alice"); enabled {
+		t.Error("重置后不应再要求两步验证")
+	}
+}
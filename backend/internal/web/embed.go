@@ -0,0 +1,22 @@
+//go:build embedstatic
+
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// dist 在构建前由 static/ 复制而来（见 Dockerfile.standalone），使用 -tags embedstatic 构建时编译进二进制
+//
+//go:embed all:dist
+var dist embed.FS
+
+// Embedded 返回编译进二进制的前端文件
+func Embedded() (fs.FS, bool) {
+	sub, err := fs.Sub(dist, "dist")
+	if err != nil {
+		return nil, false
+	}
+	return sub, true
+}
@@ -0,0 +1,10 @@
+//go:build !embedstatic
+
+package web
+
+import "io/fs"
+
+// Embedded 在未使用 embedstatic 标签构建时不可用，只能通过 static.dir 从磁盘提供前端
+func Embedded() (fs.FS, bool) {
+	return nil, false
+}
@@ -0,0 +1,248 @@
+// Package web 由后端直接提供 static/ 前端，使单个容器即可运行完整应用，无需 nginx。
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// noCacheFiles 是决定前端版本的入口文件，浏览器每次都需要用 ETag 重新验证：
+// sw.js 过期会让用户一直停留在旧版本，manifest.json 影响安装后的 PWA
+var noCacheFiles = map[string]bool{
+	"index.html":    true,
+	"sw.js":         true,
+	"manifest.json": true,
+}
+
+// contentTypes 覆盖 mime 包在不同系统上可能不一致的类型
+var contentTypes = map[string]string{
+	".html":        "text/html; charset=utf-8",
+	".js":          "text/javascript; charset=utf-8",
+	".css":         "text/css; charset=utf-8",
+	".json":        "application/json",
+	".webmanifest": "application/manifest+json",
+	".svg":         "image/svg+xml",
+	".png":         "image/png",
+	".ico":         "image/x-icon",
+}
+
+// asset 是一个静态文件及其预压缩版本
+type asset struct {
+	contentType  string
+	cacheControl string
+	modTime      time.Time
+	variants     map[string]variant // 键为 Content-Encoding，"" 表示未压缩
+}
+
+type variant struct {
+	data []byte
+	etag string
+}
+
+// Handler 返回提供前端文件的处理器，注册为 NoRoute 使 API 路由优先。
+// 目录中的 xxx.br / xxx.gz 作为预压缩版本按 Accept-Encoding 返回；没有 .gz 的文本文件在加载时压缩。
+// 文件在启动时一次性读入内存，使用 dir 时更新前端需要重启服务。
+func Handler(cfg config.StaticConfig) (gin.HandlerFunc, error) {
+	fsys, err := source(cfg)
+	if err != nil {
+		return nil, err
+	}
+	assets, err := loadAssets(fsys, cfg.MaxAgeSeconds)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := assets["index.html"]; !ok {
+		return nil, fmt.Errorf("static: index.html not found")
+	}
+	return func(c *gin.Context) {
+		serve(c, assets)
+	}, nil
+}
+
+// source 选择前端文件的来源：配置了 dir 时读取目录，否则使用编译进二进制的副本
+func source(cfg config.StaticConfig) (fs.FS, error) {
+	if cfg.Dir != "" {
+		info, err := os.Stat(cfg.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("static: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("static: %s is not a directory", cfg.Dir)
+		}
+		return os.DirFS(cfg.Dir), nil
+	}
+	fsys, ok := Embedded()
+	if !ok {
+		return nil, fmt.Errorf("static: binary was built without the embedstatic tag; set static.dir to serve files from disk")
+	}
+	return fsys, nil
+}
+
+func loadAssets(fsys fs.FS, maxAge int) (map[string]*asset, error) {
+	assets := make(map[string]*asset)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(name, ".br") || strings.HasSuffix(name, ".gz") {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		a := &asset{
+			contentType:  contentType(name),
+			cacheControl: cacheControl(name, maxAge),
+			modTime:      info.ModTime(),
+			variants:     map[string]variant{"": newVariant(data, "")},
+		}
+		for _, encoding := range []string{"br", "gzip"} {
+			ext := ".br"
+			if encoding == "gzip" {
+				ext = ".gz"
+			}
+			if compressed, err := fs.ReadFile(fsys, name+ext); err == nil {
+				a.variants[encoding] = newVariant(compressed, encoding)
+			}
+		}
+		if _, ok := a.variants["gzip"]; !ok && compressible(a.contentType) {
+			if compressed := gzipBytes(data); len(compressed) < len(data) {
+				a.variants["gzip"] = newVariant(compressed, "gzip")
+			}
+		}
+		assets[name] = a
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("static: %w", err)
+	}
+	return assets, nil
+}
+
+// newVariant 计算强 ETag。不同编码的字节不同，ETag 也必须不同
+func newVariant(data []byte, encoding string) variant {
+	sum := sha256.Sum256(data)
+	tag := hex.EncodeToString(sum[:8])
+	if encoding != "" {
+		tag += "-" + encoding
+	}
+	return variant{data: data, etag: `"` + tag + `"`}
+}
+
+func gzipBytes(data []byte) []byte {
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func contentType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if name == "manifest.json" {
+		return "application/manifest+json"
+	}
+	if ct, ok := contentTypes[ext]; ok {
+		return ct
+	}
+	if ct := mime.TypeByExtension(ext); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+func compressible(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/") ||
+		strings.Contains(contentType, "json") ||
+		strings.Contains(contentType, "javascript") ||
+		strings.Contains(contentType, "xml")
+}
+
+// cacheControl 为入口文件返回 no-cache；其他文件名不带内容哈希，默认同样每次重新验证，
+// 配置 max_age_seconds 后允许浏览器在该时间内直接使用缓存
+func cacheControl(name string, maxAge int) string {
+	if noCacheFiles[name] || maxAge <= 0 {
+		return "no-cache"
+	}
+	return "public, max-age=" + strconv.Itoa(maxAge)
+}
+
+func serve(c *gin.Context, assets map[string]*asset) {
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return // 保持 gin 默认的 404
+	}
+	urlPath := c.Request.URL.Path
+	if urlPath == "/api" || strings.HasPrefix(urlPath, "/api/") {
+		return // 未知的 API 不回退到前端页面
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+urlPath), "/")
+	if name == "" || strings.HasSuffix(urlPath, "/") {
+		name = path.Join(name, "index.html")
+	}
+	a, ok := assets[name]
+	if !ok {
+		// 单页应用回退：没有扩展名的路径（如 /plans/abc）返回 index.html，缺失的资源文件仍返回 404
+		if path.Ext(name) != "" {
+			return
+		}
+		a = assets["index.html"]
+	}
+
+	encoding := negotiate(c.GetHeader("Accept-Encoding"), a)
+	v := a.variants[encoding]
+	h := c.Writer.Header()
+	h.Set("Content-Type", a.contentType)
+	h.Set("Cache-Control", a.cacheControl)
+	h.Set("ETag", v.etag)
+	h.Set("X-Content-Type-Options", "nosniff")
+	if len(a.variants) > 1 {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if encoding != "" {
+		h.Set("Content-Encoding", encoding)
+	}
+	// ServeContent 处理 If-None-Match、Range 与 HEAD
+	http.ServeContent(c.Writer, c.Request, name, a.modTime, bytes.NewReader(v.data))
+	c.Abort()
+}
+
+// negotiate 按 br、gzip 的顺序选择客户端接受且存在的编码
+func negotiate(acceptEncoding string, a *asset) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	for _, encoding := range []string{"br", "gzip"} {
+		if _, ok := a.variants[encoding]; ok && accepted[encoding] {
+			return encoding
+		}
+	}
+	return ""
+}
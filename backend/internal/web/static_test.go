@@ -0,0 +1,168 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chenxuan520/roadmap/backend/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+var indexHTML = "<!DOCTYPE html><html>" + strings.Repeat("<p>roadbook</p>", 50) + "</html>"
+
+// newStaticRouter 在临时目录中写入前端文件，返回提供这些文件的路由
+func newStaticRouter(t *testing.T, files map[string]string, maxAge int) *gin.Engine {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h, err := Handler(config.StaticConfig{Enabled: true, Dir: dir, MaxAgeSeconds: maxAge})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	r.NoRoute(h)
+	return r
+}
+
+func get(r *gin.Engine, path string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandler_CacheHeadersAndETag(t *testing.T) {
+	r := newStaticRouter(t, map[string]string{
+		"index.html":    indexHTML,
+		"sw.js":         "self.addEventListener('fetch', () => {});",
+		"manifest.json": `{"name": "RoadbookMaker"}`,
+		"style.css":     "body {}",
+		"favicon.png":   "\x89PNG",
+	}, 3600)
+
+	for path, want := range map[string][2]string{
+		"/":              {"text/html; charset=utf-8", "no-cache"},
+		"/index.html":    {"text/html; charset=utf-8", "no-cache"},
+		"/sw.js":         {"text/javascript; charset=utf-8", "no-cache"},
+		"/manifest.json": {"application/manifest+json", "no-cache"},
+		"/style.css":     {"text/css; charset=utf-8", "public, max-age=3600"},
+		"/favicon.png":   {"image/png", "public, max-age=3600"},
+	} {
+		w := get(r, path, nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: 期望 200, 得到 %d", path, w.Code)
+			continue
+		}
+		if got := w.Header().Get("Content-Type"); got != want[0] {
+			t.Errorf("%s: Content-Type = %q, 期望 %q", path, got, want[0])
+		}
+		if got := w.Header().Get("Cache-Control"); got != want[1] {
+			t.Errorf("%s: Cache-Control = %q, 期望 %q", path, got, want[1])
+		}
+	}
+
+	etag := get(r, "/style.css", nil).Header().Get("ETag")
+	if etag == "" || strings.HasPrefix(etag, "W/") {
+		t.Fatalf("应返回强 ETag, 得到 %q", etag)
+	}
+	if w := get(r, "/style.css", map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified {
+		t.Errorf("ETag 匹配时应返回 304, 得到 %d", w.Code)
+	}
+}
+
+func TestHandler_SPAFallback(t *testing.T) {
+	r := newStaticRouter(t, map[string]string{"index.html": indexHTML, "app.js": "1"}, 0)
+
+	if w := get(r, "/plans/abc", nil); w.Code != http.StatusOK || w.Body.String() != indexHTML {
+		t.Errorf("前端路由应返回 index.html, 得到 %d", w.Code)
+	}
+	if w := get(r, "/app.js", nil); w.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("max_age_seconds 为 0 时应返回 no-cache, 得到 %q", w.Header().Get("Cache-Control"))
+	}
+	if w := get(r, "/missing.js", nil); w.Code != http.StatusNotFound {
+		t.Errorf("缺失的资源文件应返回 404, 得到 %d", w.Code)
+	}
+	if w := get(r, "/api/unknown", nil); w.Code != http.StatusNotFound || strings.Contains(w.Body.String(), "<html>") {
+		t.Errorf("未知的 API 不应回退到前端页面, 得到 %d", w.Code)
+	}
+	if w := get(r, "/api/ping", nil); w.Body.String() != "pong" {
+		t.Error("API 路由应优先于静态文件")
+	}
+	if w := get(r, "/../../etc/passwd", nil); w.Code != http.StatusNotFound && w.Body.String() != indexHTML {
+		t.Errorf("不应访问目录之外的文件, 得到 %d", w.Code)
+	}
+}
+
+func TestHandler_Precompressed(t *testing.T) {
+	r := newStaticRouter(t, map[string]string{
+		"index.html":    indexHTML,
+		"index.html.br": "brotli-bytes",
+		"favicon.png":   "\x89PNG",
+	}, 0)
+
+	w := get(r, "/", map[string]string{"Accept-Encoding": "gzip, deflate, br"})
+	if w.Header().Get("Content-Encoding") != "br" || w.Body.String() != "brotli-bytes" {
+		t.Errorf("应优先返回预压缩的 br 文件, Content-Encoding=%q", w.Header().Get("Content-Encoding"))
+	}
+	if w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("应设置 Vary: Accept-Encoding, 得到 %q", w.Header().Get("Vary"))
+	}
+	brETag := w.Header().Get("ETag")
+
+	w = get(r, "/", map[string]string{"Accept-Encoding": "gzip, br;q=0"})
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("没有 .gz 文件时应在加载时压缩, Content-Encoding=%q", w.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != indexHTML {
+		t.Error("gzip 解压后的内容不一致")
+	}
+	if w.Header().Get("ETag") == brETag {
+		t.Error("不同编码的 ETag 应不同")
+	}
+
+	if w := get(r, "/", nil); w.Header().Get("Content-Encoding") != "" || w.Body.String() != indexHTML {
+		t.Error("不支持压缩的客户端应收到原始内容")
+	}
+	if w := get(r, "/favicon.png", map[string]string{"Accept-Encoding": "gzip"}); w.Header().Get("Content-Encoding") != "" {
+		t.Error("图片不应压缩")
+	}
+	if w := get(r, "/index.html.br", nil); w.Code != http.StatusNotFound {
+		t.Errorf("预压缩文件不应直接访问, 得到 %d", w.Code)
+	}
+}
+
+func TestHandler_InvalidSource(t *testing.T) {
+	if _, err := Handler(config.StaticConfig{Enabled: true, Dir: filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("目录不存在时应返回错误")
+	}
+	if _, err := Handler(config.StaticConfig{Enabled: true, Dir: t.TempDir()}); err == nil {
+		t.Error("缺少 index.html 时应返回错误")
+	}
+	if _, ok := Embedded(); !ok {
+		if _, err := Handler(config.StaticConfig{Enabled: true}); err == nil {
+			t.Error("未嵌入前端且未配置 dir 时应返回错误")
+		}
+	}
+}
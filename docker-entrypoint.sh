@@ -3,7 +3,8 @@
 # Start nginx in the background
 nginx -g 'daemon off;' &
 
-# Start the backend API in the foreground
+# Start the backend API in the foreground. exec replaces the shell so that the
+# backend receives SIGTERM from `docker stop` and can shut down gracefully.
 cd /app
-/usr/local/bin/roadbook-api
+exec /usr/local/bin/roadbook-api
 
@@ -68,7 +68,8 @@ if [ -f "$CONFIG_FILE_PATH" ]; then
     echo "----------------------------------------------------------------"
     echo "\"${username}\": {"
     echo "  \"salt\": \"${salt}\","
-    echo "  \"hash\": \"${hashed_password}\""
+    echo "  \"hash\": \"${hashed_password}\","
+    echo "  \"role\": \"member\""
     echo "}"
     echo "----------------------------------------------------------------"
     echo
@@ -158,7 +159,8 @@ cat > "$CONFIG_FILE_PATH" << JSON_EOL
   "users": {
     "${username}": {
       "salt": "${salt}",
-      "hash": "${hashed_password}"
+      "hash": "${hashed_password}",
+      "role": "admin"
     }
   }
 }
@@ -575,11 +575,23 @@ class OnlineModeManager {
         }
 
         try {
-            const response = await this.makeApiRequest('/login', 'POST', {
+            let response = await this.makeApiRequest('/login', 'POST', {
                 username,
                 password
             });
 
+            // 启用了两步验证：用挑战令牌和验证码换取正式token
+            if (response.two_factor_required) {
+                const code = await this.promptTwoFactorCode();
+                if (!code) {
+                    throw new Error('已取消两步验证');
+                }
+                response = await this.makeApiRequest('/login/2fa', 'POST', {
+                    challenge_token: response.challenge_token,
+                    code
+                });
+            }
+
             if (response.token) {
                 this.token = response.token;
                 localStorage.setItem('online_token', response.token);
@@ -614,6 +626,25 @@ class OnlineModeManager {
         }
     }
 
+    // 弹窗输入两步验证码（TOTP 或恢复码），取消时返回空字符串
+    async promptTwoFactorCode() {
+        if (typeof Swal !== 'undefined') {
+            const result = await Swal.fire({
+                title: '两步验证',
+                text: '请输入验证器 App 中的 6 位验证码，或使用恢复码',
+                input: 'text',
+                inputAttributes: { autocomplete: 'one-time-code', inputmode: 'numeric' },
+                showCancelButton: true,
+                confirmButtonText: '验证',
+                cancelButtonText: '取消',
+                confirmButtonColor: '#667eea',
+                cancelButtonColor: '#6c757d'
+            });
+            return result.isConfirmed ? (result.value || '').trim() : '';
+        }
+        return (prompt('请输入两步验证码或恢复码') || '').trim();
+    }
+
     // 退出登录
     async logout(silent = false) {
         this.isLoggingOut = true;